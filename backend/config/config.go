@@ -3,14 +3,66 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 // Config holds application configuration
 type Config struct {
-	Port          string
-	AdminPassword string
-	DBPath        string
+	Port             string
+	AdminUsername    string // first admin account, created when there are no users
+	AdminPassword    string
+	DBPath           string
 	AutoSaveInterval int // in seconds
+	SnapshotInterval int // in seconds
+	AlertInterval    int // in seconds
+	StaleDays        int // days without a 會辦 entry before a project is flagged
+	OIDC             OIDCConfig
+	LDAP             LDAPConfig
+}
+
+// OIDCConfig configures single sign-on through an OpenID Connect provider.
+// A user's role comes from the first group list that contains one of their
+// groups, checked from admin to viewer; users in none get DefaultRole, or
+// are refused if it is empty.
+type OIDCConfig struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string // the callback URL, e.g. https://host/api/auth/oidc/callback
+	Scopes       []string
+	GroupsClaim  string
+	AdminGroups  []string
+	EditorGroups []string
+	ViewerGroups []string
+	DefaultRole  string
+}
+
+// Enabled reports whether single sign-on is configured
+func (c OIDCConfig) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != "" && c.RedirectURL != ""
+}
+
+// LDAPConfig configures password login against an LDAP directory such as
+// Active Directory. Groups are matched by DN or common name and map to roles
+// like OIDCConfig's.
+type LDAPConfig struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s is replaced by the username
+	NameAttr     string
+	GroupAttr    string
+	AdminGroups  []string
+	EditorGroups []string
+	ViewerGroups []string
+	DefaultRole  string
+}
+
+// Enabled reports whether LDAP login is configured
+func (c LDAPConfig) Enabled() bool {
+	return c.URL != "" && c.BaseDN != ""
 }
 
 // Load returns the application configuration from environment variables
@@ -20,6 +72,11 @@ func Load() *Config {
 		port = "8080"
 	}
 
+	adminUsername := os.Getenv("ADMIN_USERNAME")
+	if adminUsername == "" {
+		adminUsername = "admin"
+	}
+
 	adminPassword := os.Getenv("ADMIN_PASSWORD")
 	if adminPassword == "" {
 		adminPassword = "admin123" // Default password for development
@@ -37,10 +94,91 @@ func Load() *Config {
 		}
 	}
 
+	snapshot := 600
+	if v := os.Getenv("SNAPSHOT_INTERVAL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			snapshot = n
+		}
+	}
+
+	alertInterval := 3600
+	if v := os.Getenv("ALERT_SCAN_INTERVAL"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			alertInterval = n
+		}
+	}
+
+	staleDays := 30
+	if v := os.Getenv("STALE_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			staleDays = n
+		}
+	}
+
 	return &Config{
 		Port:             port,
+		AdminUsername:    adminUsername,
 		AdminPassword:    adminPassword,
 		DBPath:           dbPath,
 		AutoSaveInterval: autoSave,
+		SnapshotInterval: snapshot,
+		AlertInterval:    alertInterval,
+		StaleDays:        staleDays,
+		OIDC: OIDCConfig{
+			Issuer:       strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/"),
+			ClientID:     os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL:  os.Getenv("OIDC_REDIRECT_URL"),
+			Scopes:       splitList(os.Getenv("OIDC_SCOPES"), "openid,profile,email"),
+			GroupsClaim:  getenv("OIDC_GROUPS_CLAIM", "groups"),
+			AdminGroups:  splitList(os.Getenv("OIDC_ADMIN_GROUPS"), ""),
+			EditorGroups: splitList(os.Getenv("OIDC_EDITOR_GROUPS"), ""),
+			ViewerGroups: splitList(os.Getenv("OIDC_VIEWER_GROUPS"), ""),
+			DefaultRole:  os.Getenv("OIDC_DEFAULT_ROLE"),
+		},
+		LDAP: LDAPConfig{
+			URL:          os.Getenv("LDAP_URL"),
+			StartTLS:     os.Getenv("LDAP_START_TLS") == "true",
+			BindDN:       os.Getenv("LDAP_BIND_DN"),
+			BindPassword: os.Getenv("LDAP_BIND_PASSWORD"),
+			BaseDN:       os.Getenv("LDAP_BASE_DN"),
+			UserFilter:   getenv("LDAP_USER_FILTER", "(sAMAccountName=%s)"),
+			NameAttr:     getenv("LDAP_NAME_ATTRIBUTE", "displayName"),
+			GroupAttr:    getenv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+			AdminGroups:  splitGroups(os.Getenv("LDAP_ADMIN_GROUPS")),
+			EditorGroups: splitGroups(os.Getenv("LDAP_EDITOR_GROUPS")),
+			ViewerGroups: splitGroups(os.Getenv("LDAP_VIEWER_GROUPS")),
+			DefaultRole:  os.Getenv("LDAP_DEFAULT_ROLE"),
+		},
+	}
+}
+
+// getenv returns an environment variable, or def if it is empty
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// splitList splits a comma or space separated list, using def if s is empty
+func splitList(s, def string) []string {
+	if s == "" {
+		s = def
+	}
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// splitGroups splits a semicolon separated list of group names or DNs, which
+// may contain commas and spaces
+func splitGroups(s string) []string {
+	var groups []string
+	for _, g := range strings.Split(s, ";") {
+		if g = strings.TrimSpace(g); g != "" {
+			groups = append(groups, g)
+		}
 	}
+	return groups
 }
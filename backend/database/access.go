@@ -0,0 +1,208 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Access levels to a workspace, from least to most
+const (
+	AccessView    = "view"    // read the documents
+	AccessComment = "comment" // also resolve action items
+	AccessEdit    = "edit"    // also edit the documents
+	AccessOwner   = "owner"   // also manage members and share links
+)
+
+// Workspace member roles
+const (
+	MemberOwner  = "owner"
+	MemberEditor = "editor"
+	MemberViewer = "viewer"
+)
+
+// AccessAtLeast reports whether access grants everything required does
+func AccessAtLeast(access, required string) bool {
+	rank := map[string]int{AccessView: 1, AccessComment: 2, AccessEdit: 3, AccessOwner: 4}
+	return rank[access] >= rank[required] && rank[required] > 0
+}
+
+// MemberAccess returns the access a member role grants, or "" for an unknown role
+func MemberAccess(role string) string {
+	switch role {
+	case MemberOwner:
+		return AccessOwner
+	case MemberEditor:
+		return AccessEdit
+	case MemberViewer:
+		return AccessView
+	}
+	return ""
+}
+
+// ValidShareScope reports whether a share link may grant scope
+func ValidShareScope(scope string) bool {
+	return scope == AccessView || scope == AccessComment || scope == AccessEdit
+}
+
+// WorkspaceMember is a user's role in a workspace
+type WorkspaceMember struct {
+	WorkspaceID string    `json:"workspaceId"`
+	UserID      string    `json:"userId"`
+	Username    string    `json:"username"`
+	DisplayName string    `json:"displayName"`
+	Role        string    `json:"role"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// SetWorkspaceMember adds a user to a workspace or changes their role
+func SetWorkspaceMember(workspaceID, userID, role string) error {
+	_, err := db.Exec(
+		`INSERT INTO workspace_members (workspace_id, user_id, role, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(workspace_id, user_id) DO UPDATE SET role = excluded.role`,
+		workspaceID, userID, role, time.Now(),
+	)
+	return err
+}
+
+// RemoveWorkspaceMember removes a user from a workspace, reporting whether they were a member
+func RemoveWorkspaceMember(workspaceID, userID string) (bool, error) {
+	result, err := db.Exec("DELETE FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// GetWorkspaceMemberRole returns a user's role in a workspace, or "" if they are not a member
+func GetWorkspaceMemberRole(workspaceID, userID string) (string, error) {
+	var role string
+	err := db.QueryRow("SELECT role FROM workspace_members WHERE workspace_id = ? AND user_id = ?", workspaceID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// GetWorkspaceMembers lists the members of a workspace by username
+func GetWorkspaceMembers(workspaceID string) ([]*WorkspaceMember, error) {
+	rows, err := db.Query(
+		`SELECT m.workspace_id, m.user_id, u.username, u.display_name, m.role, m.created_at
+		FROM workspace_members m JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = ? ORDER BY u.username COLLATE NOCASE`,
+		workspaceID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []*WorkspaceMember{}
+	for rows.Next() {
+		m := &WorkspaceMember{}
+		if err := rows.Scan(&m.WorkspaceID, &m.UserID, &m.Username, &m.DisplayName, &m.Role, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetMemberWorkspaces retrieves the workspaces a user is a member of
+func GetMemberWorkspaces(userID string) ([]*Workspace, error) {
+	rows, err := db.Query(
+		`SELECT w.id, w.name, w.description, w.content, w.collie_content, w.version, COALESCE(w.collie_version, 0), w.created_at, w.updated_at
+		FROM workspaces w JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = ? ORDER BY w.updated_at DESC`,
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workspaces []*Workspace
+	for rows.Next() {
+		ws := &Workspace{}
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Content, &ws.CollieContent, &ws.Version, &ws.CollieVersion, &ws.CreatedAt, &ws.UpdatedAt); err != nil {
+			return nil, err
+		}
+		workspaces = append(workspaces, ws)
+	}
+	return workspaces, rows.Err()
+}
+
+// ShareLink grants access to a workspace to anyone with its token. Only a
+// hash of the token is stored; ExpiresAt is nil for a link that does not expire.
+type ShareLink struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspaceId"`
+	TokenHash   string     `json:"-"`
+	Scope       string     `json:"scope"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
+
+// Expired reports whether the link has expired at now
+func (l *ShareLink) Expired(now time.Time) bool {
+	return l.ExpiresAt != nil && now.After(*l.ExpiresAt)
+}
+
+// shareLinkColumns lists the columns read by scanShareLink
+const shareLinkColumns = "id, workspace_id, token_hash, scope, created_by, created_at, expires_at"
+
+func scanShareLink(row rowScanner) (*ShareLink, error) {
+	l := &ShareLink{}
+	var expires sql.NullTime
+	if err := row.Scan(&l.ID, &l.WorkspaceID, &l.TokenHash, &l.Scope, &l.CreatedBy, &l.CreatedAt, &expires); err != nil {
+		return nil, err
+	}
+	if expires.Valid {
+		l.ExpiresAt = &expires.Time
+	}
+	return l, nil
+}
+
+// CreateShareLink stores a new share link
+func CreateShareLink(l *ShareLink) error {
+	_, err := db.Exec(
+		"INSERT INTO share_links (id, workspace_id, token_hash, scope, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		l.ID, l.WorkspaceID, l.TokenHash, l.Scope, l.CreatedBy, l.CreatedAt, l.ExpiresAt,
+	)
+	return err
+}
+
+// GetShareLinkByTokenHash retrieves the share link of a token hash
+func GetShareLinkByTokenHash(tokenHash string) (*ShareLink, error) {
+	return scanShareLink(db.QueryRow("SELECT "+shareLinkColumns+" FROM share_links WHERE token_hash = ?", tokenHash))
+}
+
+// GetShareLinks lists the share links of a workspace, newest first
+func GetShareLinks(workspaceID string) ([]*ShareLink, error) {
+	rows, err := db.Query("SELECT "+shareLinkColumns+" FROM share_links WHERE workspace_id = ? ORDER BY created_at DESC", workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []*ShareLink{}
+	for rows.Next() {
+		l, err := scanShareLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, rows.Err()
+}
+
+// DeleteShareLink revokes a share link of a workspace, reporting whether it existed
+func DeleteShareLink(workspaceID, id string) (bool, error) {
+	result, err := db.Exec("DELETE FROM share_links WHERE workspace_id = ? AND id = ?", workspaceID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
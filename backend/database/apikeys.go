@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// API key scopes
+const (
+	ScopeWorkspaceRead  = "workspace:read"  // view workspaces
+	ScopeWorkspaceWrite = "workspace:write" // also edit them
+	ScopeAdmin          = "admin"           // everything an admin can do
+)
+
+// ValidScope reports whether scope is a known API key scope
+func ValidScope(scope string) bool {
+	return scope == ScopeWorkspaceRead || scope == ScopeWorkspaceWrite || scope == ScopeAdmin
+}
+
+// APIKey lets an automation client use the API without logging in. Only a
+// hash of the key is stored; Prefix is the start of the key, to tell keys
+// apart. An empty WorkspaceID means the key is not restricted to a workspace.
+type APIKey struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Prefix      string     `json:"prefix"`
+	KeyHash     string     `json:"-"`
+	Scopes      []string   `json:"scopes"`
+	WorkspaceID string     `json:"workspaceId"`
+	CreatedBy   string     `json:"createdBy"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+}
+
+// HasScope reports whether the key was granted scope
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired reports whether the key has expired at now
+func (k *APIKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && now.After(*k.ExpiresAt)
+}
+
+// apiKeyColumns lists the columns read by scanAPIKey
+const apiKeyColumns = "id, name, prefix, key_hash, scopes, workspace_id, created_by, created_at, expires_at, last_used_at"
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	k := &APIKey{}
+	var scopes string
+	var expires, used sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &scopes, &k.WorkspaceID, &k.CreatedBy, &k.CreatedAt, &expires, &used); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Split(scopes, ",")
+	if expires.Valid {
+		k.ExpiresAt = &expires.Time
+	}
+	if used.Valid {
+		k.LastUsedAt = &used.Time
+	}
+	return k, nil
+}
+
+// CreateAPIKey stores a new API key
+func CreateAPIKey(k *APIKey) error {
+	_, err := db.Exec(
+		"INSERT INTO api_keys (id, name, prefix, key_hash, scopes, workspace_id, created_by, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		k.ID, k.Name, k.Prefix, k.KeyHash, strings.Join(k.Scopes, ","), k.WorkspaceID, k.CreatedBy, k.CreatedAt, k.ExpiresAt,
+	)
+	return err
+}
+
+// GetAPIKey retrieves an API key by ID
+func GetAPIKey(id string) (*APIKey, error) {
+	return scanAPIKey(db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE id = ?", id))
+}
+
+// GetAPIKeyByHash retrieves the API key of a key hash
+func GetAPIKeyByHash(keyHash string) (*APIKey, error) {
+	return scanAPIKey(db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = ?", keyHash))
+}
+
+// GetAllAPIKeys lists the API keys, newest first
+func GetAllAPIKeys() ([]*APIKey, error) {
+	rows, err := db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// TouchAPIKey records a use of an API key
+func TouchAPIKey(id string, now time.Time) error {
+	_, err := db.Exec("UPDATE api_keys SET last_used_at = ? WHERE id = ?", now, id)
+	return err
+}
+
+// DeleteAPIKey revokes an API key, reporting whether it existed
+func DeleteAPIKey(id string) (bool, error) {
+	result, err := db.Exec("DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"strings"
+	"time"
+)
+
+// Audit event actions
+const (
+	AuditLogin           = "login"            // a user logged in
+	AuditLoginFailed     = "login.failed"     // a password login was refused
+	AuditSessionRevoke   = "session.revoke"   // a user logged out one of their devices
+	AuditUserCreate      = "user.create"      // a user was created
+	AuditUserUpdate      = "user.update"      // a user was renamed, or their role or password changed
+	AuditUserDelete      = "user.delete"      // a user was deleted
+	AuditAPIKeyCreate    = "apikey.create"    // an API key was created
+	AuditAPIKeyRevoke    = "apikey.revoke"    // an API key was revoked
+	AuditWorkspaceCreate = "workspace.create" // a workspace was created
+	AuditWorkspaceRename = "workspace.rename" // a workspace was renamed
+	AuditWorkspaceDelete = "workspace.delete" // a workspace was deleted
+	AuditMemberSet       = "member.set"       // a user was added to a workspace or their role changed
+	AuditMemberRemove    = "member.remove"    // a user was removed from a workspace
+	AuditContentUpdate   = "content.update"   // a workspace document was edited
+	AuditProjectUpdate   = "project.update"   // 基本資訊 fields of a project were set through the API
+	AuditVersionRestore  = "version.restore"  // a workspace document was restored from a snapshot
+	AuditShareCreate     = "share.create"     // a share link was created
+	AuditShareRevoke     = "share.revoke"     // a share link was revoked
+)
+
+// AuditEvent records who did what to which workspace, user or API key.
+// Target is the ID of the one acted on, and TargetName its name at the time,
+// so events stay readable after it is deleted. Times are stored in UTC so
+// that created_at compares as text.
+type AuditEvent struct {
+	ID         int64     `json:"id"`
+	Action     string    `json:"action"`
+	ActorID    string    `json:"actorId"`
+	ActorName  string    `json:"actorName"`
+	Target     string    `json:"target"`
+	TargetName string    `json:"targetName"`
+	Detail     string    `json:"detail"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// AuditFilter selects audit events. Actor matches the actor's ID or name,
+// ignoring case; empty fields and zero times do not filter.
+type AuditFilter struct {
+	Actor  string
+	Target string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// CreateAuditEvent stores an audit event and sets its ID
+func CreateAuditEvent(e *AuditEvent) error {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC()
+	result, err := db.Exec(
+		"INSERT INTO audit_events (action, actor_id, actor_name, target, target_name, detail, ip, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		e.Action, e.ActorID, e.ActorName, e.Target, e.TargetName, e.Detail, e.IP, e.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	e.ID, err = result.LastInsertId()
+	return err
+}
+
+// GetAuditEvents lists the audit events matching a filter, newest first
+func GetAuditEvents(f AuditFilter) ([]*AuditEvent, error) {
+	var where []string
+	var args []interface{}
+	if f.Actor != "" {
+		where = append(where, "(actor_id = ? OR actor_name = ? COLLATE NOCASE)")
+		args = append(args, f.Actor, f.Actor)
+	}
+	if f.Target != "" {
+		where = append(where, "target = ?")
+		args = append(args, f.Target)
+	}
+	if f.Action != "" {
+		where = append(where, "action = ?")
+		args = append(args, f.Action)
+	}
+	if !f.Since.IsZero() {
+		where = append(where, "created_at >= ?")
+		args = append(args, f.Since.UTC())
+	}
+	if !f.Until.IsZero() {
+		where = append(where, "created_at < ?")
+		args = append(args, f.Until.UTC())
+	}
+
+	query := "SELECT id, action, actor_id, actor_name, target, target_name, detail, ip, created_at FROM audit_events"
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT ?"
+	args = append(args, f.Limit)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*AuditEvent{}
+	for rows.Next() {
+		e := &AuditEvent{}
+		if err := rows.Scan(&e.ID, &e.Action, &e.ActorID, &e.ActorName, &e.Target, &e.TargetName, &e.Detail, &e.IP, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// BlameLine is a line of a workspace's markdown with the last user who
+// changed it. AuthorID is empty and UpdatedAt nil for lines written before
+// authorship was tracked.
+type BlameLine struct {
+	Text       string     `json:"text"`
+	AuthorID   string     `json:"authorId"`
+	AuthorName string     `json:"authorName"`
+	UpdatedAt  *time.Time `json:"updatedAt"`
+}
+
+// SaveBlame stores the lines of a workspace's markdown with their authors
+func SaveBlame(workspaceID string, lines []BlameLine) error {
+	data, err := json.Marshal(lines)
+	if err != nil {
+		return err
+	}
+	// A workspace deleted since the lines were tracked gets no rows back
+	_, err = db.Exec(
+		"INSERT OR REPLACE INTO workspace_blame (workspace_id, lines, updated_at) SELECT ?, ?, ? WHERE EXISTS (SELECT 1 FROM workspaces WHERE id = ?)",
+		workspaceID, string(data), time.Now().UTC(), workspaceID,
+	)
+	return err
+}
+
+// GetBlame retrieves the lines stored by SaveBlame
+func GetBlame(workspaceID string) ([]BlameLine, error) {
+	var data string
+	if err := db.QueryRow("SELECT lines FROM workspace_blame WHERE workspace_id = ?", workspaceID).Scan(&data); err != nil {
+		return nil, err
+	}
+	var lines []BlameLine
+	err := json.Unmarshal([]byte(data), &lines)
+	return lines, err
+}
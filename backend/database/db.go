@@ -9,6 +9,12 @@ import (
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Document channels of a workspace
+const (
+	ChannelMarkdown = "markdown" // Workspace.Content
+	ChannelCollie   = "collie"   // Workspace.CollieContent
+)
+
 // Workspace represents a collaborative workspace
 type Workspace struct {
 	ID            string    `json:"id"`
@@ -17,6 +23,7 @@ type Workspace struct {
 	Content       string    `json:"content"`
 	CollieContent string    `json:"collieContent"` // BorderCollie Gantt data
 	Version       int64     `json:"version"`
+	CollieVersion int64     `json:"collieVersion"`
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
 }
@@ -46,6 +53,7 @@ func Init(dbPath string) error {
 		content TEXT DEFAULT '',
 		collie_content TEXT DEFAULT '',
 		version INTEGER DEFAULT 0,
+		collie_version INTEGER DEFAULT 0,
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
 	);
@@ -53,12 +61,98 @@ func Init(dbPath string) error {
 	CREATE TABLE IF NOT EXISTS workspace_versions (
 		id INTEGER PRIMARY KEY AUTOINCREMENT,
 		workspace_id TEXT NOT NULL,
+		channel TEXT DEFAULT 'markdown',
 		content TEXT,
+		version INTEGER DEFAULT 0,
+		author_id TEXT DEFAULT '',
+		author_name TEXT DEFAULT '',
 		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_versions_workspace ON workspace_versions(workspace_id);
+
+	CREATE TABLE IF NOT EXISTS users (
+		id TEXT PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE COLLATE NOCASE,
+		display_name TEXT DEFAULT '',
+		role TEXT NOT NULL DEFAULT 'viewer',
+		external_id TEXT DEFAULT '',
+		password_hash TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS sessions (
+		id TEXT PRIMARY KEY,
+		token_hash TEXT NOT NULL UNIQUE,
+		user_id TEXT NOT NULL,
+		ip TEXT DEFAULT '',
+		user_agent TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME NOT NULL,
+		FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_sessions_user ON sessions(user_id);
+
+	CREATE TABLE IF NOT EXISTS workspace_members (
+		workspace_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		role TEXT NOT NULL,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (workspace_id, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_members_user ON workspace_members(user_id);
+
+	CREATE TABLE IF NOT EXISTS share_links (
+		id TEXT PRIMARY KEY,
+		workspace_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		scope TEXT NOT NULL,
+		created_by TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME,
+		FOREIGN KEY (workspace_id) REFERENCES workspaces(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_share_links_workspace ON share_links(workspace_id);
+
+	CREATE TABLE IF NOT EXISTS api_keys (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		key_hash TEXT NOT NULL UNIQUE,
+		scopes TEXT NOT NULL,
+		workspace_id TEXT DEFAULT '',
+		created_by TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		expires_at DATETIME,
+		last_used_at DATETIME
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		action TEXT NOT NULL,
+		actor_id TEXT DEFAULT '',
+		actor_name TEXT DEFAULT '',
+		target TEXT DEFAULT '',
+		target_name TEXT DEFAULT '',
+		detail TEXT DEFAULT '',
+		ip TEXT DEFAULT '',
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_created ON audit_events(created_at);
+	CREATE INDEX IF NOT EXISTS idx_audit_target ON audit_events(target);
+
+	CREATE TABLE IF NOT EXISTS workspace_blame (
+		workspace_id TEXT PRIMARY KEY,
+		lines TEXT NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
 	`
 
 	_, err = db.Exec(schema)
@@ -68,9 +162,19 @@ func Init(dbPath string) error {
 
 	// Try to add version column for existing databases (ignore error if already exists)
 	_, _ = db.Exec("ALTER TABLE workspaces ADD COLUMN version INTEGER DEFAULT 0")
-	
+
 	// Add collie_content column for BorderCollie integration
 	_, _ = db.Exec("ALTER TABLE workspaces ADD COLUMN collie_content TEXT DEFAULT ''")
+	_, _ = db.Exec("ALTER TABLE workspaces ADD COLUMN collie_version INTEGER DEFAULT 0")
+
+	// Add snapshot metadata columns to workspace_versions
+	_, _ = db.Exec("ALTER TABLE workspace_versions ADD COLUMN version INTEGER DEFAULT 0")
+	_, _ = db.Exec("ALTER TABLE workspace_versions ADD COLUMN author_id TEXT DEFAULT ''")
+	_, _ = db.Exec("ALTER TABLE workspace_versions ADD COLUMN author_name TEXT DEFAULT ''")
+	_, _ = db.Exec("ALTER TABLE workspace_versions ADD COLUMN channel TEXT DEFAULT 'markdown'")
+
+	// Link users to single sign-on identities
+	_, _ = db.Exec("ALTER TABLE users ADD COLUMN external_id TEXT DEFAULT ''")
 
 	return nil
 }
@@ -96,9 +200,9 @@ func CreateWorkspace(ws *Workspace) error {
 func GetWorkspace(id string) (*Workspace, error) {
 	ws := &Workspace{}
 	err := db.QueryRow(
-		"SELECT id, name, description, content, collie_content, version, created_at, updated_at FROM workspaces WHERE id = ?",
+		"SELECT id, name, description, content, collie_content, version, COALESCE(collie_version, 0), created_at, updated_at FROM workspaces WHERE id = ?",
 		id,
-	).Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Content, &ws.CollieContent, &ws.Version, &ws.CreatedAt, &ws.UpdatedAt)
+	).Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Content, &ws.CollieContent, &ws.Version, &ws.CollieVersion, &ws.CreatedAt, &ws.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -107,7 +211,7 @@ func GetWorkspace(id string) (*Workspace, error) {
 
 // GetAllWorkspaces retrieves all workspaces
 func GetAllWorkspaces() ([]*Workspace, error) {
-	rows, err := db.Query("SELECT id, name, description, content, collie_content, version, created_at, updated_at FROM workspaces ORDER BY updated_at DESC")
+	rows, err := db.Query("SELECT id, name, description, content, collie_content, version, COALESCE(collie_version, 0), created_at, updated_at FROM workspaces ORDER BY updated_at DESC")
 	if err != nil {
 		return nil, err
 	}
@@ -116,7 +220,7 @@ func GetAllWorkspaces() ([]*Workspace, error) {
 	var workspaces []*Workspace
 	for rows.Next() {
 		ws := &Workspace{}
-		if err := rows.Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Content, &ws.CollieContent, &ws.Version, &ws.CreatedAt, &ws.UpdatedAt); err != nil {
+		if err := rows.Scan(&ws.ID, &ws.Name, &ws.Description, &ws.Content, &ws.CollieContent, &ws.Version, &ws.CollieVersion, &ws.CreatedAt, &ws.UpdatedAt); err != nil {
 			return nil, err
 		}
 		workspaces = append(workspaces, ws)
@@ -134,37 +238,69 @@ func UpdateWorkspace(ws *Workspace) error {
 	return err
 }
 
-// UpdateWorkspaceContent updates only the content field with version check
-func UpdateWorkspaceContentWithVersion(id, content string, expectedVersion int64) (bool, error) {
+// documentColumns returns the content and version columns backing a document channel
+func documentColumns(channel string) (string, string) {
+	if channel == ChannelCollie {
+		return "collie_content", "collie_version"
+	}
+	return "content", "version"
+}
+
+// UpdateWorkspaceContentVersion overwrites one document channel and sets its version explicitly
+func UpdateWorkspaceContentVersion(id, channel, content string, version int64) error {
+	contentCol, versionCol := documentColumns(channel)
 	result, err := db.Exec(
-		"UPDATE workspaces SET content = ?, updated_at = ?, version = version + 1 WHERE id = ? AND version = ?",
-		content, time.Now(), id, expectedVersion,
+		"UPDATE workspaces SET "+contentCol+" = ?, "+versionCol+" = ?, updated_at = ? WHERE id = ?",
+		content, version, time.Now(), id,
 	)
 	if err != nil {
-		return false, err
-	}
-	
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return false, err
+		return err
 	}
-	
-	return rowsAffected > 0, nil
+	return requireRow(result)
 }
 
-// UpdateWorkspaceContent updates only the content field
-func UpdateWorkspaceContent(id, content string) error {
-	_, err := db.Exec(
-		"UPDATE workspaces SET content = ?, updated_at = ? WHERE id = ?",
+// UpdateWorkspaceContent updates only the content of one document channel
+func UpdateWorkspaceContent(id, channel, content string) error {
+	contentCol, _ := documentColumns(channel)
+	result, err := db.Exec(
+		"UPDATE workspaces SET "+contentCol+" = ?, updated_at = ? WHERE id = ?",
 		content, time.Now(), id,
 	)
+	if err != nil {
+		return err
+	}
+	return requireRow(result)
+}
+
+// requireRow returns sql.ErrNoRows if an update matched no row, such as
+// for a workspace deleted while it was being edited
+func requireRow(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
 	return err
 }
 
 // DeleteWorkspace deletes a workspace
 func DeleteWorkspace(id string) error {
-	_, err := db.Exec("DELETE FROM workspaces WHERE id = ?", id)
+	if _, err := db.Exec("DELETE FROM workspaces WHERE id = ?", id); err != nil {
+		return err
+	}
+	// Foreign keys are not enforced, so remove the snapshots, access rows, keys
+	// and blame too
+	if _, err := db.Exec("DELETE FROM workspace_versions WHERE workspace_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM workspace_members WHERE workspace_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM share_links WHERE workspace_id = ?", id); err != nil {
+		return err
+	}
+	if _, err := db.Exec("DELETE FROM api_keys WHERE workspace_id = ?", id); err != nil {
+		return err
+	}
+	_, err := db.Exec("DELETE FROM workspace_blame WHERE workspace_id = ?", id)
 	return err
 }
-
-
@@ -0,0 +1,102 @@
+package database
+
+import "time"
+
+// Session is a login of a user. Only a hash of the session token is stored,
+// so the table cannot be used to log in. Times are stored in UTC so that
+// expires_at compares as text.
+type Session struct {
+	ID         string    `json:"id"`
+	TokenHash  string    `json:"-"`
+	UserID     string    `json:"userId"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// sessionColumns lists the columns read by scanSession
+const sessionColumns = "id, token_hash, user_id, ip, user_agent, created_at, last_seen_at, expires_at"
+
+func scanSession(row rowScanner) (*Session, error) {
+	s := &Session{}
+	if err := row.Scan(&s.ID, &s.TokenHash, &s.UserID, &s.IP, &s.UserAgent, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateSession stores a new session
+func CreateSession(s *Session) error {
+	_, err := db.Exec(
+		"INSERT INTO sessions (id, token_hash, user_id, ip, user_agent, created_at, last_seen_at, expires_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		s.ID, s.TokenHash, s.UserID, s.IP, s.UserAgent, s.CreatedAt.UTC(), s.LastSeenAt.UTC(), s.ExpiresAt.UTC(),
+	)
+	return err
+}
+
+// GetSessionByTokenHash retrieves the session of a token hash
+func GetSessionByTokenHash(tokenHash string) (*Session, error) {
+	return scanSession(db.QueryRow("SELECT "+sessionColumns+" FROM sessions WHERE token_hash = ?", tokenHash))
+}
+
+// GetUserSessions lists the sessions of a user, most recently used first
+func GetUserSessions(userID string) ([]*Session, error) {
+	rows, err := db.Query("SELECT "+sessionColumns+" FROM sessions WHERE user_id = ? ORDER BY last_seen_at DESC", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*Session{}
+	for rows.Next() {
+		s, err := scanSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, s)
+	}
+	return sessions, rows.Err()
+}
+
+// TouchSession records a use of a session and moves its expiry
+func TouchSession(s *Session) error {
+	_, err := db.Exec(
+		"UPDATE sessions SET ip = ?, user_agent = ?, last_seen_at = ?, expires_at = ? WHERE id = ?",
+		s.IP, s.UserAgent, s.LastSeenAt.UTC(), s.ExpiresAt.UTC(), s.ID,
+	)
+	return err
+}
+
+// DeleteSession deletes a session of a user, reporting whether it existed
+func DeleteSession(userID, id string) (bool, error) {
+	result, err := db.Exec("DELETE FROM sessions WHERE user_id = ? AND id = ?", userID, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// DeleteSessionByTokenHash deletes the session of a token hash
+func DeleteSessionByTokenHash(tokenHash string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE token_hash = ?", tokenHash)
+	return err
+}
+
+// DeleteUserSessions deletes all sessions of a user except the one with ID except
+func DeleteUserSessions(userID, except string) error {
+	_, err := db.Exec("DELETE FROM sessions WHERE user_id = ? AND id != ?", userID, except)
+	return err
+}
+
+// DeleteExpiredSessions deletes the sessions that expired before now and
+// returns how many there were
+func DeleteExpiredSessions(now time.Time) (int64, error) {
+	result, err := db.Exec("DELETE FROM sessions WHERE expires_at < ?", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
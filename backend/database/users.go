@@ -0,0 +1,130 @@
+package database
+
+import "time"
+
+// User roles, from most to least privileged
+const (
+	RoleAdmin  = "admin"  // manages users and workspaces
+	RoleEditor = "editor" // creates and edits workspaces
+	RoleViewer = "viewer" // reads the admin dashboards
+)
+
+// User is an account that can log in
+type User struct {
+	ID           string    `json:"id"`
+	Username     string    `json:"username"`
+	DisplayName  string    `json:"displayName"`
+	Role         string    `json:"role"`
+	ExternalID   string    `json:"externalId,omitempty"` // identity at a single sign-on provider
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+// ValidRole reports whether role is one of the user roles
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleEditor || role == RoleViewer
+}
+
+// RoleAtLeast reports whether role grants everything required does
+func RoleAtLeast(role, required string) bool {
+	rank := map[string]int{RoleViewer: 1, RoleEditor: 2, RoleAdmin: 3}
+	return rank[role] >= rank[required] && rank[required] > 0
+}
+
+// userColumns lists the columns read by scanUser
+const userColumns = "id, username, display_name, role, COALESCE(external_id, ''), password_hash, created_at, updated_at"
+
+// rowScanner is a *sql.Row or *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*User, error) {
+	u := &User{}
+	if err := row.Scan(&u.ID, &u.Username, &u.DisplayName, &u.Role, &u.ExternalID, &u.PasswordHash, &u.CreatedAt, &u.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// CreateUser creates a new user
+func CreateUser(u *User) error {
+	now := time.Now()
+	u.CreatedAt, u.UpdatedAt = now, now
+	_, err := db.Exec(
+		"INSERT INTO users (id, username, display_name, role, external_id, password_hash, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		u.ID, u.Username, u.DisplayName, u.Role, u.ExternalID, u.PasswordHash, u.CreatedAt, u.UpdatedAt,
+	)
+	return err
+}
+
+// GetUser retrieves a user by ID
+func GetUser(id string) (*User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE id = ?", id))
+}
+
+// GetUserByUsername retrieves a user by username, ignoring case
+func GetUserByUsername(username string) (*User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE username = ? COLLATE NOCASE", username))
+}
+
+// UserNameTaken reports whether a user has name as their username or display name, ignoring case
+func UserNameTaken(name string) (bool, error) {
+	var n int
+	err := db.QueryRow(
+		"SELECT COUNT(*) FROM users WHERE username = ? COLLATE NOCASE OR display_name = ? COLLATE NOCASE",
+		name, name,
+	).Scan(&n)
+	return n > 0, err
+}
+
+// GetUserByExternalID retrieves the user linked to a single sign-on identity
+func GetUserByExternalID(externalID string) (*User, error) {
+	return scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE external_id = ? AND external_id != ''", externalID))
+}
+
+// GetAllUsers retrieves all users ordered by username
+func GetAllUsers() ([]*User, error) {
+	rows, err := db.Query("SELECT " + userColumns + " FROM users ORDER BY username COLLATE NOCASE")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// UpdateUser updates a user's username, display name, role, external ID and password hash
+func UpdateUser(u *User) error {
+	u.UpdatedAt = time.Now()
+	_, err := db.Exec(
+		"UPDATE users SET username = ?, display_name = ?, role = ?, external_id = ?, password_hash = ?, updated_at = ? WHERE id = ?",
+		u.Username, u.DisplayName, u.Role, u.ExternalID, u.PasswordHash, u.UpdatedAt, u.ID,
+	)
+	return err
+}
+
+// DeleteUser deletes a user and their workspace memberships
+func DeleteUser(id string) error {
+	if _, err := db.Exec("DELETE FROM users WHERE id = ?", id); err != nil {
+		return err
+	}
+	_, err := db.Exec("DELETE FROM workspace_members WHERE user_id = ?", id)
+	return err
+}
+
+// CountUsers returns the number of users with a role, or of all users if role is empty
+func CountUsers(role string) (int, error) {
+	var n int
+	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE ? = '' OR role = ?", role, role).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+)
+
+// WorkspaceVersion represents a historical snapshot of one of a workspace's documents
+type WorkspaceVersion struct {
+	ID          int64     `json:"id"`
+	WorkspaceID string    `json:"workspaceId"`
+	Channel     string    `json:"channel"`
+	Content     string    `json:"content"`
+	Version     int64     `json:"version"`
+	AuthorID    string    `json:"authorId"`
+	AuthorName  string    `json:"authorName"`
+	Size        int       `json:"size"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// versionColumns lists the columns read by scanWorkspaceVersion
+const versionColumns = `id, workspace_id, COALESCE(channel, 'markdown'), COALESCE(content, ''), COALESCE(version, 0),
+	COALESCE(author_id, ''), COALESCE(author_name, ''), created_at`
+
+// CreateWorkspaceVersion stores a new snapshot and sets its ID
+func CreateWorkspaceVersion(v *WorkspaceVersion) error {
+	if v.CreatedAt.IsZero() {
+		v.CreatedAt = time.Now()
+	}
+	if v.Channel == "" {
+		v.Channel = ChannelMarkdown
+	}
+	result, err := db.Exec(
+		"INSERT INTO workspace_versions (workspace_id, channel, content, version, author_id, author_name, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		v.WorkspaceID, v.Channel, v.Content, v.Version, v.AuthorID, v.AuthorName, v.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	v.ID, err = result.LastInsertId()
+	v.Size = len(v.Content)
+	return err
+}
+
+// GetWorkspaceVersions lists snapshots of a workspace, newest first, without content.
+// An empty channel lists snapshots of all channels.
+func GetWorkspaceVersions(workspaceID, channel string) ([]*WorkspaceVersion, error) {
+	rows, err := db.Query(
+		`SELECT id, workspace_id, COALESCE(channel, 'markdown'), COALESCE(version, 0), COALESCE(author_id, ''), COALESCE(author_name, ''),
+		COALESCE(length(content), 0), created_at
+		FROM workspace_versions WHERE workspace_id = ? AND (? = '' OR COALESCE(channel, 'markdown') = ?)
+		ORDER BY created_at DESC, id DESC`,
+		workspaceID, channel, channel,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []*WorkspaceVersion{}
+	for rows.Next() {
+		v := &WorkspaceVersion{}
+		if err := rows.Scan(&v.ID, &v.WorkspaceID, &v.Channel, &v.Version, &v.AuthorID, &v.AuthorName, &v.Size, &v.CreatedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// GetWorkspaceVersion retrieves a single snapshot including its content
+func GetWorkspaceVersion(workspaceID string, id int64) (*WorkspaceVersion, error) {
+	return scanWorkspaceVersion(db.QueryRow(
+		"SELECT "+versionColumns+" FROM workspace_versions WHERE workspace_id = ? AND id = ?",
+		workspaceID, id,
+	))
+}
+
+// GetLatestWorkspaceVersion retrieves the most recent snapshot of a workspace channel
+func GetLatestWorkspaceVersion(workspaceID, channel string) (*WorkspaceVersion, error) {
+	return scanWorkspaceVersion(db.QueryRow(
+		"SELECT "+versionColumns+` FROM workspace_versions
+		WHERE workspace_id = ? AND COALESCE(channel, 'markdown') = ?
+		ORDER BY created_at DESC, id DESC LIMIT 1`,
+		workspaceID, channel,
+	))
+}
+
+// GetWorkspaceVersionAt retrieves the snapshot of a workspace channel as it was at the given time:
+// the latest snapshot taken at or before t, or the earliest one after it if none exists
+func GetWorkspaceVersionAt(workspaceID, channel string, t time.Time) (*WorkspaceVersion, error) {
+	v, err := scanWorkspaceVersion(db.QueryRow(
+		"SELECT "+versionColumns+` FROM workspace_versions
+		WHERE workspace_id = ? AND COALESCE(channel, 'markdown') = ? AND created_at <= ?
+		ORDER BY created_at DESC, id DESC LIMIT 1`,
+		workspaceID, channel, t,
+	))
+	if err != sql.ErrNoRows {
+		return v, err
+	}
+	return scanWorkspaceVersion(db.QueryRow(
+		"SELECT "+versionColumns+` FROM workspace_versions
+		WHERE workspace_id = ? AND COALESCE(channel, 'markdown') = ?
+		ORDER BY created_at ASC, id ASC LIMIT 1`,
+		workspaceID, channel,
+	))
+}
+
+// scanWorkspaceVersion scans a full snapshot row
+func scanWorkspaceVersion(row *sql.Row) (*WorkspaceVersion, error) {
+	v := &WorkspaceVersion{}
+	if err := row.Scan(&v.ID, &v.WorkspaceID, &v.Channel, &v.Content, &v.Version, &v.AuthorID, &v.AuthorName, &v.CreatedAt); err != nil {
+		return nil, err
+	}
+	v.Size = len(v.Content)
+	return v, nil
+}
@@ -0,0 +1,378 @@
+// Package diff computes line-level differences between two texts
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Line kinds
+const (
+	KindContext = "context"
+	KindAdd     = "add"
+	KindDelete  = "delete"
+)
+
+// maxEditDistance bounds the Myers search; beyond it the changed region is
+// reported as a single block replacement to keep the running time predictable.
+const maxEditDistance = 2000
+
+// Line is a single line of a hunk
+type Line struct {
+	Kind    string `json:"kind"`
+	Text    string `json:"text"`
+	OldLine int    `json:"oldLine,omitempty"` // 1-based, 0 for added lines
+	NewLine int    `json:"newLine,omitempty"` // 1-based, 0 for deleted lines
+}
+
+// Hunk is a group of nearby changes with surrounding context
+type Hunk struct {
+	OldStart int    `json:"oldStart"`
+	OldLines int    `json:"oldLines"`
+	NewStart int    `json:"newStart"`
+	NewLines int    `json:"newLines"`
+	Lines    []Line `json:"lines"`
+}
+
+// Stats summarizes a diff
+type Stats struct {
+	Added   int `json:"added"`
+	Deleted int `json:"deleted"`
+}
+
+// Compute returns the hunks turning a into b, with the given lines of context
+func Compute(a, b string, context int) []Hunk {
+	lines := diffLines(splitLines(a), splitLines(b))
+	return groupHunks(lines, context)
+}
+
+// Lines returns the full line-by-line edit script turning a into b
+func Lines(a, b string) []Line {
+	return diffLines(splitLines(a), splitLines(b))
+}
+
+// Summarize counts added and deleted lines in a set of hunks
+func Summarize(hunks []Hunk) Stats {
+	var s Stats
+	for _, h := range hunks {
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case KindAdd:
+				s.Added++
+			case KindDelete:
+				s.Deleted++
+			}
+		}
+	}
+	return s
+}
+
+// Unified renders hunks in unified diff format
+func Unified(fromName, toName string, hunks []Hunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, h := range hunks {
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+		for _, l := range h.Lines {
+			switch l.Kind {
+			case KindAdd:
+				sb.WriteByte('+')
+			case KindDelete:
+				sb.WriteByte('-')
+			default:
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange formats a unified diff range, omitting the count when it is 1
+func hunkRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines, ignoring a trailing newline
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// diffLines returns the full line-by-line edit script from a to b
+func diffLines(a, b []string) []Line {
+	// Trim common prefix and suffix so the search only covers the changed region
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var lines []Line
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, Line{Kind: KindContext, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	for _, l := range myers(midA, midB) {
+		if l.OldLine > 0 {
+			l.OldLine += prefix
+		}
+		if l.NewLine > 0 {
+			l.NewLine += prefix
+		}
+		lines = append(lines, l)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ai, bi := len(a)-suffix+i, len(b)-suffix+i
+		lines = append(lines, Line{Kind: KindContext, Text: a[ai], OldLine: ai + 1, NewLine: bi + 1})
+	}
+	return lines
+}
+
+// myers computes a shortest edit script with the linear-space variant of
+// Myers' O(ND) algorithm. It searches from both ends for where an optimal
+// path crosses the middle and recurses on the two halves, so memory stays
+// proportional to the input instead of growing with the edit distance.
+func myers(a, b []string) []Line {
+	if len(a) == 0 || len(b) == 0 {
+		return replaceAll(a, b)
+	}
+	s := &editScript{a: a, b: b, lines: make([]Line, 0, len(a)+len(b))}
+	s.compare(0, len(a), 0, len(b))
+	return s.lines
+}
+
+// editScript collects the edit script turning a into b
+type editScript struct {
+	a, b  []string
+	lines []Line
+}
+
+// compare appends the edit script turning a[x0:x1] into b[y0:y1]
+func (s *editScript) compare(x0, x1, y0, y1 int) {
+	prefix := 0
+	for x0+prefix < x1 && y0+prefix < y1 && s.a[x0+prefix] == s.b[y0+prefix] {
+		prefix++
+	}
+	s.context(x0, y0, prefix)
+	x0, y0 = x0+prefix, y0+prefix
+
+	suffix := 0
+	for x1-suffix > x0 && y1-suffix > y0 && s.a[x1-1-suffix] == s.b[y1-1-suffix] {
+		suffix++
+	}
+	x1, y1 = x1-suffix, y1-suffix
+
+	if x, y, ok := s.split(x0, x1, y0, y1); ok {
+		s.compare(x0, x, y0, y)
+		s.compare(x, x1, y, y1)
+	} else {
+		s.replace(x0, x1, y0, y1)
+	}
+	s.context(x1, y1, suffix)
+}
+
+// split returns a point on a shortest path from (x0, y0) to (x1, y1), half
+// way along it. It is false when either side is empty, when the two sides
+// have nothing in common, or when they are more than maxEditDistance apart.
+func (s *editScript) split(x0, x1, y0, y1 int) (int, int, bool) {
+	n, m := x1-x0, y1-y0
+	if n == 0 || m == 0 {
+		return 0, 0, false
+	}
+
+	// The searches meet after at most half the edit distance each
+	limit := min((n+m+1)/2, maxEditDistance/2+1)
+	offset := limit + 1
+	forward := make([]int, 2*limit+3)
+	reverse := make([]int, 2*limit+3)
+	for i := range forward {
+		forward[i], reverse[i] = -1, -1
+	}
+	forward[offset+1], reverse[offset+1] = 0, 0
+
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from the
+	// start, reverse[offset+k] the same counted back from the end. When the
+	// diagonals are an odd number apart the forward search finds the meeting
+	// point, otherwise the reverse one does.
+	delta := n - m
+	odd := delta%2 != 0
+	kStart, kEnd, rStart, rEnd := 0, 0, 0, 0
+	for d := 0; d < limit; d++ {
+		for k := -d + kStart; k <= d-kEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && s.a[x0+x] == s.b[y0+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			switch {
+			case x > n:
+				kEnd += 2 // ran off the right of the grid
+			case y > m:
+				kStart += 2 // ran off the bottom of the grid
+			case odd:
+				r := offset + delta - k
+				if r >= 0 && r < len(reverse) && reverse[r] != -1 && x >= n-reverse[r] {
+					return x0 + x, y0 + y, true
+				}
+			}
+		}
+
+		for k := -d + rStart; k <= d-rEnd; k += 2 {
+			var x int
+			if k == -d || (k != d && reverse[offset+k-1] < reverse[offset+k+1]) {
+				x = reverse[offset+k+1]
+			} else {
+				x = reverse[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && s.a[x1-1-x] == s.b[y1-1-y] {
+				x++
+				y++
+			}
+			reverse[offset+k] = x
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !odd:
+				f := offset + delta - k
+				if f >= 0 && f < len(forward) && forward[f] != -1 {
+					fx := forward[f]
+					if fx >= n-x {
+						return x0 + fx, y0 + fx - (f - offset), true
+					}
+				}
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// context appends count unchanged lines starting at a[x] and b[y]
+func (s *editScript) context(x, y, count int) {
+	for i := 0; i < count; i++ {
+		s.lines = append(s.lines, Line{Kind: KindContext, Text: s.a[x+i], OldLine: x + i + 1, NewLine: y + i + 1})
+	}
+}
+
+// replace appends a[x0:x1] as deleted and b[y0:y1] as added
+func (s *editScript) replace(x0, x1, y0, y1 int) {
+	for x := x0; x < x1; x++ {
+		s.lines = append(s.lines, Line{Kind: KindDelete, Text: s.a[x], OldLine: x + 1})
+	}
+	for y := y0; y < y1; y++ {
+		s.lines = append(s.lines, Line{Kind: KindAdd, Text: s.b[y], NewLine: y + 1})
+	}
+}
+
+// replaceAll reports every line of a as deleted and every line of b as added
+func replaceAll(a, b []string) []Line {
+	lines := make([]Line, 0, len(a)+len(b))
+	for i, t := range a {
+		lines = append(lines, Line{Kind: KindDelete, Text: t, OldLine: i + 1})
+	}
+	for i, t := range b {
+		lines = append(lines, Line{Kind: KindAdd, Text: t, NewLine: i + 1})
+	}
+	return lines
+}
+
+// groupHunks collects changed lines into hunks with surrounding context
+func groupHunks(lines []Line, context int) []Hunk {
+	var hunks []Hunk
+	i := 0
+	for i < len(lines) {
+		// Find the next change
+		for i < len(lines) && lines[i].Kind == KindContext {
+			i++
+		}
+		if i == len(lines) {
+			break
+		}
+
+		start := max(0, i-context)
+		end := i
+		// Extend while the gap to the next change is small enough to merge
+		for end < len(lines) {
+			if lines[end].Kind != KindContext {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(lines) && lines[gap].Kind == KindContext {
+				gap++
+			}
+			if gap == len(lines) || gap-end > 2*context {
+				end = min(len(lines), end+context)
+				break
+			}
+			end = gap
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end], lines[:start]))
+		i = end
+	}
+	return hunks
+}
+
+// newHunk builds a hunk from its lines; before holds all lines preceding it
+func newHunk(lines, before []Line) Hunk {
+	h := Hunk{Lines: append([]Line(nil), lines...)}
+
+	// Line numbers just before the hunk, used when a side has no lines
+	for _, l := range before {
+		if l.OldLine > 0 {
+			h.OldStart = l.OldLine
+		}
+		if l.NewLine > 0 {
+			h.NewStart = l.NewLine
+		}
+	}
+
+	oldStart, newStart := 0, 0
+	for _, l := range lines {
+		if l.OldLine > 0 {
+			h.OldLines++
+			if oldStart == 0 {
+				oldStart = l.OldLine
+			}
+		}
+		if l.NewLine > 0 {
+			h.NewLines++
+			if newStart == 0 {
+				newStart = l.NewLine
+			}
+		}
+	}
+	if oldStart > 0 {
+		h.OldStart = oldStart
+	}
+	if newStart > 0 {
+		h.NewStart = newStart
+	}
+	return h
+}
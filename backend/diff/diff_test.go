@@ -0,0 +1,321 @@
+package diff
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{
+			name: "identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: []Line{
+				{Kind: KindContext, Text: "a", OldLine: 1, NewLine: 1},
+				{Kind: KindContext, Text: "b", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "trailing newline is ignored",
+			a:    "a\nb",
+			b:    "a\nb\n",
+			want: []Line{
+				{Kind: KindContext, Text: "a", OldLine: 1, NewLine: 1},
+				{Kind: KindContext, Text: "b", OldLine: 2, NewLine: 2},
+			},
+		},
+		{
+			name: "change between common prefix and suffix",
+			a:    "a\nb\nc\nd\n",
+			b:    "a\nB\nc\nd\n",
+			want: []Line{
+				{Kind: KindContext, Text: "a", OldLine: 1, NewLine: 1},
+				{Kind: KindDelete, Text: "b", OldLine: 2},
+				{Kind: KindAdd, Text: "B", NewLine: 2},
+				{Kind: KindContext, Text: "c", OldLine: 3, NewLine: 3},
+				{Kind: KindContext, Text: "d", OldLine: 4, NewLine: 4},
+			},
+		},
+		{
+			name: "insert shifts the suffix",
+			a:    "a\nc\n",
+			b:    "a\nb\nc\n",
+			want: []Line{
+				{Kind: KindContext, Text: "a", OldLine: 1, NewLine: 1},
+				{Kind: KindAdd, Text: "b", NewLine: 2},
+				{Kind: KindContext, Text: "c", OldLine: 2, NewLine: 3},
+			},
+		},
+		{
+			name: "myers keeps the common middle line",
+			a:    "x\nm\ny\n",
+			b:    "p\nm\nq\n",
+			want: []Line{
+				{Kind: KindDelete, Text: "x", OldLine: 1},
+				{Kind: KindAdd, Text: "p", NewLine: 1},
+				{Kind: KindContext, Text: "m", OldLine: 2, NewLine: 2},
+				{Kind: KindDelete, Text: "y", OldLine: 3},
+				{Kind: KindAdd, Text: "q", NewLine: 3},
+			},
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "a\n",
+			want: []Line{{Kind: KindAdd, Text: "a", NewLine: 1}},
+		},
+		{
+			name: "to empty",
+			a:    "a\n",
+			b:    "",
+			want: []Line{{Kind: KindDelete, Text: "a", OldLine: 1}},
+		},
+		{
+			name: "CJK lines",
+			a:    "- 燈號: 綠\n- 進度: 10%\n",
+			b:    "- 燈號: 紅\n- 進度: 10%\n",
+			want: []Line{
+				{Kind: KindDelete, Text: "- 燈號: 綠", OldLine: 1},
+				{Kind: KindAdd, Text: "- 燈號: 紅", NewLine: 1},
+				{Kind: KindContext, Text: "- 進度: 10%", OldLine: 2, NewLine: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) =\n%+v\nwant\n%+v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// numbered returns n lines "<prefix>0" to "<prefix>n-1"
+func numbered(prefix string, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = prefix + strings.Repeat("x", i%7) + string(rune('a'+i%26)) + strings.Repeat("y", i/26)
+	}
+	return lines
+}
+
+func TestMyersFallsBackToReplaceAll(t *testing.T) {
+	// Nothing in common and more than maxEditDistance edits apart
+	a := numbered("old ", maxEditDistance/2+1)
+	b := numbered("new ", maxEditDistance/2+1)
+
+	got := myers(a, b)
+	if want := replaceAll(a, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("myers did not fall back to replaceAll")
+	}
+	if got[0].Kind != KindDelete || got[len(got)-1].Kind != KindAdd {
+		t.Errorf("replaceAll should delete all then add all, got %v ... %v", got[0], got[len(got)-1])
+	}
+}
+
+func TestMyersWithinLimit(t *testing.T) {
+	a := numbered("line ", 50)
+	b := append(append([]string{}, a[:25]...), append([]string{"inserted"}, a[25:]...)...)
+
+	got := myers(a, b)
+	adds, deletes := 0, 0
+	for _, l := range got {
+		switch l.Kind {
+		case KindAdd:
+			adds++
+		case KindDelete:
+			deletes++
+		}
+	}
+	if adds != 1 || deletes != 0 {
+		t.Errorf("got %d adds and %d deletes, want the single insert", adds, deletes)
+	}
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestMyersIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	random := func() []string {
+		lines := make([]string, rng.Intn(30))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		got := myers(a, b)
+
+		// the script must rebuild both sides, in order, with correct numbers
+		var oldSide, newSide []string
+		edits := 0
+		for _, l := range got {
+			if l.Kind != KindAdd {
+				oldSide = append(oldSide, l.Text)
+				if l.OldLine != len(oldSide) {
+					t.Fatalf("myers(%q, %q): line %+v should be old line %d", a, b, l, len(oldSide))
+				}
+			}
+			if l.Kind != KindDelete {
+				newSide = append(newSide, l.Text)
+				if l.NewLine != len(newSide) {
+					t.Fatalf("myers(%q, %q): line %+v should be new line %d", a, b, l, len(newSide))
+				}
+			}
+			if l.Kind != KindContext {
+				edits++
+			}
+		}
+		if strings.Join(oldSide, "\n") != strings.Join(a, "\n") || strings.Join(newSide, "\n") != strings.Join(b, "\n") {
+			t.Fatalf("myers(%q, %q) rebuilds %q and %q", a, b, oldSide, newSide)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("myers(%q, %q) made %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestMyersNearLimit(t *testing.T) {
+	// maxEditDistance edits apart, which the search must still resolve
+	a := numbered("line ", 2000)
+	var b []string
+	for i, l := range a {
+		if i%2 == 0 {
+			b = append(b, l)
+		} else {
+			b = append(b, "changed")
+		}
+	}
+
+	adds, deletes := 0, 0
+	for _, l := range myers(a, b) {
+		switch l.Kind {
+		case KindAdd:
+			adds++
+		case KindDelete:
+			deletes++
+		}
+	}
+	if adds != 1000 || deletes != 1000 {
+		t.Errorf("got %d adds and %d deletes, want 1000 of each", adds, deletes)
+	}
+}
+
+func TestComputeHunks(t *testing.T) {
+	a := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n"
+	b := "1\n2\nthree\n4\n5\n6\n7\n8\nnine\n10\n"
+
+	hunks := Compute(a, b, 1)
+	if len(hunks) != 2 {
+		t.Fatalf("got %d hunks, want 2 separated by unchanged lines", len(hunks))
+	}
+	want := []struct{ oldStart, oldLines, newStart, newLines int }{
+		{2, 3, 2, 3},
+		{8, 3, 8, 3},
+	}
+	for i, w := range want {
+		h := hunks[i]
+		if h.OldStart != w.oldStart || h.OldLines != w.oldLines || h.NewStart != w.newStart || h.NewLines != w.newLines {
+			t.Errorf("hunk %d = -%d,%d +%d,%d, want -%d,%d +%d,%d", i,
+				h.OldStart, h.OldLines, h.NewStart, h.NewLines, w.oldStart, w.oldLines, w.newStart, w.newLines)
+		}
+	}
+
+	// With more context the two changes merge into one hunk
+	if got := len(Compute(a, b, 3)); got != 1 {
+		t.Errorf("with context 3 got %d hunks, want 1", got)
+	}
+
+	if got := Summarize(hunks); got != (Stats{Added: 2, Deleted: 2}) {
+		t.Errorf("Summarize = %+v, want 2 added and 2 deleted", got)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "no changes",
+			a:    "a\n",
+			b:    "a\n",
+			want: "",
+		},
+		{
+			name: "single line ranges omit the count",
+			a:    "a\n",
+			b:    "b\n",
+			want: "--- old\n+++ new\n@@ -1 +1 @@\n-a\n+b\n",
+		},
+		{
+			name: "multi line ranges",
+			a:    "a\nb\nc\n",
+			b:    "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "addition to an empty file",
+			a:    "",
+			b:    "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deletion of every line",
+			a:    "a\nb\n",
+			b:    "",
+			want: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Unified("old", "new", Compute(tt.a, tt.b, 3))
+			if got != tt.want {
+				t.Errorf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHunkRange(t *testing.T) {
+	tests := []struct {
+		start, count int
+		want         string
+	}{
+		{1, 1, "1"},
+		{3, 2, "3,2"},
+		{0, 0, "0,0"},
+	}
+	for _, tt := range tests {
+		if got := hunkRange(tt.start, tt.count); got != tt.want {
+			t.Errorf("hunkRange(%d, %d) = %q, want %q", tt.start, tt.count, got, tt.want)
+		}
+	}
+}
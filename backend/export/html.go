@@ -0,0 +1,287 @@
+package export
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// htmlDeck is the data of the HTML template
+type htmlDeck struct {
+	Title    string
+	Exported string
+	Slides   []htmlSlide
+}
+
+// htmlSlide is a summary page or a project slide of the HTML deck
+type htmlSlide struct {
+	Page      int
+	Projects  []parser.Project
+	Project   *parser.Project
+	Bars      []ganttBar
+	Today     float64
+	ShowToday bool
+}
+
+var htmlFuncs = template.FuncMap{
+	"statusIcon":  statusIcon,
+	"statusClass": statusClass,
+	"departments": departments,
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.3f%%", v*100)
+	},
+	"summaryTitle": summaryTitle,
+	"limit": func(n int, v any) any {
+		switch s := v.(type) {
+		case []parser.MeetingEntry:
+			return s[:min(n, len(s))]
+		case []parser.NoteLine:
+			return s[:min(n, len(s))]
+		}
+		return v
+	},
+}
+
+// HTML writes the projects as a single self-contained HTML page that works
+// offline: summary tables followed by one slide per project. Arrow keys step
+// through the slides and printing puts one slide on each page.
+func HTML(w io.Writer, title string, projects []parser.Project) error {
+	now := time.Now()
+	deck := htmlDeck{Title: title, Exported: now.Format("2006-01-02 15:04")}
+
+	page := 0
+	for _, s := range parser.GenerateSlides(projects) {
+		if s.Type == parser.SlideSummary {
+			deck.Slides = append(deck.Slides, htmlSlide{Page: page, Projects: s.Projects})
+			page++
+			continue
+		}
+		slide := htmlSlide{Project: s.Project, Bars: ganttBars(s.Project.Phases)}
+		slide.Today, slide.ShowToday = todayPosition(s.Project.Phases, now)
+		deck.Slides = append(deck.Slides, slide)
+	}
+
+	return htmlTemplate.Execute(w, deck)
+}
+
+// statusClass returns the CSS class of a 燈號, as in status.ts
+func statusClass(status string) string {
+	switch {
+	case status == "":
+		return ""
+	case strings.Contains(status, parser.StatusRed):
+		return "red"
+	case strings.Contains(status, parser.StatusYellow):
+		return "yellow"
+	default:
+		return "green"
+	}
+}
+
+var htmlTemplate = template.Must(template.New("deck").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="generator" content="Sheltie">
+<title>{{.Title}}</title>
+<style>
+* { box-sizing: border-box; }
+body { margin: 0; padding: 24px 0; background: #e5e7eb; color: #1a1a1a;
+  font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", "PingFang TC", "Microsoft JhengHei", "Noto Sans TC", sans-serif; }
+.deck-title { width: 960px; margin: 0 auto 16px; color: #6b7280; font-size: 13px; }
+.slide { position: relative; width: 960px; height: 540px; margin: 0 auto 24px; padding: 28px 40px;
+  background: white; border-radius: 8px; box-shadow: 0 4px 6px rgba(0, 0, 0, 0.1); overflow: hidden;
+  display: flex; flex-direction: column; }
+.slide:focus { outline: 3px solid #2563eb; }
+.slide-title { font-size: 24px; font-weight: 700; }
+.slide-header { display: flex; justify-content: space-between; align-items: center;
+  margin-bottom: 8px; padding-bottom: 8px; border-bottom: 1px solid #e5e7eb; }
+.header-main { display: flex; align-items: center; gap: 8px; min-width: 0; }
+.header-tags { display: flex; align-items: center; gap: 6px; flex-wrap: wrap; justify-content: flex-end; }
+.tag { padding: 3px 10px; border-radius: 12px; font-size: 12px; font-weight: 500; white-space: nowrap; }
+.tag-state { background: #dbeafe; color: #1e40af; }
+.tag-progress { background: #dcfce7; color: #166534; }
+.tag-contact { background: #fef3c7; color: #92400e; }
+.tag-category { background: #f3e8ff; color: #7c3aed; }
+.status-light { font-size: 16px; }
+
+.summary-table { width: 100%; border-collapse: collapse; font-size: 15px; margin-top: 16px; }
+.summary-table th, .summary-table td { padding: 6px 10px; text-align: left; border: 1px solid #e5e7eb; }
+.summary-table th { background: #f9fafb; font-weight: 600; color: #374151; }
+.col-status, .col-progress { text-align: center !important; }
+.col-status { width: 70px; }
+.col-name { width: 34%; }
+.col-progress { width: 90px; }
+
+.slide-body { flex: 1; display: flex; flex-direction: column; gap: 8px; min-height: 0; }
+.departments { font-size: 14px; }
+.dept-label { color: #64748b; font-weight: 500; }
+.dept-value { color: #1e293b; }
+.dept-divider { color: #d1d5db; margin: 0 8px; }
+.section-label { font-size: 14px; font-weight: 600; color: #475569; margin-bottom: 4px; }
+
+.timeline-gantt { position: relative; height: 56px; margin-top: 8px; }
+.gantt-phases { position: relative; height: 44px; }
+.gantt-phase { position: absolute; top: 0; height: 100%; }
+.phase-arrow { height: 100%; display: flex; flex-direction: column; align-items: center; justify-content: center;
+  padding: 0 14px; color: white; font-size: 12px; font-weight: 600; overflow: hidden; white-space: nowrap;
+  clip-path: polygon(0 0, calc(100% - 10px) 0, 100% 50%, calc(100% - 10px) 100%, 0 100%, 10px 50%);
+  text-shadow: 0 1px 2px rgba(0, 0, 0, 0.3); }
+.gantt-phase.first .phase-arrow { clip-path: polygon(0 0, calc(100% - 10px) 0, 100% 50%, calc(100% - 10px) 100%, 0 100%); }
+.phase-dates { font-size: 10px; opacity: 0.9; font-weight: 400; }
+.today-pin { position: absolute; top: 0; bottom: 0; width: 2px; transform: translateX(-50%); z-index: 100; }
+.today-line { position: absolute; top: 0; bottom: 12px; width: 2px; background: #ef4444; }
+.today-marker { position: absolute; bottom: -4px; left: 50%; transform: translateX(-50%); font-size: 11px; }
+
+.bottom-section { display: flex; gap: 24px; flex: 1; min-height: 0; }
+.meetings-column { flex: 2; min-width: 0; }
+.notes-column { flex: 1; min-width: 0; padding-left: 16px; border-left: 1px solid #e5e7eb; }
+.meetings-list { display: flex; flex-direction: column; gap: 8px; }
+.meeting-entry { display: flex; gap: 4px; font-size: 14px; }
+.meeting-date { color: #6b7280; width: 92px; flex-shrink: 0; font-weight: 500; font-size: 12px; padding-top: 2px; }
+.meeting-lines { flex: 1; display: flex; flex-direction: column; gap: 2px; }
+.meeting-line { color: #1f2937; }
+.tracking { color: #dc2626 !important; font-weight: 700; }
+.planned { color: #2563eb !important; font-style: italic; }
+.meeting-entry.old .meeting-date, .meeting-entry.old .meeting-line { color: #9ca3af; }
+.notes-column ul { margin: 0; padding-left: 18px; list-style-type: disc; }
+.notes-column li { font-size: 14px; color: #4b5563; margin-bottom: 6px; line-height: 1.5; }
+.notes-column li ul { margin-top: 6px; }
+
+@media print {
+  @page { size: 10in 5.625in; margin: 0; }
+  body { padding: 0; background: white; }
+  .deck-title { display: none; }
+  .slide { margin: 0; border-radius: 0; box-shadow: none; page-break-after: always; break-after: page; }
+}
+</style>
+</head>
+<body>
+<div class="deck-title">{{.Title}} · {{.Exported}}</div>
+{{range .Slides}}{{if .Project}}{{with .Project}}
+<section class="slide project-slide" tabindex="-1">
+  <div class="slide-header">
+    <div class="header-main">
+      <span class="status-light {{statusClass .Status}}">{{statusIcon .Status}}</span>
+      <div class="slide-title">{{.Name}}</div>
+    </div>
+    <div class="header-tags">
+      <span class="tag tag-state">{{or .CurrentState "-"}}</span>
+      <span class="tag tag-progress">{{.Progress}}%</span>
+      {{- if .Contact}}
+      <span class="tag tag-contact">{{.Contact}}</span>
+      {{- end}}
+      {{- if .Category}}
+      <span class="tag tag-category">{{.Category}}</span>
+      {{- end}}
+    </div>
+  </div>
+  <div class="slide-body">
+    <div class="departments">
+      <span class="dept-label">主辦：</span><span class="dept-value">{{departments .Departments.Lead}}</span>
+      <span class="dept-divider">│</span>
+      <span class="dept-label">協辦：</span><span class="dept-value">{{departments .Departments.Support}}</span>
+    </div>
+{{end}}{{if .Bars}}
+    <div class="timeline-section">
+      <div class="section-label">時程</div>
+      <div class="timeline-gantt">
+        <div class="gantt-phases">
+          {{- range $i, $bar := .Bars}}
+          <div class="gantt-phase{{if eq $i 0}} first{{end}}" style="left: calc({{percent $bar.Left}} - {{if $i}}8{{else}}0{{end}}px); width: calc({{percent $bar.Width}} + {{if $i}}8{{else}}0{{end}}px);">
+            <div class="phase-arrow" style="background: linear-gradient(135deg, #{{$bar.Light}} 0%, #{{$bar.Color}} 100%);">
+              <span class="phase-name">{{$bar.Name}}</span>
+              <span class="phase-dates">{{$bar.Dates}}</span>
+            </div>
+          </div>
+          {{- end}}
+        </div>
+        {{- if .ShowToday}}
+        <div class="today-pin" style="left: {{percent .Today}};"><div class="today-line"></div><div class="today-marker">📍</div></div>
+        {{- end}}
+      </div>
+    </div>
+{{end}}{{with .Project}}
+    <div class="bottom-section">
+      <div class="meetings-column">
+        <div class="section-label">會辦狀況</div>
+        <div class="meetings-list">
+          {{- range limit 5 .Meetings}}
+          <div class="meeting-entry{{if .IsOld}} old{{end}}">
+            <div class="meeting-date">{{.Date}}</div>
+            <div class="meeting-lines">
+              {{- range .Lines}}
+              <div class="meeting-line{{if .IsTracking}} tracking{{end}}{{if .IsPlanned}} planned{{end}}">{{.Text}}</div>
+              {{- end}}
+            </div>
+          </div>
+          {{- end}}
+        </div>
+      </div>
+      {{- if .Notes}}
+      <div class="notes-column">
+        <div class="section-label">其他補充事項</div>
+        <ul>
+          {{- range limit 5 .Notes}}
+          <li class="{{if .IsTracking}}tracking{{end}}{{if .IsPlanned}}planned{{end}}">{{.Text}}
+            {{- if .Children}}
+            <ul>
+              {{- range .Children}}
+              <li class="{{if .IsTracking}}tracking{{end}}{{if .IsPlanned}}planned{{end}}">{{.Text}}</li>
+              {{- end}}
+            </ul>
+            {{- end}}
+          </li>
+          {{- end}}
+        </ul>
+      </div>
+      {{- end}}
+    </div>
+  </div>
+</section>
+{{end}}{{else}}
+<section class="slide summary-slide" tabindex="-1">
+  <div class="slide-title">{{summaryTitle .Page}}</div>
+  <table class="summary-table">
+    <thead>
+      <tr><th class="col-status">燈號</th><th class="col-name">專案名稱</th><th class="col-state">狀態</th><th class="col-progress">進度</th><th class="col-contact">窗口</th></tr>
+    </thead>
+    <tbody>
+      {{- range .Projects}}
+      <tr>
+        <td class="col-status"><span class="status-light {{statusClass .Status}}">{{statusIcon .Status}}</span></td>
+        <td>{{.Name}}</td>
+        <td>{{.CurrentState}}</td>
+        <td class="col-progress">{{.Progress}}%</td>
+        <td>{{.Contact}}</td>
+      </tr>
+      {{- end}}
+    </tbody>
+  </table>
+</section>
+{{end}}{{end}}
+<script>
+document.addEventListener('keydown', function (e) {
+  var step = { ArrowRight: 1, ArrowDown: 1, PageDown: 1, ' ': 1, ArrowLeft: -1, ArrowUp: -1, PageUp: -1 }[e.key];
+  if (!step) return;
+  var slides = Array.prototype.slice.call(document.querySelectorAll('.slide'));
+  var current = slides.indexOf(document.activeElement);
+  if (current < 0) {
+    current = slides.findIndex(function (s) { return s.getBoundingClientRect().bottom > 1; }) - step;
+  }
+  var next = slides[Math.min(slides.length - 1, Math.max(0, current + step))];
+  if (next) {
+    e.preventDefault();
+    next.focus({ preventScroll: true });
+    next.scrollIntoView({ behavior: 'smooth', block: 'center' });
+  }
+});
+</script>
+</body>
+</html>
+`))
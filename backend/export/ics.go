@@ -0,0 +1,123 @@
+package export
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// icsLineLimit is the maximum length of a content line in octets (RFC 5545)
+const icsLineLimit = 75
+
+// ICS writes an iCalendar feed with an all-day event for every phase. Each
+// event's UID derives from the workspace, project and phase names, so a
+// calendar subscription updates moved phases instead of duplicating them.
+// Go-live phases are marked with the GO-LIVE category.
+func ICS(w io.Writer, name string, sources []WorkspaceProjects, now time.Time) error {
+	var b strings.Builder
+	line := func(s string) {
+		writeFolded(&b, s)
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Sheltie//Project Phases//ZH-TW")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + icsEscape(name))
+
+	stamp := now.UTC().Format("20060102T150405Z")
+	for _, src := range sources {
+		for _, p := range src.Projects {
+			seen := map[string]int{}
+			for _, phase := range p.Phases {
+				start, errStart := time.Parse("2006-01-02", phase.StartDate)
+				end, errEnd := time.Parse("2006-01-02", phase.EndDate)
+				if errStart != nil {
+					continue
+				}
+				if errEnd != nil || end.Before(start) {
+					end = start
+				}
+
+				// a phase name repeated within a project gets its own UID
+				seen[phase.Name]++
+				key := phase.Name
+				if n := seen[phase.Name]; n > 1 {
+					key = fmt.Sprintf("%s#%d", phase.Name, n)
+				}
+
+				summary := p.Name + " · " + phase.Name
+				category := "PHASE"
+				if parser.IsGoLivePhase(phase.Name) {
+					summary = "🚀 " + summary
+					category = "GO-LIVE"
+				}
+
+				desc := []string{"工作區: " + src.WorkspaceName}
+				if p.Status != "" {
+					desc = append(desc, "燈號: "+p.Status)
+				}
+				if p.CurrentState != "" {
+					desc = append(desc, "目前狀態: "+p.CurrentState)
+				}
+				if p.Contact != "" {
+					desc = append(desc, "窗口: "+p.Contact)
+				}
+				if len(phase.Assignees) > 0 {
+					desc = append(desc, "人員: "+strings.Join(phase.Assignees, ", "))
+				}
+
+				line("BEGIN:VEVENT")
+				line("UID:" + phaseUID(src.WorkspaceID, p.Name, key))
+				line("DTSTAMP:" + stamp)
+				line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+				line("DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format("20060102"))
+				line("SUMMARY:" + icsEscape(summary))
+				line("DESCRIPTION:" + icsEscape(strings.Join(desc, "\n")))
+				line("CATEGORIES:" + category)
+				line("TRANSP:TRANSPARENT")
+				line("END:VEVENT")
+			}
+		}
+	}
+
+	line("END:VCALENDAR")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// phaseUID returns a stable UID for a phase of a project in a workspace
+func phaseUID(workspaceID, project, phase string) string {
+	sum := sha1.Sum([]byte(workspaceID + "\x00" + project + "\x00" + phase))
+	return hex.EncodeToString(sum[:]) + "@sheltie"
+}
+
+// icsEscape escapes a TEXT value
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// writeFolded writes a content line, folding it into lines of at most 75
+// octets without splitting a UTF-8 character
+func writeFolded(b *strings.Builder, s string) {
+	limit := icsLineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = icsLineLimit - 1 // the leading space counts
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,85 @@
+package export
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+var uidRe = regexp.MustCompile(`(?m)^UID:(.+)\r$`)
+
+// icsUIDs exports the sources and returns the UID of each event, in order
+func icsUIDs(t *testing.T, sources []WorkspaceProjects, now time.Time) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := ICS(&buf, "PMO", sources, now); err != nil {
+		t.Fatal(err)
+	}
+	var uids []string
+	for _, m := range uidRe.FindAllStringSubmatch(buf.String(), -1) {
+		uids = append(uids, m[1])
+	}
+	return uids
+}
+
+func TestICSUIDsAreStable(t *testing.T) {
+	doc := "# 報表平台\n## 基本資訊\n- 時程:\n  - 開發: 2025-03-01 ~ 2025-04-30\n  - SIT: 2025-05-01 ~ 2025-05-31\n  - SIT: 2025-07-01 ~ 2025-07-15\n"
+	moved := strings.NewReplacer("2025-05-31", "2025-06-30", "2025-03-01", "2025-02-15").Replace(doc)
+	sources := func(doc string) []WorkspaceProjects {
+		return []WorkspaceProjects{{WorkspaceID: "w1", WorkspaceName: "PMO", Projects: parser.Parse(doc)}}
+	}
+
+	first := icsUIDs(t, sources(doc), time.Date(2025, 5, 1, 9, 0, 0, 0, time.UTC))
+	if len(first) != 3 {
+		t.Fatalf("%d events, want 3", len(first))
+	}
+	seen := map[string]bool{}
+	for _, uid := range first {
+		if seen[uid] {
+			t.Errorf("UID %s is used twice", uid)
+		}
+		seen[uid] = true
+	}
+
+	// a later export, with phases moved, updates the same events
+	again := icsUIDs(t, sources(moved), time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC))
+	if strings.Join(again, " ") != strings.Join(first, " ") {
+		t.Errorf("UIDs changed across exports:\n%v\n%v", first, again)
+	}
+
+	// the same project in another workspace is a different event
+	other := icsUIDs(t, []WorkspaceProjects{{WorkspaceID: "w2", Projects: parser.Parse(doc)}}, time.Now())
+	for _, uid := range other {
+		if seen[uid] {
+			t.Errorf("UID %s is shared with another workspace", uid)
+		}
+	}
+}
+
+func TestICSFoldsLongLines(t *testing.T) {
+	doc := "# " + strings.Repeat("很長的專案名稱", 10) + "\n## 基本資訊\n- 時程:\n  - PROD: 2025-06-15\n"
+	var buf bytes.Buffer
+	if err := ICS(&buf, "PMO", []WorkspaceProjects{{WorkspaceID: "w1", Projects: parser.Parse(doc)}}, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.HasSuffix(out, "END:VCALENDAR\r\n") {
+		t.Error("calendar does not end with a CRLF terminated END:VCALENDAR")
+	}
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > icsLineLimit {
+			t.Errorf("line of %d octets: %q", len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("folding split a character: %q", l)
+		}
+	}
+	if unfolded := strings.ReplaceAll(out, "\r\n ", ""); !strings.Contains(unfolded, strings.Repeat("很長的專案名稱", 10)) {
+		t.Error("summary does not unfold to the project name")
+	}
+}
@@ -0,0 +1,166 @@
+package export
+
+import (
+	"fmt"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// canvas is a slide surface; positions and sizes are in inches from the top
+// left of a 10in × 5.625in slide, as in the browser export
+type canvas interface {
+	text(x, y, w, h float64, opts textOpts, paras ...para)
+	shape(prst string, x, y, w, h float64, fill string, opts textOpts, paras ...para)
+	line(x, y, w, h float64, color string)
+	table(x, y float64, colW []float64, rowH float64, size int, rows [][]cell)
+}
+
+// run is a span of text with a single format
+type run struct {
+	text   string
+	size   int // points
+	bold   bool
+	italic bool
+	color  string
+}
+
+// para is a paragraph of runs
+type para struct {
+	runs []run
+}
+
+// textOpts sets the alignment of a text body: algn (l, ctr, r) and anchor (t, ctr, b)
+type textOpts struct {
+	align  string
+	anchor string
+}
+
+// cell is a table cell
+type cell struct {
+	text  string
+	bold  bool
+	align string
+	fill  string
+}
+
+// summarySlide renders the n-th summary table
+func summarySlide(s canvas, n int, projects []parser.Project) {
+	s.text(0.5, 0.3, 9.0, 0.5, textOpts{}, para{runs: []run{{text: summaryTitle(n), size: 24, bold: true, color: colorText}}})
+
+	header := func(text string) cell {
+		return cell{text: text, bold: true, fill: colorBg}
+	}
+	rows := [][]cell{{header("燈號"), header("專案名稱"), header("狀態"), header("進度"), header("窗口")}}
+	for _, p := range projects {
+		rows = append(rows, []cell{
+			{text: statusIcon(p.Status), align: "ctr"},
+			{text: p.Name},
+			{text: p.CurrentState},
+			{text: fmt.Sprintf("%d%%", p.Progress), align: "ctr"},
+			{text: p.Contact},
+		})
+	}
+	s.table(0.5, 1.0, []float64{0.8, 3.0, 2.0, 1.0, 2.2}, 0.35, 11, rows)
+}
+
+// projectSlide renders the details of one project
+func projectSlide(s canvas, p parser.Project) {
+	s.text(0.5, 0.25, 5.5, 0.5, textOpts{}, para{runs: []run{{text: statusIcon(p.Status) + " " + p.Name, size: 22, bold: true, color: colorText}}})
+
+	// Header tags
+	tagX, tagY, tagH := 6.0, 0.3, 0.35
+	tag := func(kind, text string, w float64) {
+		c := tagColors[kind]
+		s.shape("roundRect", tagX, tagY, w, tagH, c[0], textOpts{align: "ctr", anchor: "ctr"},
+			para{runs: []run{{text: text, size: 10, color: c[1]}}})
+		tagX += w + 0.1
+	}
+	if p.CurrentState != "" {
+		tag("state", p.CurrentState, tagWidth(p.CurrentState))
+	}
+	tag("progress", fmt.Sprintf("%d%%", p.Progress), 0.6)
+	if p.Contact != "" {
+		tag("contact", p.Contact, tagWidth(p.Contact))
+	}
+	if p.Category != "" {
+		tag("category", p.Category, tagWidth(p.Category))
+	}
+
+	s.line(0.5, 0.8, 9.0, 0, colorBorder)
+
+	s.text(0.5, 0.9, 9.0, 0.35, textOpts{}, para{runs: []run{
+		{text: "主辦：", size: 11, bold: true, color: colorMuted},
+		{text: departments(p.Departments.Lead), size: 11, color: colorText},
+		{text: "  │  ", size: 11, color: colorDivider},
+		{text: "協辦：", size: 11, bold: true, color: colorMuted},
+		{text: departments(p.Departments.Support), size: 11, color: colorText},
+	}})
+
+	// Timeline gantt chart
+	yPos := 1.35
+	if len(p.Phases) > 0 {
+		s.text(0.5, yPos, 9.0, 0.3, textOpts{}, label("時程"))
+		yPos += 0.35
+
+		const arrowHeight, overlap = 0.45, 0.1
+		x := 0.5
+		for i, w := range phaseWidths(p.Phases, 9.0, overlap) {
+			phase := p.Phases[i]
+			s.shape("chevron", x, yPos, w, arrowHeight, phaseColor(i), textOpts{align: "ctr", anchor: "ctr"},
+				para{runs: []run{{text: phase.Name, size: 9, bold: true, color: "FFFFFF"}}},
+				para{runs: []run{{text: phaseDateShort(phase), size: 8, color: "FFFFFF"}}})
+			x += w - overlap
+		}
+		yPos += arrowHeight + 0.25
+	}
+
+	// Meetings (left) and notes (right)
+	bottomY := yPos + 0.1
+	const meetingsWidth, notesWidth = 6.0, 2.8
+	notesX := 0.5 + meetingsWidth + 0.2
+
+	s.text(0.5, bottomY, meetingsWidth, 0.3, textOpts{}, label("會辦狀況"))
+	meetingY := bottomY + 0.35
+	for _, m := range p.Meetings[:min(len(p.Meetings), maxMeetings)] {
+		dateColor := colorText
+		if m.IsOld {
+			dateColor = colorMuted
+		}
+		s.text(0.5, meetingY, 1.0, 0.28, textOpts{}, para{runs: []run{{text: m.Date, size: 9, bold: true, color: dateColor}}})
+
+		for _, l := range m.Lines[:min(len(m.Lines), maxMeetingLines)] {
+			s.text(1.5, meetingY, 4.5, 0.28, textOpts{}, para{runs: []run{{
+				text:   l.Text,
+				size:   10,
+				bold:   l.IsTracking,
+				italic: l.IsPlanned,
+				color:  lineColor(l.IsTracking, l.IsPlanned, m.IsOld),
+			}}})
+			meetingY += 0.28
+		}
+		meetingY += 0.05
+	}
+
+	if len(p.Notes) > 0 {
+		s.line(notesX-0.15, bottomY, 0, 2.5, colorBorder)
+		s.text(notesX, bottomY, notesWidth, 0.3, textOpts{}, label("其他補充事項"))
+
+		noteY := bottomY + 0.35
+		for _, n := range p.Notes[:min(len(p.Notes), maxNotes)] {
+			s.text(notesX, noteY, notesWidth, 0.28, textOpts{}, para{runs: []run{{
+				text:   "• " + n.Text,
+				size:   10,
+				bold:   n.IsTracking,
+				italic: n.IsPlanned,
+				color:  lineColor(n.IsTracking, n.IsPlanned, false),
+			}}})
+			noteY += 0.3
+		}
+	}
+
+}
+
+// label is a section heading on a project slide
+func label(text string) para {
+	return para{runs: []run{{text: text, size: 12, bold: true, color: colorMuted}}}
+}
@@ -0,0 +1,366 @@
+package export
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// PDF page size in points, the same 16:9 slide as the PPTX deck
+const (
+	pdfPointsPerInch = 72
+	pdfPageWidth     = 10 * pdfPointsPerInch
+	pdfPageHeight    = 5.625 * pdfPointsPerInch
+)
+
+// The PDF uses MSung-Light, a standard Traditional Chinese CID font that PDF
+// readers provide themselves, so no font has to be embedded. It covers
+// Big5/CNS 11643 and ASCII; other characters are dropped.
+const (
+	pdfFontName   = "MSung-Light"
+	pdfItalicSkew = 0.21
+	pdfAscent     = 0.88
+)
+
+// statusGlyphs replaces the status emoji, which the font lacks, with a colored dot
+var statusGlyphs = map[rune]string{
+	'🔴': statusColor(parser.StatusRed),
+	'🟡': statusColor(parser.StatusYellow),
+	'🟢': statusColor(parser.StatusGreen),
+}
+
+// PDF writes the projects as a PDF handout with the same pages as the PPTX
+// deck: summary tables followed by one page per project
+func PDF(w io.Writer, title string, projects []parser.Project) error {
+	var pages []*pdfPage
+	page := 0
+	for _, s := range parser.GenerateSlides(projects) {
+		p := &pdfPage{}
+		if s.Type == parser.SlideSummary {
+			summarySlide(p, page, s.Projects)
+			page++
+		} else {
+			projectSlide(p, *s.Project)
+		}
+		pages = append(pages, p)
+	}
+	if len(pages) == 0 {
+		pages = append(pages, &pdfPage{}) // a PDF needs at least one page
+	}
+
+	doc := &pdfWriter{}
+	doc.add("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 7+2*i)
+	}
+	doc.add(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	doc.add("<< /Type /Font /Subtype /Type0 /BaseFont /" + pdfFontName + " /Encoding /UniCNS-UCS2-H /DescendantFonts [4 0 R] >>")
+	doc.add("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /" + pdfFontName + " /CIDSystemInfo << /Registry (Adobe) /Ordering (CNS1) /Supplement 0 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+	doc.add("<< /Type /FontDescriptor /FontName /" + pdfFontName + " /Flags 6 /FontBBox [-160 -249 1015 1071] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+	doc.add("<< /Title " + pdfTextString(title) + " /Creator (Sheltie) /Producer (Sheltie) >>")
+
+	for i, p := range pages {
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		zw.Write(p.ops.Bytes())
+		zw.Close()
+
+		doc.add(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			num(pdfPageWidth), num(pdfPageHeight), 8+2*i))
+		doc.add(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", z.Len(), z.Bytes()))
+	}
+
+	_, err := w.Write(doc.bytes(6))
+	return err
+}
+
+// pdfWriter numbers objects from 1 and writes the cross-reference table
+type pdfWriter struct {
+	objects []string
+}
+
+func (d *pdfWriter) add(obj string) {
+	d.objects = append(d.objects, obj)
+}
+
+// bytes returns the file; info is the object number of the document information
+func (d *pdfWriter) bytes(info int) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(d.objects))
+	for i, obj := range d.objects {
+		offsets[i] = b.Len()
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(d.objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.objects)+1, info, xref)
+	return b.Bytes()
+}
+
+// pdfPage draws a slide into a PDF content stream
+type pdfPage struct {
+	ops bytes.Buffer
+}
+
+func (p *pdfPage) text(x, y, w, h float64, opts textOpts, paras ...para) {
+	p.textBody(x+0.05, y+0.025, w-0.1, h-0.05, opts, paras)
+}
+
+func (p *pdfPage) shape(prst string, x, y, w, h float64, fill string, opts textOpts, paras ...para) {
+	p.fillColor(fill)
+	switch prst {
+	case "roundRect":
+		p.roundRect(x, y, w, h, 0.16667*min(w, h))
+	case "chevron":
+		d := 0.5 * min(w, h)
+		p.polygon([][2]float64{{x, y}, {x + w - d, y}, {x + w, y + h/2}, {x + w - d, y + h}, {x, y + h}, {x + d, y + h/2}})
+	default:
+		p.polygon([][2]float64{{x, y}, {x + w, y}, {x + w, y + h}, {x, y + h}})
+	}
+	p.ops.WriteString("f\n")
+	p.text(x, y, w, h, opts, paras...)
+}
+
+func (p *pdfPage) line(x, y, w, h float64, color string) {
+	p.strokeColor(color)
+	fmt.Fprintf(&p.ops, "1 w %s %s m %s %s l S\n", px(x), py(y), px(x+w), py(y+h))
+}
+
+func (p *pdfPage) table(x, y float64, colW []float64, rowH float64, size int, rows [][]cell) {
+	for r, row := range rows {
+		cx := x
+		cy := y + float64(r)*rowH
+		for c, cl := range row {
+			if c >= len(colW) {
+				break
+			}
+			cw := colW[c]
+			if cl.fill != "" {
+				p.fillColor(cl.fill)
+				fmt.Fprintf(&p.ops, "%s %s %s %s re f\n", px(cx), py(cy+rowH), pt(cw), pt(rowH))
+			}
+			p.strokeColor(colorBorder)
+			fmt.Fprintf(&p.ops, "0.5 w %s %s %s %s re S\n", px(cx), py(cy+rowH), pt(cw), pt(rowH))
+			p.textBody(cx+0.1, cy+0.05, cw-0.2, rowH-0.1, textOpts{align: cl.align, anchor: "ctr"},
+				[]para{{runs: []run{{text: cl.text, size: size, bold: cl.bold, color: colorText}}}})
+			cx += cw
+		}
+	}
+}
+
+// textBody lays out paragraphs of single lines inside a box, truncating
+// lines that do not fit
+func (p *pdfPage) textBody(x, y, w, h float64, opts textOpts, paras []para) {
+	heights := make([]float64, len(paras))
+	total := 0.0
+	for i, para := range paras {
+		size := 0
+		for _, r := range para.runs {
+			size = max(size, r.size)
+		}
+		heights[i] = float64(size) * 1.2 / pdfPointsPerInch
+		total += heights[i]
+	}
+
+	top := y
+	switch opts.anchor {
+	case "ctr":
+		top = y + (h-total)/2
+	case "b":
+		top = y + h - total
+	}
+
+	for i, para := range paras {
+		runs := fitRuns(pdfRuns(para.runs), w)
+		width := 0.0
+		for _, r := range runs {
+			width += textWidth(r.text, r.size)
+		}
+		left := x
+		switch opts.align {
+		case "ctr":
+			left = x + (w-width)/2
+		case "r":
+			left = x + w - width
+		}
+
+		// the baseline sits below the top of the line by the ascent plus
+		// half of the extra line spacing
+		baseline := top + heights[i]/1.2*(pdfAscent+0.1)
+		for _, r := range runs {
+			p.run(left, baseline, r)
+			left += textWidth(r.text, r.size)
+		}
+		top += heights[i]
+	}
+}
+
+// run draws a run with its baseline at y
+func (p *pdfPage) run(x, y float64, r run) {
+	if r.text == "" {
+		return
+	}
+	color := r.color
+	if color == "" {
+		color = colorText
+	}
+	p.fillColor(color)
+	p.ops.WriteString("BT /F1 " + strconv.Itoa(r.size) + " Tf ")
+	if r.bold {
+		// fake bold by stroking the glyph outlines
+		p.strokeColor(color)
+		fmt.Fprintf(&p.ops, "2 Tr %s w ", num(float64(r.size)*0.03))
+	} else {
+		p.ops.WriteString("0 Tr ")
+	}
+	skew := 0.0
+	if r.italic {
+		skew = pdfItalicSkew
+	}
+	fmt.Fprintf(&p.ops, "1 0 %s 1 %s %s Tm <%s> Tj ET\n", num(skew), px(x), py(y), ucs2(r.text))
+}
+
+func (p *pdfPage) roundRect(x, y, w, h, r float64) {
+	const k = 0.5523 // control point distance for a quarter circle
+	fmt.Fprintf(&p.ops, "%s %s m ", px(x+r), py(y))
+	fmt.Fprintf(&p.ops, "%s %s l ", px(x+w-r), py(y))
+	fmt.Fprintf(&p.ops, "%s %s %s %s %s %s c ", px(x+w-r+k*r), py(y), px(x+w), py(y+r-k*r), px(x+w), py(y+r))
+	fmt.Fprintf(&p.ops, "%s %s l ", px(x+w), py(y+h-r))
+	fmt.Fprintf(&p.ops, "%s %s %s %s %s %s c ", px(x+w), py(y+h-r+k*r), px(x+w-r+k*r), py(y+h), px(x+w-r), py(y+h))
+	fmt.Fprintf(&p.ops, "%s %s l ", px(x+r), py(y+h))
+	fmt.Fprintf(&p.ops, "%s %s %s %s %s %s c ", px(x+r-k*r), py(y+h), px(x), py(y+h-r+k*r), px(x), py(y+h-r))
+	fmt.Fprintf(&p.ops, "%s %s l ", px(x), py(y+r))
+	fmt.Fprintf(&p.ops, "%s %s %s %s %s %s c h ", px(x), py(y+r-k*r), px(x+r-k*r), py(y), px(x+r), py(y))
+}
+
+func (p *pdfPage) polygon(points [][2]float64) {
+	for i, pnt := range points {
+		op := "l"
+		if i == 0 {
+			op = "m"
+		}
+		fmt.Fprintf(&p.ops, "%s %s %s ", px(pnt[0]), py(pnt[1]), op)
+	}
+	p.ops.WriteString("h ")
+}
+
+func (p *pdfPage) fillColor(color string) {
+	r, g, b := rgb(color)
+	fmt.Fprintf(&p.ops, "%s %s %s rg ", num(r), num(g), num(b))
+}
+
+func (p *pdfPage) strokeColor(color string) {
+	r, g, b := rgb(color)
+	fmt.Fprintf(&p.ops, "%s %s %s RG ", num(r), num(g), num(b))
+}
+
+// pdfRuns prepares runs for the PDF font: status emoji become colored dots
+// and characters outside the Basic Multilingual Plane are dropped
+func pdfRuns(runs []run) []run {
+	var out []run
+	for _, r := range runs {
+		var text strings.Builder
+		flush := func() {
+			if text.Len() > 0 {
+				out = append(out, run{text: text.String(), size: r.size, bold: r.bold, italic: r.italic, color: r.color})
+				text.Reset()
+			}
+		}
+		for _, c := range r.text {
+			if color, ok := statusGlyphs[c]; ok {
+				flush()
+				out = append(out, run{text: "●", size: r.size, color: color})
+				continue
+			}
+			if c > 0xFFFF || (c >= 0xFE00 && c <= 0xFE0F) || c < 0x20 {
+				continue
+			}
+			text.WriteRune(c)
+		}
+		flush()
+	}
+	return out
+}
+
+// fitRuns truncates runs with an ellipsis so that they fit in w inches
+func fitRuns(runs []run, w float64) []run {
+	used := 0.0
+	for i, r := range runs {
+		rw := textWidth(r.text, r.size)
+		if used+rw <= w {
+			used += rw
+			continue
+		}
+		text := []rune(r.text)
+		for len(text) > 0 && used+textWidth(string(text)+"…", r.size) > w {
+			text = text[:len(text)-1]
+		}
+		r.text = string(text) + "…"
+		return append(runs[:i:i], r)
+	}
+	return runs
+}
+
+// textWidth returns the width in inches of text in the PDF font: ASCII is half width
+func textWidth(text string, size int) float64 {
+	em := 0.0
+	for _, c := range text {
+		if c < 0x7F {
+			em += 0.5
+		} else {
+			em++
+		}
+	}
+	return em * float64(size) / pdfPointsPerInch
+}
+
+// ucs2 hex-encodes text as big-endian UTF-16 for the UniCNS-UCS2-H encoding
+func ucs2(text string) string {
+	var b strings.Builder
+	for _, u := range utf16.Encode([]rune(text)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	return b.String()
+}
+
+// pdfTextString encodes text as a PDF text string in UTF-16 with a byte order mark
+func pdfTextString(text string) string {
+	return "<FEFF" + ucs2(text) + ">"
+}
+
+// rgb splits an RRGGBB color into components between 0 and 1
+func rgb(color string) (float64, float64, float64) {
+	v, err := strconv.ParseUint(color, 16, 32)
+	if err != nil || len(color) != 6 {
+		return 0, 0, 0
+	}
+	return float64(v>>16&0xFF) / 255, float64(v>>8&0xFF) / 255, float64(v&0xFF) / 255
+}
+
+// px and py convert a slide position in inches to PDF coordinates, which
+// start at the bottom left; pt converts a length
+func px(x float64) string { return num(x * pdfPointsPerInch) }
+func py(y float64) string { return num(pdfPageHeight - y*pdfPointsPerInch) }
+func pt(v float64) string { return num(v * pdfPointsPerInch) }
+
+// num formats a number for a content stream
+func num(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" {
+		return "0"
+	}
+	return s
+}
@@ -0,0 +1,68 @@
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var (
+	startxrefRe = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+	xrefEntryRe = regexp.MustCompile(`^(\d{10}) (\d{5}) ([nf]) \n$`)
+	streamRe    = regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+)
+
+func TestPDFCrossReferences(t *testing.T) {
+	for _, n := range []int{0, 1, 12} {
+		var buf bytes.Buffer
+		if err := PDF(&buf, "報表 (PMO) \\ deck", testProjects(n)); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+
+		m := startxrefRe.FindSubmatch(data)
+		if m == nil {
+			t.Fatalf("%d projects: no startxref at the end", n)
+		}
+		xref, _ := strconv.Atoi(string(m[1]))
+		if !bytes.HasPrefix(data[xref:], []byte("xref\n0 ")) {
+			t.Fatalf("%d projects: startxref %d does not point at the xref table", n, xref)
+		}
+
+		// xref\n0 <size>\n, then 20-byte entries
+		rest := data[xref+len("xref\n"):]
+		header := rest[:bytes.IndexByte(rest, '\n')+1]
+		var size int
+		if _, err := fmt.Sscanf(string(header), "0 %d\n", &size); err != nil {
+			t.Fatal(err)
+		}
+		rest = rest[len(header):]
+		for i := 0; i < size; i++ {
+			entry := xrefEntryRe.FindSubmatch(rest[:20])
+			if entry == nil {
+				t.Fatalf("%d projects: xref entry %d is %q", n, i, rest[:20])
+			}
+			rest = rest[20:]
+			if i == 0 {
+				continue
+			}
+			offset, _ := strconv.Atoi(string(entry[1]))
+			if want := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(data[offset:], []byte(want)) {
+				t.Errorf("%d projects: object %d at %d starts %q", n, i, offset, data[offset:offset+len(want)])
+			}
+		}
+		if !bytes.HasPrefix(rest, []byte(fmt.Sprintf("trailer\n<< /Size %d ", size))) {
+			t.Errorf("%d projects: trailer does not give /Size %d", n, size)
+		}
+
+		// each stream is as long as its /Length says
+		for _, loc := range streamRe.FindAllSubmatchIndex(data, -1) {
+			length, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+			if end := loc[1] + length; !bytes.HasPrefix(data[end:], []byte("\nendstream")) {
+				t.Errorf("%d projects: stream at %d is not %d bytes long", n, loc[1], length)
+			}
+		}
+	}
+}
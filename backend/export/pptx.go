@@ -0,0 +1,248 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// emu is the number of English Metric Units in an inch
+const emu = 914400
+
+// Slide size, 16:9 (10in × 5.625in)
+const (
+	slideWidth  = 10 * emu
+	slideHeight = 5143500
+)
+
+// pptxFont is the typeface of every run, as in the browser export
+const pptxFont = "Arial"
+
+// PPTX writes the projects as a PowerPoint deck: summary slides of
+// parser.SummaryPageSize projects followed by one slide per project
+func PPTX(w io.Writer, title string, projects []parser.Project) error {
+	slides := parser.GenerateSlides(projects)
+
+	zw := zip.NewWriter(w)
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", pptxContentTypes(len(slides))},
+		{"_rels/.rels", pptxRootRels},
+		{"docProps/core.xml", pptxCore(title, time.Now())},
+		{"docProps/app.xml", pptxApp(len(slides))},
+		{"ppt/presentation.xml", pptxPresentation(len(slides))},
+		{"ppt/_rels/presentation.xml.rels", pptxPresentationRels(len(slides))},
+		{"ppt/presProps.xml", pptxPresProps},
+		{"ppt/viewProps.xml", pptxViewProps},
+		{"ppt/tableStyles.xml", pptxTableStyles},
+		{"ppt/theme/theme1.xml", pptxTheme},
+		{"ppt/slideMasters/slideMaster1.xml", pptxSlideMaster},
+		{"ppt/slideMasters/_rels/slideMaster1.xml.rels", pptxSlideMasterRels},
+		{"ppt/slideLayouts/slideLayout1.xml", pptxSlideLayout},
+		{"ppt/slideLayouts/_rels/slideLayout1.xml.rels", pptxSlideLayoutRels},
+	}
+
+	page := 0
+	for i, s := range slides {
+		sb := newSlide()
+		if s.Type == parser.SlideSummary {
+			summarySlide(sb, page, s.Projects)
+			page++
+		} else {
+			projectSlide(sb, *s.Project)
+		}
+		files = append(files,
+			struct{ name, body string }{fmt.Sprintf("ppt/slides/slide%d.xml", i+1), sb.xml()},
+			struct{ name, body string }{fmt.Sprintf("ppt/slides/_rels/slide%d.xml.rels", i+1), pptxSlideRels},
+		)
+	}
+
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// slideBuilder collects the shapes of a slide
+type slideBuilder struct {
+	shapes strings.Builder
+	nextID int
+}
+
+func newSlide() *slideBuilder {
+	return &slideBuilder{nextID: 2} // 1 is the group shape
+}
+
+// id returns the next shape id
+func (s *slideBuilder) id() int {
+	id := s.nextID
+	s.nextID++
+	return id
+}
+
+// text adds a text box without fill
+func (s *slideBuilder) text(x, y, w, h float64, opts textOpts, paras ...para) {
+	s.sp("rect", true, x, y, w, h, "", opts, paras)
+}
+
+// shape adds a filled preset shape with text
+func (s *slideBuilder) shape(prst string, x, y, w, h float64, fill string, opts textOpts, paras ...para) {
+	s.sp(prst, false, x, y, w, h, fill, opts, paras)
+}
+
+func (s *slideBuilder) sp(prst string, textBox bool, x, y, w, h float64, fill string, opts textOpts, paras []para) {
+	id := s.id()
+	b := &s.shapes
+	fmt.Fprintf(b, `<p:sp><p:nvSpPr><p:cNvPr id="%d" name="Shape %d"/>`, id, id)
+	if textBox {
+		b.WriteString(`<p:cNvSpPr txBox="1"/>`)
+	} else {
+		b.WriteString(`<p:cNvSpPr/>`)
+	}
+	b.WriteString(`<p:nvPr/></p:nvSpPr><p:spPr>`)
+	writeXfrm(b, x, y, w, h)
+	fmt.Fprintf(b, `<a:prstGeom prst="%s"><a:avLst/></a:prstGeom>`, prst)
+	if fill != "" {
+		b.WriteString(solidFill(fill) + `<a:ln>` + solidFill(fill) + `</a:ln>`)
+	} else {
+		b.WriteString(`<a:noFill/>`)
+	}
+	b.WriteString(`</p:spPr>`)
+
+	anchor := opts.anchor
+	if anchor == "" {
+		anchor = "t"
+	}
+	fmt.Fprintf(b, `<p:txBody><a:bodyPr wrap="square" lIns="45720" tIns="22860" rIns="45720" bIns="22860" anchor="%s" rtlCol="0"><a:noAutofit/></a:bodyPr><a:lstStyle/>`, anchor)
+	for _, p := range paras {
+		writePara(b, p, opts.align)
+	}
+	b.WriteString(`</p:txBody></p:sp>`)
+}
+
+// line adds a straight line from (x, y) spanning w × h
+func (s *slideBuilder) line(x, y, w, h float64, color string) {
+	id := s.id()
+	b := &s.shapes
+	fmt.Fprintf(b, `<p:cxnSp><p:nvCxnSpPr><p:cNvPr id="%d" name="Line %d"/><p:cNvCxnSpPr/><p:nvPr/></p:nvCxnSpPr><p:spPr>`, id, id)
+	writeXfrm(b, x, y, w, h)
+	b.WriteString(`<a:prstGeom prst="line"><a:avLst/></a:prstGeom><a:ln w="12700">` + solidFill(color) + `</a:ln></p:spPr></p:cxnSp>`)
+}
+
+// table adds a table with the given column widths and row height
+func (s *slideBuilder) table(x, y float64, colW []float64, rowH float64, size int, rows [][]cell) {
+	id := s.id()
+	b := &s.shapes
+	w := 0.0
+	for _, cw := range colW {
+		w += cw
+	}
+
+	fmt.Fprintf(b, `<p:graphicFrame><p:nvGraphicFramePr><p:cNvPr id="%d" name="Table %d"/><p:cNvGraphicFramePr><a:graphicFrameLocks noGrp="1"/></p:cNvGraphicFramePr><p:nvPr/></p:nvGraphicFramePr>`, id, id)
+	fmt.Fprintf(b, `<p:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></p:xfrm>`, inch(x), inch(y), inch(w), inch(rowH*float64(len(rows))))
+	b.WriteString(`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/table"><a:tbl><a:tblPr firstRow="1"/><a:tblGrid>`)
+	for _, cw := range colW {
+		fmt.Fprintf(b, `<a:gridCol w="%d"/>`, inch(cw))
+	}
+	b.WriteString(`</a:tblGrid>`)
+
+	border := solidFill(colorBorder)
+	for _, row := range rows {
+		fmt.Fprintf(b, `<a:tr h="%d">`, inch(rowH))
+		for _, c := range row {
+			b.WriteString(`<a:tc><a:txBody><a:bodyPr/><a:lstStyle/>`)
+			writePara(b, para{runs: []run{{text: c.text, size: size, bold: c.bold, color: colorText}}}, c.align)
+			b.WriteString(`</a:txBody><a:tcPr anchor="ctr">`)
+			for _, edge := range []string{"lnL", "lnR", "lnT", "lnB"} {
+				fmt.Fprintf(b, `<a:%s w="6350">%s</a:%s>`, edge, border, edge)
+			}
+			if c.fill != "" {
+				b.WriteString(solidFill(c.fill))
+			}
+			b.WriteString(`</a:tcPr></a:tc>`)
+		}
+		b.WriteString(`</a:tr>`)
+	}
+	b.WriteString(`</a:tbl></a:graphicData></a:graphic></p:graphicFrame>`)
+}
+
+// xml returns the slide part
+func (s *slideBuilder) xml() string {
+	return xmlHeader + `<p:sld xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main">` +
+		`<p:cSld><p:spTree><p:nvGrpSpPr><p:cNvPr id="1" name=""/><p:cNvGrpSpPr/><p:nvPr/></p:nvGrpSpPr>` +
+		`<p:grpSpPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="0" cy="0"/><a:chOff x="0" y="0"/><a:chExt cx="0" cy="0"/></a:xfrm></p:grpSpPr>` +
+		s.shapes.String() +
+		`</p:spTree></p:cSld><p:clrMapOvr><a:masterClrMapping/></p:clrMapOvr></p:sld>`
+}
+
+// writeXfrm writes the position and size of a shape, in inches
+func writeXfrm(b *strings.Builder, x, y, w, h float64) {
+	fmt.Fprintf(b, `<a:xfrm><a:off x="%d" y="%d"/><a:ext cx="%d" cy="%d"/></a:xfrm>`, inch(x), inch(y), inch(w), inch(h))
+}
+
+// writePara writes a paragraph with the given alignment
+func writePara(b *strings.Builder, p para, align string) {
+	b.WriteString(`<a:p>`)
+	if align != "" {
+		fmt.Fprintf(b, `<a:pPr algn="%s"/>`, align)
+	}
+	for _, r := range p.runs {
+		fmt.Fprintf(b, `<a:r><a:rPr lang="zh-TW" sz="%d" b="%s" i="%s" dirty="0">`, r.size*100, flag(r.bold), flag(r.italic))
+		if r.color != "" {
+			b.WriteString(solidFill(r.color))
+		}
+		fmt.Fprintf(b, `<a:latin typeface="%s"/><a:ea typeface="%s"/></a:rPr><a:t>%s</a:t></a:r>`, pptxFont, pptxFont, escape(r.text))
+	}
+	b.WriteString(`</a:p>`)
+}
+
+// solidFill returns a fill of an RRGGBB color
+func solidFill(color string) string {
+	return `<a:solidFill><a:srgbClr val="` + strings.ToUpper(color) + `"/></a:solidFill>`
+}
+
+// inch converts inches to EMU
+func inch(v float64) int {
+	return int(v * emu)
+}
+
+func flag(v bool) string {
+	if v {
+		return "1"
+	}
+	return "0"
+}
+
+// escape escapes text for XML character data and attributes
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\t', '\n', '\r':
+			b.WriteRune(r)
+		default:
+			if r < 0x20 || r == 0xFFFE || r == 0xFFFF {
+				continue // not allowed in XML 1.0
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
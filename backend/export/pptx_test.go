@@ -0,0 +1,167 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// testDoc has text that needs escaping, a control character pasted from a
+// spreadsheet, and emoji the PDF font lacks
+const testDoc = `# 報表 <平台> & "BI"
+## 基本資訊
+- 燈號: 紅
+- 目前狀態: SIT` + "\x0b" + `測試中 🚀
+- 進度: 65%
+- 窗口: Amy、Bob
+- 時程:
+  - 開發: 2025-03-01 ~ 2025-04-30
+  - SIT: 2025-05-01 ~ 2025-05-31
+  - PROD: 2025-06-15
+- 分類: 數據
+## 會辦狀況
+- 2025-05-02
+  - _待追蹤_ 確認 'SIT' 範圍 & 時程
+---
+# 新專案
+## 基本資訊
+- 燈號: 綠
+`
+
+// testProjects returns the projects of testDoc, with n copies of the
+// second one so there are several summary slides
+func testProjects(n int) []parser.Project {
+	projects := parser.Parse(testDoc)
+	for i := 1; i < n; i++ {
+		p := projects[1]
+		p.Name = strings.Repeat("新", i) + "專案"
+		projects = append(projects, p)
+	}
+	return projects
+}
+
+// checkPackage checks that every XML part of an OPC package (PPTX or XLSX) is
+// well-formed, has a content type, and that relationships point at parts
+func checkPackage(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name] = body
+	}
+
+	for name, body := range parts {
+		d := xml.NewDecoder(bytes.NewReader(body))
+		for {
+			_, err := d.Token()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				t.Errorf("%s is not well-formed: %v", name, err)
+				break
+			}
+		}
+	}
+
+	var types struct {
+		Defaults []struct {
+			Extension string `xml:"Extension,attr"`
+		} `xml:"Default"`
+		Overrides []struct {
+			PartName string `xml:"PartName,attr"`
+		} `xml:"Override"`
+	}
+	if err := xml.Unmarshal(parts["[Content_Types].xml"], &types); err != nil {
+		t.Fatalf("[Content_Types].xml: %v", err)
+	}
+	typed := map[string]bool{}
+	for _, d := range types.Defaults {
+		typed["."+d.Extension] = true
+	}
+	for _, o := range types.Overrides {
+		typed[o.PartName] = true
+		if _, ok := parts[strings.TrimPrefix(o.PartName, "/")]; !ok {
+			t.Errorf("content type for missing part %s", o.PartName)
+		}
+	}
+
+	for name, body := range parts {
+		if name == "[Content_Types].xml" {
+			continue
+		}
+		if !typed["/"+name] && !typed[path.Ext(name)] {
+			t.Errorf("%s has no content type", name)
+		}
+		if path.Ext(name) != ".rels" {
+			continue
+		}
+		var rels struct {
+			Relationships []struct {
+				Target     string `xml:"Target,attr"`
+				TargetMode string `xml:"TargetMode,attr"`
+			} `xml:"Relationship"`
+		}
+		if err := xml.Unmarshal(body, &rels); err != nil {
+			continue // reported above
+		}
+		// a/_rels/b.xml.rels holds the relationships of a/b.xml
+		base := path.Dir(path.Dir(name))
+		for _, r := range rels.Relationships {
+			if r.TargetMode == "External" {
+				continue
+			}
+			target := path.Join(base, r.Target)
+			if strings.HasPrefix(r.Target, "/") {
+				target = strings.TrimPrefix(r.Target, "/")
+			}
+			if _, ok := parts[target]; !ok {
+				t.Errorf("%s points at missing part %s", name, target)
+			}
+		}
+	}
+	return parts
+}
+
+func TestPPTXIsWellFormed(t *testing.T) {
+	for _, n := range []int{0, 1, parser.SummaryPageSize + 2} {
+		var buf bytes.Buffer
+		projects := testProjects(n)
+		if n == 0 {
+			projects = nil
+		}
+		if err := PPTX(&buf, `Deck <&> "報表"`, projects); err != nil {
+			t.Fatal(err)
+		}
+		parts := checkPackage(t, buf.Bytes())
+
+		slides := 0
+		for name := range parts {
+			if strings.HasPrefix(name, "ppt/slides/slide") {
+				slides++
+			}
+		}
+		if want := len(parser.GenerateSlides(projects)); slides != want {
+			t.Errorf("%d projects: %d slides, want %d", len(projects), slides, want)
+		}
+	}
+}
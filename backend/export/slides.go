@@ -0,0 +1,207 @@
+// Package export renders workspace projects as slide decks and documents
+// without a browser. Layouts follow the frontend (pptx-export.ts,
+// SlidePreview.vue) so server and browser exports look alike.
+package export
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// Colors shared by the slide renderers, as RRGGBB
+const (
+	colorPrimary = "2563eb"
+	colorDanger  = "ef4444"
+	colorText    = "1f2937"
+	colorMuted   = "6b7280"
+	colorBorder  = "e5e7eb"
+	colorBg      = "f9fafb"
+	colorDivider = "d1d5db"
+)
+
+// tagColors are the background and text colors of the header tags
+var tagColors = map[string][2]string{
+	"state":    {"dbeafe", "1e40af"},
+	"progress": {"dcfce7", "166534"},
+	"contact":  {"fef3c7", "92400e"},
+	"category": {"f3e8ff", "7c3aed"},
+}
+
+// phaseColors cycle through the phases of the gantt chart, as in timeline.ts;
+// phaseLightColors are the start of each phase's gradient
+var (
+	phaseColors      = []string{"3b82f6", "10b981", "f59e0b", "ec4899", "8b5cf6", "ea580c", "06b6d4"}
+	phaseLightColors = []string{"60a5fa", "34d399", "fbbf24", "f472b6", "a78bfa", "fb923c", "22d3ee"}
+)
+
+// Slide limits, matching the browser export
+const (
+	maxMeetings     = 5
+	maxMeetingLines = 3
+	maxNotes        = 5
+)
+
+// statusIcon returns the emoji shown for a 燈號
+func statusIcon(status string) string {
+	switch {
+	case status == "":
+		return "●"
+	case strings.Contains(status, parser.StatusRed):
+		return "🔴"
+	case strings.Contains(status, parser.StatusYellow):
+		return "🟡"
+	default:
+		return "🟢"
+	}
+}
+
+// statusColor returns the color of a 燈號
+func statusColor(status string) string {
+	switch {
+	case strings.Contains(status, parser.StatusRed):
+		return "ef4444"
+	case strings.Contains(status, parser.StatusYellow):
+		return "f59e0b"
+	default:
+		return "22c55e"
+	}
+}
+
+// phaseColor returns the color of the i-th phase
+func phaseColor(i int) string {
+	return phaseColors[i%len(phaseColors)]
+}
+
+// ganttBar is a phase placed on the gantt chart; Left and Width are
+// fractions of the chart width
+type ganttBar struct {
+	Name  string
+	Dates string
+	Color string
+	Light string
+	Left  float64
+	Width float64
+}
+
+// ganttBars places the phases side by side in proportion to their length
+func ganttBars(phases []parser.Phase) []ganttBar {
+	bars := make([]ganttBar, len(phases))
+	left := 0.0
+	for i, w := range phaseWidths(phases, 1, 0) {
+		bars[i] = ganttBar{
+			Name:  phases[i].Name,
+			Dates: phaseDateShort(phases[i]),
+			Color: phaseColor(i),
+			Light: phaseLightColors[i%len(phaseLightColors)],
+			Left:  left,
+			Width: w,
+		}
+		left += w
+	}
+	return bars
+}
+
+// todayPosition returns where today falls on the gantt chart, as a fraction
+// of its width, and false if today is outside the project's phases
+func todayPosition(phases []parser.Phase, now time.Time) (float64, bool) {
+	if len(phases) == 0 {
+		return 0, false
+	}
+	first := phases[0].StartDate
+	last := phases[len(phases)-1].EndDate
+	if last == "" {
+		last = phases[len(phases)-1].StartDate
+	}
+	today := now.Format("2006-01-02")
+	if today < first || today > last {
+		return 0, false
+	}
+
+	start, errStart := time.Parse("2006-01-02", first)
+	end, errEnd := time.Parse("2006-01-02", last)
+	day, _ := time.Parse("2006-01-02", today)
+	if errStart != nil || errEnd != nil || !end.After(start) {
+		return 0, true
+	}
+	return day.Sub(start).Hours() / end.Sub(start).Hours(), true
+}
+
+// phaseWidths splits total between the phases in proportion to their length
+// in days; overlap is added back for each arrow overlapping the previous one
+func phaseWidths(phases []parser.Phase, total, overlap float64) []float64 {
+	if len(phases) == 0 {
+		return nil
+	}
+
+	days := make([]float64, len(phases))
+	sum := 0.0
+	for i, p := range phases {
+		start, errStart := time.Parse("2006-01-02", p.StartDate)
+		end, errEnd := time.Parse("2006-01-02", p.EndDate)
+		d := 1.0
+		if errStart == nil && errEnd == nil {
+			d = math.Max(1, math.Ceil(end.Sub(start).Hours()/24)+1)
+		}
+		days[i] = d
+		sum += d
+	}
+
+	effective := total + overlap*float64(len(phases)-1)
+	widths := make([]float64, len(phases))
+	for i, d := range days {
+		widths[i] = d / sum * effective
+	}
+	return widths
+}
+
+// phaseDateShort formats a phase's dates as MM-DD~MM-DD
+func phaseDateShort(p parser.Phase) string {
+	if len(p.StartDate) < 10 {
+		return ""
+	}
+	start := p.StartDate[5:]
+	end := ""
+	if len(p.EndDate) == 10 {
+		end = p.EndDate[5:]
+	}
+	if end != "" && end != start {
+		return start + "~" + end
+	}
+	return start
+}
+
+// lineColor returns the text color of a meeting or note line
+func lineColor(tracking, planned, old bool) string {
+	switch {
+	case planned:
+		return colorPrimary
+	case tracking:
+		return colorDanger
+	case old:
+		return colorMuted
+	default:
+		return colorText
+	}
+}
+
+// departments joins a list of departments, or returns - if it is empty
+func departments(list []string) string {
+	if len(list) == 0 {
+		return "-"
+	}
+	return strings.Join(list, ", ")
+}
+
+// summaryTitle is the heading of the n-th (0-based) summary slide
+func summaryTitle(n int) string {
+	return "專案進展彙整 " + strconv.Itoa(n+1)
+}
+
+// tagWidth estimates the width in inches of a header tag holding text
+func tagWidth(text string) float64 {
+	return math.Max(0.8, float64(len([]rune(text)))*0.12+0.3)
+}
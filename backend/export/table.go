@@ -0,0 +1,145 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// WorkspaceProjects is the projects of one workspace
+type WorkspaceProjects struct {
+	WorkspaceID   string
+	WorkspaceName string
+	Projects      []parser.Project
+}
+
+// Table is the project summary flattened into one row per project. Cells are
+// strings, except 進度 which is an int.
+type Table struct {
+	Header []string
+	Rows   [][]any
+}
+
+// Summary table columns
+const (
+	ColumnWorkspace      = "工作區"
+	ColumnName           = "專案名稱"
+	ColumnStatus         = "燈號"
+	ColumnCurrentState   = "目前狀態"
+	ColumnProgress       = "進度"
+	ColumnContact        = "窗口"
+	ColumnCategory       = "分類"
+	ColumnLead           = "主辦"
+	ColumnSupport        = "協辦"
+	ColumnMeetingDate    = "最新會辦日期"
+	ColumnMeetingContent = "最新會辦內容"
+	ColumnPhaseStart     = " 開始" // suffix of a phase name
+	ColumnPhaseEnd       = " 結束" // suffix of a phase name
+)
+
+// SummaryTable flattens the projects in slide order. Every phase name gets a
+// start and an end column, in the order the names first appear. The 工作區
+// column is added when withWorkspace is set.
+func SummaryTable(sources []WorkspaceProjects, withWorkspace bool) Table {
+	var phaseNames []string
+	phaseIndex := map[string]int{}
+	for _, src := range sources {
+		for _, p := range src.Projects {
+			for _, phase := range p.Phases {
+				if _, ok := phaseIndex[phase.Name]; !ok {
+					phaseIndex[phase.Name] = len(phaseNames)
+					phaseNames = append(phaseNames, phase.Name)
+				}
+			}
+		}
+	}
+
+	var t Table
+	if withWorkspace {
+		t.Header = append(t.Header, ColumnWorkspace)
+	}
+	t.Header = append(t.Header, ColumnName, ColumnStatus, ColumnCurrentState, ColumnProgress,
+		ColumnContact, ColumnCategory, ColumnLead, ColumnSupport)
+	for _, name := range phaseNames {
+		t.Header = append(t.Header, name+ColumnPhaseStart, name+ColumnPhaseEnd)
+	}
+	t.Header = append(t.Header, ColumnMeetingDate, ColumnMeetingContent)
+
+	for _, src := range sources {
+		for _, p := range parser.SortProjects(src.Projects) {
+			var row []any
+			if withWorkspace {
+				row = append(row, src.WorkspaceName)
+			}
+			row = append(row, p.Name, p.Status, p.CurrentState, p.Progress, p.Contact, p.Category,
+				strings.Join(p.Departments.Lead, ", "), strings.Join(p.Departments.Support, ", "))
+
+			// the first phase of each name fills its columns
+			phases := make([]any, 2*len(phaseNames))
+			for i := range phases {
+				phases[i] = ""
+			}
+			filled := map[string]bool{}
+			for _, phase := range p.Phases {
+				if filled[phase.Name] {
+					continue
+				}
+				filled[phase.Name] = true
+				i := phaseIndex[phase.Name]
+				phases[2*i], phases[2*i+1] = phase.StartDate, phase.EndDate
+			}
+			row = append(row, phases...)
+
+			if m, ok := parser.LatestMeeting(p); ok {
+				lines := make([]string, len(m.Lines))
+				for i, l := range m.Lines {
+					lines[i] = l.Text
+				}
+				row = append(row, m.Date, strings.Join(lines, "\n"))
+			} else {
+				row = append(row, "", "")
+			}
+			t.Rows = append(t.Rows, row)
+		}
+	}
+	return t
+}
+
+// CSV writes the table as UTF-8 CSV with a byte order mark, so Excel opens
+// the Chinese text correctly
+func CSV(w io.Writer, t Table) error {
+	if _, err := io.WriteString(w, "\ufeff"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = cellString(v)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// cellString formats a table cell as text
+func cellString(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return ""
+	}
+}
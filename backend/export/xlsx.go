@@ -0,0 +1,184 @@
+package export
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// XLSX limits and sizes
+const (
+	xlsxSheetName    = "專案"
+	xlsxMinWidth     = 8
+	xlsxMaxWidth     = 60
+	xlsxStyleHeader  = 1 // bold on grey, see xlsxStyles
+	xlsxStyleWrapped = 2 // wraps multi-line text
+)
+
+// XLSX writes the table as an Excel workbook with a single sheet; the header
+// row is bold, frozen and filterable
+func XLSX(w io.Writer, t Table) error {
+	zw := zip.NewWriter(w)
+	files := []struct{ name, body string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", xlsxSheet(t)},
+	}
+	for _, f := range files {
+		fw, err := zw.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.body); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// xlsxSheet renders the worksheet, with inline strings rather than a shared
+// string table
+func xlsxSheet(t Table) string {
+	var b strings.Builder
+	b.WriteString(xmlHeader)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">`)
+	b.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	if len(t.Header) > 0 {
+		b.WriteString(`<cols>`)
+		for i, w := range columnWidths(t) {
+			fmt.Fprintf(&b, `<col min="%d" max="%d" width="%d" customWidth="1"/>`, i+1, i+1, w)
+		}
+		b.WriteString(`</cols>`)
+	}
+
+	b.WriteString(`<sheetData>`)
+	header := make([]any, len(t.Header))
+	for i, h := range t.Header {
+		header[i] = h
+	}
+	writeRow(&b, 1, header, xlsxStyleHeader)
+	for i, row := range t.Rows {
+		writeRow(&b, i+2, row, 0)
+	}
+	b.WriteString(`</sheetData>`)
+
+	if len(t.Header) > 0 {
+		fmt.Fprintf(&b, `<autoFilter ref="A1:%s%d"/>`, columnName(len(t.Header)-1), len(t.Rows)+1)
+	}
+	b.WriteString(`</worksheet>`)
+	return b.String()
+}
+
+// writeRow writes a row; style 0 keeps the default, except for multi-line text
+func writeRow(b *strings.Builder, n int, cells []any, style int) {
+	fmt.Fprintf(b, `<row r="%d">`, n)
+	for i, v := range cells {
+		ref := columnName(i) + strconv.Itoa(n)
+		s := style
+		switch v := v.(type) {
+		case int:
+			fmt.Fprintf(b, `<c r="%s"%s><v>%d</v></c>`, ref, styleAttr(s), v)
+		default:
+			text := cellString(v)
+			if text == "" {
+				continue
+			}
+			if s == 0 && strings.Contains(text, "\n") {
+				s = xlsxStyleWrapped
+			}
+			fmt.Fprintf(b, `<c r="%s" t="inlineStr"%s><is><t xml:space="preserve">%s</t></is></c>`, ref, styleAttr(s), escape(text))
+		}
+	}
+	b.WriteString(`</row>`)
+}
+
+func styleAttr(style int) string {
+	if style == 0 {
+		return ""
+	}
+	return fmt.Sprintf(` s="%d"`, style)
+}
+
+// columnName returns the letters of a 0-based column index: A, B, … Z, AA, …
+func columnName(i int) string {
+	name := ""
+	for i++; i > 0; i = (i - 1) / 26 {
+		name = string(rune('A'+(i-1)%26)) + name
+	}
+	return name
+}
+
+// columnWidths estimates column widths in characters; CJK counts double
+func columnWidths(t Table) []int {
+	widths := make([]int, len(t.Header))
+	measure := func(i int, s string) {
+		for _, line := range strings.Split(s, "\n") {
+			w := 0
+			for _, r := range line {
+				if utf8.RuneLen(r) > 1 {
+					w += 2
+				} else {
+					w++
+				}
+			}
+			widths[i] = max(widths[i], w+2)
+		}
+	}
+	for i, h := range t.Header {
+		measure(i, h)
+	}
+	for _, row := range t.Rows {
+		for i, v := range row {
+			if i < len(widths) {
+				measure(i, cellString(v))
+			}
+		}
+	}
+	for i := range widths {
+		widths[i] = min(max(widths[i], xlsxMinWidth), xlsxMaxWidth)
+	}
+	return widths
+}
+
+const xlsxContentTypes = xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xmlHeader + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="` + xlsxSheetName + `" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xmlHeader + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines cell formats 0 (default), 1 (header) and 2 (wrapped text)
+const xlsxStyles = xmlHeader + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="3"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill>` +
+	`<fill><patternFill patternType="solid"><fgColor rgb="FFF9FAFB"/><bgColor indexed="64"/></patternFill></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="3">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="2" borderId="0" xfId="0" applyFont="1" applyFill="1"/>` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0" applyAlignment="1"><alignment vertical="top" wrapText="1"/></xf>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,39 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestXLSXIsWellFormed(t *testing.T) {
+	table := SummaryTable([]WorkspaceProjects{{WorkspaceID: "w1", WorkspaceName: "PMO & <IT>", Projects: testProjects(3)}}, true)
+	var buf bytes.Buffer
+	if err := XLSX(&buf, table); err != nil {
+		t.Fatal(err)
+	}
+	parts := checkPackage(t, buf.Bytes())
+
+	// the cells read back as written, apart from dropped control characters
+	var sheet struct {
+		Rows []struct {
+			Cells []struct {
+				Value  string `xml:"v"`
+				Inline string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != len(table.Rows)+1 {
+		t.Fatalf("%d rows, want %d", len(sheet.Rows), len(table.Rows)+1)
+	}
+	first := sheet.Rows[1].Cells
+	if first[0].Inline != "PMO & <IT>" || first[1].Inline != `報表 <平台> & "BI"` {
+		t.Errorf("first row starts %q, %q", first[0].Inline, first[1].Inline)
+	}
+	if first[3].Inline != "SIT測試中 🚀" {
+		t.Errorf("目前狀態 = %q, want the control character dropped", first[3].Inline)
+	}
+}
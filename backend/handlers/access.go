@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kywk/sheltie/backend/database"
+)
+
+const (
+	// contextAccess is the gin context key of the request's access to the workspace
+	contextAccess = "access"
+
+	// contextShareLink is the gin context key of the share link a request came with
+	contextShareLink = "shareLink"
+)
+
+// RequireWorkspaceAccess lets through requests with at least the access to
+// the workspace in the :id parameter. Access comes from a login token or API
+// key, sent in the Authorization header or as ?token=, and from a share
+// token, sent in the X-Share-Token header or as ?share=.
+func RequireWorkspaceAccess(level string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		access, authenticated := workspaceAccess(c, c.Param("id"))
+		if access == "" && !authenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login or share link required"})
+			c.Abort()
+			return
+		}
+		if !database.AccessAtLeast(access, level) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Set(contextAccess, access)
+		c.Next()
+	}
+}
+
+// currentAccess returns the access set by RequireWorkspaceAccess, or ""
+func currentAccess(c *gin.Context) string {
+	return c.GetString(contextAccess)
+}
+
+// currentShareLink returns the valid share link set by workspaceAccess, or nil
+func currentShareLink(c *gin.Context) *database.ShareLink {
+	if v, ok := c.Get(contextShareLink); ok {
+		return v.(*database.ShareLink)
+	}
+	return nil
+}
+
+// workspaceAccess returns the best access the request has to a workspace,
+// or "" for none, and whether it came with a valid login. A logged in user
+// is remembered in the context like AdminAuthMiddleware does, and so is a
+// valid share link.
+func workspaceAccess(c *gin.Context, workspaceID string) (string, bool) {
+	access := ""
+
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		token = c.Query("token")
+	}
+	authenticated := authenticateRequest(c, token)
+	if key := currentAPIKey(c); key != nil {
+		access = apiKeyAccess(key, workspaceID)
+	} else if authenticated {
+		access = userAccess(currentUser(c), workspaceID)
+	}
+
+	share := c.GetHeader("X-Share-Token")
+	if share == "" {
+		share = c.Query("share")
+	}
+	if share != "" {
+		link, err := database.GetShareLinkByTokenHash(hashToken(share))
+		if err == nil && link.WorkspaceID == workspaceID && !link.Expired(time.Now().UTC()) {
+			c.Set(contextShareLink, link)
+			if !database.AccessAtLeast(access, link.Scope) {
+				access = link.Scope
+			}
+		}
+	}
+	return access, authenticated
+}
+
+// userAccess returns a user's access to a workspace: admins own every
+// workspace, other users have the access of their membership
+func userAccess(user *database.User, workspaceID string) string {
+	if user.Role == database.RoleAdmin {
+		return database.AccessOwner
+	}
+	role, err := database.GetWorkspaceMemberRole(workspaceID, user.ID)
+	if err != nil {
+		log.Printf("Error reading workspace membership: %v", err)
+	}
+	return database.MemberAccess(role)
+}
+
+// visibleWorkspaces returns the workspaces the logged in user can see: all
+// of them for admins, the ones they are a member of otherwise. An API key
+// sees all workspaces, or the one it is restricted to.
+func visibleWorkspaces(c *gin.Context) ([]*database.Workspace, error) {
+	if key := currentAPIKey(c); key != nil && key.WorkspaceID != "" {
+		ws, err := database.GetWorkspace(key.WorkspaceID)
+		if err != nil {
+			return nil, err
+		}
+		return []*database.Workspace{ws}, nil
+	}
+	user := currentUser(c)
+	if user.Role == database.RoleAdmin || currentAPIKey(c) != nil {
+		return database.GetAllWorkspaces()
+	}
+	return database.GetMemberWorkspaces(user.ID)
+}
+
+// ListWorkspaceMembers handles GET /api/workspaces/:id/members
+func ListWorkspaceMembers(c *gin.Context) {
+	members, err := database.GetWorkspaceMembers(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list members"})
+		return
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// SetWorkspaceMemberRequest represents the request body for adding a member
+type SetWorkspaceMemberRequest struct {
+	Username string `json:"username" binding:"required"`
+	Role     string `json:"role" binding:"required"`
+}
+
+// SetWorkspaceMember handles PUT /api/workspaces/:id/members, adding a user
+// to the workspace or changing their role
+func SetWorkspaceMember(c *gin.Context) {
+	id := c.Param("id")
+
+	var req SetWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username and role are required"})
+		return
+	}
+	if database.MemberAccess(req.Role) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role must be owner, editor or viewer"})
+		return
+	}
+
+	ws, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+	user, err := database.GetUserByUsername(req.Username)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if req.Role != database.MemberOwner && lastOwner(id, user.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A workspace needs an owner"})
+		return
+	}
+
+	if err := database.SetWorkspaceMember(id, user.ID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save member"})
+		return
+	}
+	if !database.AccessAtLeast(database.MemberAccess(req.Role), database.AccessEdit) {
+		// Open connections may still be editing; they reconnect as viewers
+		wsHub.DisconnectUser(id, user.ID)
+	}
+	audit(c, database.AuditMemberSet, id, ws.Name, user.Username+" as "+req.Role)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member saved"})
+}
+
+// RemoveWorkspaceMember handles DELETE /api/workspaces/:id/members/:userId
+func RemoveWorkspaceMember(c *gin.Context) {
+	id, userID := c.Param("id"), c.Param("userId")
+
+	if lastOwner(id, userID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A workspace needs an owner"})
+		return
+	}
+
+	removed, err := database.RemoveWorkspaceMember(id, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Member not found"})
+		return
+	}
+	wsHub.DisconnectUser(id, userID)
+	member := userID
+	if user, err := database.GetUser(userID); err == nil {
+		member = user.Username
+	}
+	audit(c, database.AuditMemberRemove, id, workspaceName(id), member)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Member removed"})
+}
+
+// lastOwner reports whether a user is the only owner of a workspace
+func lastOwner(workspaceID, userID string) bool {
+	members, err := database.GetWorkspaceMembers(workspaceID)
+	if err != nil {
+		return false
+	}
+	owners, isOwner := 0, false
+	for _, m := range members {
+		if m.Role == database.MemberOwner {
+			owners++
+			isOwner = isOwner || m.UserID == userID
+		}
+	}
+	return isOwner && owners == 1
+}
+
+// ListShareLinks handles GET /api/workspaces/:id/shares
+func ListShareLinks(c *gin.Context) {
+	links, err := database.GetShareLinks(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list share links"})
+		return
+	}
+	c.JSON(http.StatusOK, links)
+}
+
+// CreateShareLinkRequest represents the request body for creating a share
+// link; ExpiresIn is in seconds, and 0 means the link does not expire
+type CreateShareLinkRequest struct {
+	Scope     string `json:"scope" binding:"required"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+// ShareLinkResponse is a new share link with its token, which is only shown once
+type ShareLinkResponse struct {
+	*database.ShareLink
+	Token string `json:"token"`
+	Path  string `json:"path"`
+}
+
+// CreateShareLink handles POST /api/workspaces/:id/shares
+func CreateShareLink(c *gin.Context) {
+	id := c.Param("id")
+
+	var req CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scope is required"})
+		return
+	}
+	if !database.ValidShareScope(req.Scope) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Scope must be view, comment or edit"})
+		return
+	}
+	if req.ExpiresIn < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiry"})
+		return
+	}
+
+	ws, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+	now := time.Now().UTC()
+	link := &database.ShareLink{
+		ID:          uuid.New().String(),
+		WorkspaceID: id,
+		TokenHash:   hashToken(token),
+		Scope:       req.Scope,
+		CreatedAt:   now,
+	}
+	if user := currentUser(c); user != nil {
+		link.CreatedBy = user.Username
+	}
+	if req.ExpiresIn > 0 {
+		expires := now.Add(time.Duration(req.ExpiresIn) * time.Second)
+		link.ExpiresAt = &expires
+	}
+
+	if err := database.CreateShareLink(link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+	detail := link.Scope + " link " + link.ID
+	if link.ExpiresAt != nil {
+		detail += ", expires " + link.ExpiresAt.Format(time.RFC3339)
+	}
+	audit(c, database.AuditShareCreate, id, ws.Name, detail)
+
+	c.JSON(http.StatusCreated, ShareLinkResponse{
+		ShareLink: link,
+		Token:     token,
+		Path:      "/workspace/" + id + "?share=" + token,
+	})
+}
+
+// RevokeShareLink handles DELETE /api/workspaces/:id/shares/:shareId
+func RevokeShareLink(c *gin.Context) {
+	id, shareID := c.Param("id"), c.Param("shareId")
+	deleted, err := database.DeleteShareLink(id, shareID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+	wsHub.DisconnectShareLink(id, shareID)
+	audit(c, database.AuditShareRevoke, id, workspaceName(id), "link "+shareID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
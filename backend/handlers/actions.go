@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// ActionItemResponse represents an action item with its workspace
+type ActionItemResponse struct {
+	parser.ActionItem
+	WorkspaceID   string `json:"workspaceId"`
+	WorkspaceName string `json:"workspaceName"`
+}
+
+// ListActionItems handles GET /api/action-items
+//
+// Query parameters:
+//   - workspace: only items of this workspace, which needs view access to it;
+//     without it, the items of every workspace the logged in user can see
+//   - owner: only items whose 窗口 lists this name (case-insensitive)
+//   - minAge, maxAge: only items raised at least / at most this many days ago
+//   - status: open (default), resolved or all
+func ListActionItems(c *gin.Context) {
+	workspaceID := c.Query("workspace")
+	if workspaceID == "" {
+		if !authenticateRequest(c, c.GetHeader("Authorization")) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Listing all workspaces requires admin login"})
+			return
+		}
+	} else if access, authenticated := workspaceAccess(c, workspaceID); access == "" {
+		if !authenticated {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Login or share link required"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	minAge, errMin := optionalInt(c.Query("minAge"), 0)
+	maxAge, errMax := optionalInt(c.Query("maxAge"), -1)
+	if errMin != nil || errMax != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid age filter"})
+		return
+	}
+	status := c.DefaultQuery("status", "open")
+	if status != "open" && status != "resolved" && status != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	owner := c.Query("owner")
+
+	var workspaces []*database.Workspace
+	if workspaceID != "" {
+		ws, err := database.GetWorkspace(workspaceID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			return
+		}
+		workspaces = []*database.Workspace{ws}
+	} else {
+		var err error
+		if workspaces, err = visibleWorkspaces(c); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+			return
+		}
+	}
+
+	now := time.Now()
+	items := []ActionItemResponse{}
+	for _, ws := range workspaces {
+		for _, item := range parser.ActionItems(parser.Parse(ws.Content), now) {
+			switch {
+			case status == "open" && item.Resolved, status == "resolved" && !item.Resolved:
+				continue
+			case owner != "" && !item.OwnedBy(owner):
+				continue
+			case item.Age < minAge, maxAge >= 0 && item.Age > maxAge:
+				continue
+			}
+			items = append(items, ActionItemResponse{ActionItem: item, WorkspaceID: ws.ID, WorkspaceName: ws.Name})
+		}
+	}
+
+	// Oldest first
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Date < items[j].Date })
+
+	c.JSON(http.StatusOK, items)
+}
+
+// ResolveActionItem handles POST /api/workspaces/:id/action-items/:itemId/resolve
+func ResolveActionItem(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	base, version, _ := wsHub.GetVersionManager().GetDocument(id, database.ChannelMarkdown).GetState()
+	content, err := parser.ResolveActionItem(base, c.Param("itemId"))
+	if errors.Is(err, parser.ErrActionItemNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Action item not found"})
+		return
+	}
+	if content == base {
+		c.JSON(http.StatusOK, gin.H{"message": "Action item already resolved", "version": version})
+		return
+	}
+
+	result, err := wsHub.EditContent(id, database.ChannelMarkdown, base, content, version, requestAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Document changed, please retry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Action item resolved",
+		"version": result.Version,
+		"hash":    result.Hash,
+	})
+}
+
+// optionalInt parses a non-negative integer query value, returning def when it is empty
+func optionalInt(s string, def int) (int, error) {
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err == nil && n < 0 {
+		err = errors.New("negative value")
+	}
+	return n, err
+}
@@ -2,72 +2,143 @@ package handlers
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/kywk/sheltie/backend/config"
+	"github.com/kywk/sheltie/backend/database"
+	ws "github.com/kywk/sheltie/backend/websocket"
+	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	// Token expiry duration
+	// Token expiry duration; each use of a token renews it
 	tokenExpiry = 24 * time.Hour
-)
 
-var (
-	// Active admin tokens (in production, use Redis or JWT)
-	adminTokens = make(map[string]time.Time)
-	cfg         *config.Config
+	// sessionMaxAge is how long a session can be renewed before logging in again
+	sessionMaxAge = 30 * 24 * time.Hour
+
+	// sessionTouchInterval limits how often a session's last use is written
+	sessionTouchInterval = time.Minute
+
+	// contextUser and contextSession are the gin context keys of the logged
+	// in user and their session; contextAPIKey is set instead of the session
+	// for requests made with an API key
+	contextUser    = "user"
+	contextSession = "session"
+	contextAPIKey  = "apiKey"
 )
 
+var cfg *config.Config
+
 // SetConfig sets the configuration for admin handlers
 func SetConfig(c *config.Config) {
 	cfg = c
 }
 
-// LoginRequest represents the login request body
+// BootstrapAdmin creates the first admin account from ADMIN_USERNAME and
+// ADMIN_PASSWORD when there are no users yet
+func BootstrapAdmin() error {
+	n, err := database.CountUsers("")
+	if err != nil || n > 0 {
+		return err
+	}
+
+	hash, err := hashPassword(cfg.AdminPassword)
+	if err != nil {
+		return err
+	}
+	return database.CreateUser(&database.User{
+		ID:           uuid.New().String(),
+		Username:     cfg.AdminUsername,
+		DisplayName:  cfg.AdminUsername,
+		Role:         database.RoleAdmin,
+		PasswordHash: hash,
+	})
+}
+
+// LoginRequest represents the login request body; the username defaults to
+// the first admin account
 type LoginRequest struct {
+	Username string `json:"username"`
 	Password string `json:"password" binding:"required"`
 }
 
-// Login handles POST /api/admin/login
+// Login handles POST /api/admin/login, checking the password with each
+// authenticator in turn
 func Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is required"})
 		return
 	}
+	if req.Username == "" {
+		req.Username = cfg.AdminUsername
+	}
 
-	if req.Password != cfg.AdminPassword {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+	user, err := passwordLogin(req.Username, req.Password)
+	if errors.Is(err, errInvalidCredentials) {
+		auditAs(ws.Author{Name: req.Username, IP: c.ClientIP()}, database.AuditLoginFailed, "", req.Username, "password")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Login service unavailable"})
 		return
 	}
 
-	// Generate a secure random token
-	token, err := generateSecureToken(32)
+	token, err := startSession(c, user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
-	adminTokens[token] = time.Now().Add(tokenExpiry)
+	auditAs(userAuthor(c, user), database.AuditLogin, user.ID, user.Username, "password")
 
 	c.JSON(http.StatusOK, gin.H{
 		"token":     token,
 		"expiresIn": int(tokenExpiry.Seconds()),
+		"user":      user,
+	})
+}
+
+// startSession logs a user in, returning the new session's token
+func startSession(c *gin.Context, user *database.User) (string, error) {
+	// Generate a secure random token
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now().UTC()
+	err = database.CreateSession(&database.Session{
+		ID:         uuid.New().String(),
+		TokenHash:  hashToken(token),
+		UserID:     user.ID,
+		IP:         c.ClientIP(),
+		UserAgent:  c.Request.UserAgent(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(tokenExpiry),
 	})
+	return token, err
 }
 
 // Logout handles POST /api/admin/logout
 func Logout(c *gin.Context) {
-	token := c.GetHeader("Authorization")
+	token := bearerToken(c.GetHeader("Authorization"))
 	if token != "" {
-		delete(adminTokens, token)
+		_ = database.DeleteSessionByTokenHash(hashToken(token))
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
 }
 
-// AdminAuthMiddleware checks for valid admin token
+// AdminAuthMiddleware checks for a valid session token or API key
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
@@ -77,18 +148,146 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		expiry, exists := adminTokens[token]
-		if !exists || time.Now().After(expiry) {
-			delete(adminTokens, token)
+		if !authenticateRequest(c, token) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
+// RequireSession lets through requests logged in with a session rather than
+// an API key; it runs after AdminAuthMiddleware
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if currentSession(c) == nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not available with an API key"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
 
+// RequireRole lets through users with at least the role; it runs after AdminAuthMiddleware
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if user := currentUser(c); user == nil || !database.RoleAtLeast(user.Role, role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUser returns the user set by AdminAuthMiddleware, or nil
+func currentUser(c *gin.Context) *database.User {
+	if v, ok := c.Get(contextUser); ok {
+		return v.(*database.User)
+	}
+	return nil
+}
+
+// currentSession returns the session set by AdminAuthMiddleware, or nil
+func currentSession(c *gin.Context) *database.Session {
+	if v, ok := c.Get(contextSession); ok {
+		return v.(*database.Session)
+	}
+	return nil
+}
+
+// authenticateRequest logs a request in with a session token or an API key,
+// sent as is or after "Bearer ", and remembers the user in the context
+func authenticateRequest(c *gin.Context, token string) bool {
+	token = bearerToken(token)
+	if strings.HasPrefix(token, apiKeyPrefix) {
+		key, ok := authenticateAPIKey(token)
+		if !ok {
+			return false
+		}
+		c.Set(contextUser, apiKeyUser(key))
+		c.Set(contextAPIKey, key)
+		return true
+	}
+
+	user, session, ok := authenticate(c, token)
+	if !ok {
+		return false
+	}
+	c.Set(contextUser, user)
+	c.Set(contextSession, session)
+	return true
+}
+
+// bearerToken returns the token of an Authorization header
+func bearerToken(header string) string {
+	return strings.TrimPrefix(header, "Bearer ")
+}
+
+// authenticate returns the user and session of a token. A valid token's
+// expiry moves forward on use, up to sessionMaxAge after login.
+func authenticate(c *gin.Context, token string) (*database.User, *database.Session, bool) {
+	if token == "" {
+		return nil, nil, false
+	}
+	session, err := database.GetSessionByTokenHash(hashToken(token))
+	if err != nil {
+		return nil, nil, false
+	}
+	now := time.Now().UTC()
+	if now.After(session.ExpiresAt) {
+		_ = database.DeleteSessionByTokenHash(session.TokenHash)
+		return nil, nil, false
+	}
+	user, err := database.GetUser(session.UserID)
+	if err != nil {
+		_ = database.DeleteSessionByTokenHash(session.TokenHash)
+		return nil, nil, false
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval {
+		session.LastSeenAt = now
+		session.ExpiresAt = now.Add(tokenExpiry)
+		if limit := session.CreatedAt.Add(sessionMaxAge); session.ExpiresAt.After(limit) {
+			session.ExpiresAt = limit
+		}
+		session.IP = c.ClientIP()
+		session.UserAgent = c.Request.UserAgent()
+		if err := database.TouchSession(session); err != nil {
+			log.Printf("Error renewing session: %v", err)
+		}
+	}
+	return user, session, true
+}
+
+// revokeUserSessions logs a user out everywhere, closing their open
+// WebSocket connections too
+func revokeUserSessions(userID string) {
+	if err := database.DeleteUserSessions(userID, ""); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+	}
+	wsHub.DisconnectUser("", userID)
+}
+
+// hashToken returns the hash under which a session token is stored
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashPassword hashes a password for storage
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// checkPassword reports whether password matches a stored hash
+func checkPassword(hash, password string) bool {
+	return hash != "" && bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
 // generateSecureToken creates a cryptographically secure random token
 func generateSecureToken(length int) (string, error) {
 	bytes := make([]byte, length)
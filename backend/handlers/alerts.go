@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/jobs"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// Alert scanner reference
+var alertScanner *jobs.AlertScanner
+
+// SetAlertScanner sets the alert scanner reference
+func SetAlertScanner(s *jobs.AlertScanner) {
+	alertScanner = s
+}
+
+// AlertsResponse represents the alerts of one workspace
+type AlertsResponse struct {
+	WorkspaceID   string         `json:"workspaceId"`
+	WorkspaceName string         `json:"workspaceName"`
+	ScannedAt     time.Time      `json:"scannedAt"`
+	Alerts        []parser.Alert `json:"alerts"`
+}
+
+// GetWorkspaceAlerts handles GET /api/workspaces/:id/alerts
+func GetWorkspaceAlerts(c *gin.Context) {
+	ws, err := database.GetWorkspace(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	alerts, scannedAt := alertScanner.Alerts(ws.ID)
+	c.JSON(http.StatusOK, AlertsResponse{
+		WorkspaceID:   ws.ID,
+		WorkspaceName: ws.Name,
+		ScannedAt:     scannedAt,
+		Alerts:        alerts,
+	})
+}
+
+// ListAlerts handles GET /api/admin/alerts, listing the user's workspaces that have alerts
+func ListAlerts(c *gin.Context) {
+	workspaces, err := visibleWorkspaces(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+		return
+	}
+
+	results, scannedAt := alertScanner.All()
+	items := []AlertsResponse{}
+	for _, ws := range workspaces {
+		if alerts := results[ws.ID]; len(alerts) > 0 {
+			items = append(items, AlertsResponse{
+				WorkspaceID:   ws.ID,
+				WorkspaceName: ws.Name,
+				ScannedAt:     scannedAt,
+				Alerts:        alerts,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kywk/sheltie/backend/database"
+)
+
+const (
+	// apiKeyPrefix starts every API key, telling them apart from session tokens
+	apiKeyPrefix = "shk_"
+
+	// apiKeyPrefixLength is how much of a key is kept to identify it
+	apiKeyPrefixLength = len(apiKeyPrefix) + 8
+
+	// apiKeyTouchInterval limits how often a key's last use is written
+	apiKeyTouchInterval = time.Minute
+)
+
+// authenticateAPIKey returns the unexpired API key of a raw key
+func authenticateAPIKey(raw string) (*database.APIKey, bool) {
+	key, err := database.GetAPIKeyByHash(hashToken(raw))
+	if err != nil {
+		return nil, false
+	}
+	now := time.Now().UTC()
+	if key.Expired(now) {
+		return nil, false
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := database.TouchAPIKey(key.ID, now); err != nil {
+			log.Printf("Error recording API key use: %v", err)
+		}
+	}
+	return key, true
+}
+
+// apiKeyUser returns the user a request with an API key acts as. It is not
+// stored; it has the admin role with the admin scope and is a viewer otherwise,
+// and its workspace access comes from apiKeyAccess.
+func apiKeyUser(key *database.APIKey) *database.User {
+	role := database.RoleViewer
+	if key.HasScope(database.ScopeAdmin) {
+		role = database.RoleAdmin
+	}
+	return &database.User{
+		ID:          "apikey:" + key.ID,
+		Username:    key.Name,
+		DisplayName: key.Name,
+		Role:        role,
+		CreatedAt:   key.CreatedAt,
+		UpdatedAt:   key.CreatedAt,
+	}
+}
+
+// currentAPIKey returns the API key of the request, or nil
+func currentAPIKey(c *gin.Context) *database.APIKey {
+	if v, ok := c.Get(contextAPIKey); ok {
+		return v.(*database.APIKey)
+	}
+	return nil
+}
+
+// apiKeyAccess returns the access an API key has to a workspace
+func apiKeyAccess(key *database.APIKey, workspaceID string) string {
+	switch {
+	case key.HasScope(database.ScopeAdmin):
+		return database.AccessOwner
+	case key.WorkspaceID != "" && key.WorkspaceID != workspaceID:
+		return ""
+	case key.HasScope(database.ScopeWorkspaceWrite):
+		return database.AccessEdit
+	case key.HasScope(database.ScopeWorkspaceRead):
+		return database.AccessView
+	}
+	return ""
+}
+
+// ListAPIKeys handles GET /api/admin/api-keys
+func ListAPIKeys(c *gin.Context) {
+	keys, err := database.GetAllAPIKeys()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKeyRequest represents the request body for creating an API key;
+// ExpiresIn is in seconds, and 0 means the key does not expire
+type CreateAPIKeyRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Scopes      []string `json:"scopes" binding:"required"`
+	WorkspaceID string   `json:"workspaceId"`
+	ExpiresIn   int      `json:"expiresIn"`
+}
+
+// APIKeyResponse is a new API key with the key itself, which is only shown once
+type APIKeyResponse struct {
+	*database.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey handles POST /api/admin/api-keys
+func CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and scopes are required"})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Name and scopes are required"})
+		return
+	}
+	admin := false
+	for _, scope := range req.Scopes {
+		if !database.ValidScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope: " + scope})
+			return
+		}
+		admin = admin || scope == database.ScopeAdmin
+	}
+	if req.WorkspaceID != "" {
+		if admin {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "An admin key cannot be restricted to a workspace"})
+			return
+		}
+		if _, err := database.GetWorkspace(req.WorkspaceID); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+			return
+		}
+	}
+	if req.ExpiresIn < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid expiry"})
+		return
+	}
+
+	secret, err := generateSecureToken(32)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	raw := apiKeyPrefix + secret
+	now := time.Now().UTC()
+	key := &database.APIKey{
+		ID:          uuid.New().String(),
+		Name:        req.Name,
+		Prefix:      raw[:apiKeyPrefixLength],
+		KeyHash:     hashToken(raw),
+		Scopes:      req.Scopes,
+		WorkspaceID: req.WorkspaceID,
+		CreatedBy:   currentUser(c).Username,
+		CreatedAt:   now,
+	}
+	if req.ExpiresIn > 0 {
+		expires := now.Add(time.Duration(req.ExpiresIn) * time.Second)
+		key.ExpiresAt = &expires
+	}
+
+	if err := database.CreateAPIKey(key); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	detail := strings.Join(key.Scopes, ",")
+	if key.WorkspaceID != "" {
+		detail += " on workspace " + key.WorkspaceID
+	}
+	if key.ExpiresAt != nil {
+		detail += ", expires " + key.ExpiresAt.Format(time.RFC3339)
+	}
+	audit(c, database.AuditAPIKeyCreate, key.ID, key.Name, detail)
+
+	c.JSON(http.StatusCreated, APIKeyResponse{APIKey: key, Key: raw})
+}
+
+// RevokeAPIKey handles DELETE /api/admin/api-keys/:keyId
+func RevokeAPIKey(c *gin.Context) {
+	key, err := database.GetAPIKey(c.Param("keyId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	deleted, err := database.DeleteAPIKey(key.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	audit(c, database.AuditAPIKeyRevoke, key.ID, key.Name, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
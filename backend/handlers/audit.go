@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	ws "github.com/kywk/sheltie/backend/websocket"
+)
+
+const (
+	// defaultAuditLimit is how many audit events are listed without ?limit=
+	defaultAuditLimit = 100
+
+	// maxAuditLimit caps ?limit=
+	maxAuditLimit = 1000
+)
+
+// audit records an action of the request's user or share link guest
+func audit(c *gin.Context, action, target, targetName, detail string) {
+	auditAs(requestAuthor(c), action, target, targetName, detail)
+}
+
+// auditAs records an action of author. A failure is logged, not returned,
+// so that the action itself still succeeds.
+func auditAs(author ws.Author, action, target, targetName, detail string) {
+	err := database.CreateAuditEvent(&database.AuditEvent{
+		Action:     action,
+		ActorID:    author.ID,
+		ActorName:  author.Name,
+		Target:     target,
+		TargetName: targetName,
+		Detail:     detail,
+		IP:         author.IP,
+	})
+	if err != nil {
+		log.Printf("Error recording audit event: %v", err)
+	}
+}
+
+// workspaceName returns the name of a workspace for an audit event, or ""
+func workspaceName(id string) string {
+	if ws, err := database.GetWorkspace(id); err == nil {
+		return ws.Name
+	}
+	return ""
+}
+
+// ListAuditEvents handles GET /api/admin/audit?actor=&target=&action=&since=&until=&limit=,
+// listing audit events newest first. Actor is a user ID or name, target a
+// workspace, user or API key ID, and since and until are RFC 3339 times.
+func ListAuditEvents(c *gin.Context) {
+	filter := database.AuditFilter{
+		Actor:  c.Query("actor"),
+		Target: c.Query("target"),
+		Action: c.Query("action"),
+		Limit:  defaultAuditLimit,
+	}
+
+	for param, t := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if v := c.Query(param); v != "" {
+			parsed, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + param + " time, use RFC 3339"})
+				return
+			}
+			*t = parsed
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+			return
+		}
+		filter.Limit = min(limit, maxAuditLimit)
+	}
+
+	events, err := database.GetAuditEvents(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list audit events"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/ldapauth"
+)
+
+// errInvalidCredentials means an authenticator does not accept a username and password
+var errInvalidCredentials = errors.New("invalid username or password")
+
+// Authenticator checks a username and password for Login. It returns the
+// user to log in as, errInvalidCredentials if it does not accept them, or
+// another error if it could not check them.
+type Authenticator interface {
+	Authenticate(username, password string) (*database.User, error)
+}
+
+// authenticators are tried in order by Login
+var authenticators = []Authenticator{localAuthenticator{}}
+
+// SetupLDAP adds LDAP login after the local accounts if it is configured
+func SetupLDAP() {
+	if !cfg.LDAP.Enabled() {
+		return
+	}
+	authenticators = append(authenticators, &ldapAuthenticator{
+		client: ldapauth.NewClient(ldapauth.Config{
+			URL:          cfg.LDAP.URL,
+			StartTLS:     cfg.LDAP.StartTLS,
+			BindDN:       cfg.LDAP.BindDN,
+			BindPassword: cfg.LDAP.BindPassword,
+			BaseDN:       cfg.LDAP.BaseDN,
+			UserFilter:   cfg.LDAP.UserFilter,
+			NameAttr:     cfg.LDAP.NameAttr,
+			GroupAttr:    cfg.LDAP.GroupAttr,
+		}),
+	})
+}
+
+// passwordLogin returns the user of the first authenticator that accepts
+// the username and password. errInvalidCredentials means none did; another
+// error means one that might have could not be reached.
+func passwordLogin(username, password string) (*database.User, error) {
+	var failure error
+	for _, a := range authenticators {
+		user, err := a.Authenticate(username, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, errInvalidCredentials) {
+			log.Printf("Error checking password: %v", err)
+			failure = err
+		}
+	}
+	if failure != nil {
+		return nil, failure
+	}
+	return nil, errInvalidCredentials
+}
+
+// localAuthenticator checks the passwords of accounts stored in the database
+type localAuthenticator struct{}
+
+func (localAuthenticator) Authenticate(username, password string) (*database.User, error) {
+	user, err := database.GetUserByUsername(username)
+	if err != nil || !checkPassword(user.PasswordHash, password) {
+		return nil, errInvalidCredentials
+	}
+	return user, nil
+}
+
+// ldapAuthenticator checks passwords against an LDAP directory, creating
+// the user on first login with the role of their groups
+type ldapAuthenticator struct {
+	client *ldapauth.Client
+}
+
+func (a *ldapAuthenticator) Authenticate(username, password string) (*database.User, error) {
+	entry, err := a.client.Authenticate(username, password)
+	if errors.Is(err, ldapauth.ErrInvalidCredentials) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	role := groupRole(entry.Groups, cfg.LDAP.AdminGroups, cfg.LDAP.EditorGroups, cfg.LDAP.ViewerGroups, cfg.LDAP.DefaultRole)
+	if role == "" {
+		return nil, errInvalidCredentials
+	}
+	return externalUser("ldap|"+strings.ToLower(entry.Username), entry.Username, entry.DisplayName, role)
+}
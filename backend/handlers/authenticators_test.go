@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/kywk/sheltie/backend/config"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/ldapauth"
+)
+
+func TestGroupRole(t *testing.T) {
+	admin := []string{"cn=Sheltie Admins,ou=groups,dc=example,dc=com"}
+	editor := []string{"PMO", "Sheltie Editors"}
+	viewer := []string{"Everyone"}
+
+	tests := []struct {
+		name        string
+		groups      []string
+		defaultRole string
+		want        string
+	}{
+		{"admin by DN", []string{"cn=Sheltie Admins,ou=groups,dc=example,dc=com", "Sheltie Admins"}, "", database.RoleAdmin},
+		{"admin CN alone does not match a DN", []string{"cn=other,dc=example,dc=com", "Sheltie Admins"}, "", ""},
+		{"editor by CN", []string{"CN=PMO,OU=Groups,DC=example,DC=com", "PMO"}, "", database.RoleEditor},
+		{"highest role wins", []string{"Everyone", "PMO", "cn=Sheltie Admins,ou=groups,dc=example,dc=com"}, "", database.RoleAdmin},
+		{"viewer", []string{"cn=Everyone,dc=example,dc=com", "Everyone"}, database.RoleEditor, database.RoleViewer},
+		{"matching is exact", []string{"pmo", "Sheltie Editors "}, "", ""},
+		{"default role", []string{"Interns"}, database.RoleViewer, database.RoleViewer},
+		{"no groups", nil, database.RoleEditor, database.RoleEditor},
+		{"invalid default role", []string{"Interns"}, "owner", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := groupRole(tt.groups, admin, editor, viewer, tt.defaultRole); got != tt.want {
+				t.Errorf("groupRole(%q) = %q, want %q", tt.groups, got, tt.want)
+			}
+		})
+	}
+}
+
+// stubDirectory holds one user, amy, who is in the PMO group
+type stubDirectory struct{}
+
+func (stubDirectory) Bind(username, password string) error {
+	if username == "uid=amy,dc=example,dc=com" && password == "amy-pass" {
+		return nil
+	}
+	return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+}
+
+func (stubDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	if req.Filter != "(uid=amy)" {
+		return &ldap.SearchResult{}, nil
+	}
+	return &ldap.SearchResult{Entries: []*ldap.Entry{ldap.NewEntry("uid=amy,dc=example,dc=com", map[string][]string{
+		"displayName": {"Amy Chen"},
+		"memberOf":    {"CN=PMO,OU=Groups,DC=example,DC=com"},
+	})}}, nil
+}
+
+func (stubDirectory) Close() error { return nil }
+
+func TestLDAPAuthenticator(t *testing.T) {
+	if err := database.Init(filepath.Join(t.TempDir(), "sheltie.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg = &config.Config{LDAP: config.LDAPConfig{
+		URL:          "ldap://directory.example.com",
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(uid=%s)",
+		NameAttr:     "displayName",
+		GroupAttr:    "memberOf",
+		AdminGroups:  []string{"Sheltie Admins"},
+		EditorGroups: []string{"PMO"},
+	}}
+
+	client := ldapauth.NewClient(ldapauth.Config{
+		URL:        cfg.LDAP.URL,
+		BaseDN:     cfg.LDAP.BaseDN,
+		UserFilter: cfg.LDAP.UserFilter,
+		NameAttr:   cfg.LDAP.NameAttr,
+		GroupAttr:  cfg.LDAP.GroupAttr,
+	})
+	client.Dial = func() (ldapauth.Conn, error) { return stubDirectory{}, nil }
+	a := &ldapAuthenticator{client: client}
+
+	if _, err := a.Authenticate("amy", "wrong"); !errors.Is(err, errInvalidCredentials) {
+		t.Errorf("wrong password: error = %v, want errInvalidCredentials", err)
+	}
+
+	user, err := a.Authenticate("amy", "amy-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "amy" || user.DisplayName != "Amy Chen" || user.Role != database.RoleEditor || user.ExternalID != "ldap|amy" {
+		t.Errorf("user = %+v", user)
+	}
+
+	// the role follows the directory's groups on the next login
+	cfg.LDAP.EditorGroups = nil
+	cfg.LDAP.ViewerGroups = []string{"CN=PMO,OU=Groups,DC=example,DC=com"}
+	again, err := a.Authenticate("amy", "amy-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != user.ID || again.Role != database.RoleViewer {
+		t.Errorf("second login = %+v, want %s as viewer", again, user.ID)
+	}
+
+	// groups that map to no role keep the user out
+	cfg.LDAP.ViewerGroups = nil
+	if _, err := a.Authenticate("amy", "amy-pass"); !errors.Is(err, errInvalidCredentials) {
+		t.Errorf("no role: error = %v, want errInvalidCredentials", err)
+	}
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+)
+
+// BlameLineResponse is a numbered line of the markdown with its last author
+type BlameLineResponse struct {
+	Line int `json:"line"` // 1-based
+	database.BlameLine
+}
+
+// BlameResponse lists the lines of a workspace's markdown at a version
+type BlameResponse struct {
+	WorkspaceID string              `json:"workspaceId"`
+	Version     int64               `json:"version"`
+	Lines       []BlameLineResponse `json:"lines"`
+}
+
+// GetWorkspaceBlame handles GET /api/workspaces/:id/blame, listing every line
+// of the markdown with the user who last changed it and when
+func GetWorkspaceBlame(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	lines, version := wsHub.GetVersionManager().GetDocument(id, database.ChannelMarkdown).Blame()
+
+	resp := BlameResponse{WorkspaceID: id, Version: version, Lines: make([]BlameLineResponse, len(lines))}
+	for i, l := range lines {
+		resp.Lines[i] = BlameLineResponse{Line: i + 1, BlameLine: l}
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/export"
+)
+
+// calendarContentType is the MIME type of an iCalendar feed
+const calendarContentType = "text/calendar; charset=utf-8"
+
+// GetWorkspaceCalendar handles GET /api/workspaces/:id/calendar.ics
+func GetWorkspaceCalendar(c *gin.Context) {
+	id := c.Param("id")
+
+	ws, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	writeCalendar(c, "Sheltie - "+ws.Name, []export.WorkspaceProjects{{
+		WorkspaceID:   ws.ID,
+		WorkspaceName: ws.Name,
+		Projects:      liveProjects(id),
+	}})
+}
+
+// GetCalendar handles GET /api/calendar.ics, the phases of every workspace
+// the user can see. Calendar clients cannot send headers, so the admin token
+// or an API key may also be passed as ?token=.
+func GetCalendar(c *gin.Context) {
+	token := c.GetHeader("Authorization")
+	if token == "" {
+		token = c.Query("token")
+	}
+	if !authenticateRequest(c, token) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	sources, err := allWorkspaceProjects(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+		return
+	}
+	writeCalendar(c, "Sheltie", sources)
+}
+
+// writeCalendar renders the phases of the sources as an iCalendar feed
+func writeCalendar(c *gin.Context, name string, sources []export.WorkspaceProjects) {
+	var buf bytes.Buffer
+	if err := export.ICS(&buf, name, sources, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render calendar"})
+		return
+	}
+	c.Data(http.StatusOK, calendarContentType, buf.Bytes())
+}
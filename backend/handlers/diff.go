@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/diff"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// DiffSide describes one side of a diff
+type DiffSide struct {
+	VersionID *int64     `json:"versionId,omitempty"` // nil for the live document
+	Version   int64      `json:"version"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	Label     string     `json:"label"`
+}
+
+// DiffResponse represents a diff between two versions of a workspace document
+type DiffResponse struct {
+	Channel string      `json:"channel"`
+	From    DiffSide    `json:"from"`
+	To      DiffSide    `json:"to"`
+	Stats   diff.Stats  `json:"stats"`
+	Hunks   []diff.Hunk `json:"hunks"`
+	Unified string      `json:"unified"`
+}
+
+// diffSource is a resolved side of a diff request
+type diffSource struct {
+	side    DiffSide
+	channel string
+	content string
+}
+
+// DiffWorkspaceVersions handles GET /api/workspaces/:id/diff
+//
+// Query parameters:
+//   - from: snapshot ID to diff from
+//   - since: date (YYYY-MM-DD) or RFC 3339 time; diffs from the snapshot in effect at that time
+//   - to: snapshot ID to diff to (default: the live document)
+//   - channel: markdown (default) or collie; inferred from snapshot IDs when given
+//   - format: json (default) or unified for a plain text patch
+func DiffWorkspaceVersions(c *gin.Context) {
+	id := c.Param("id")
+
+	workspace, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	channel := c.DefaultQuery("channel", database.ChannelMarkdown)
+	if !validChannel(channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel"})
+		return
+	}
+
+	var from *diffSource
+	switch {
+	case c.Query("from") != "":
+		if from, err = snapshotSource(id, c.Query("from")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+			return
+		}
+		channel = from.channel
+	case c.Query("since") != "":
+		since, err := parseSince(c.Query("since"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid since date, expected YYYY-MM-DD"})
+			return
+		}
+		v, err := database.GetWorkspaceVersionAt(id, channel, since)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No version found for that date"})
+			return
+		}
+		from = versionSource(v)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either from or since is required"})
+		return
+	}
+
+	var to *diffSource
+	if c.Query("to") != "" {
+		if to, err = snapshotSource(id, c.Query("to")); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+			return
+		}
+		if to.channel != channel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Versions belong to different channels"})
+			return
+		}
+	} else {
+		to = liveSource(workspace, channel)
+	}
+
+	hunks := diff.Compute(from.content, to.content, diffContext)
+	unified := diff.Unified(from.side.Label, to.side.Label, hunks)
+
+	if c.Query("format") == "unified" {
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(unified))
+		return
+	}
+
+	if hunks == nil {
+		hunks = []diff.Hunk{}
+	}
+	c.JSON(http.StatusOK, DiffResponse{
+		Channel: channel,
+		From:    from.side,
+		To:      to.side,
+		Stats:   diff.Summarize(hunks),
+		Hunks:   hunks,
+		Unified: unified,
+	})
+}
+
+// snapshotSource loads a snapshot by its ID parameter
+func snapshotSource(workspaceID, param string) (*diffSource, error) {
+	versionID, err := strconv.ParseInt(param, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	v, err := database.GetWorkspaceVersion(workspaceID, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return versionSource(v), nil
+}
+
+// versionSource wraps a snapshot as a diff side
+func versionSource(v *database.WorkspaceVersion) *diffSource {
+	createdAt := v.CreatedAt
+	return &diffSource{
+		side: DiffSide{
+			VersionID: &v.ID,
+			Version:   v.Version,
+			CreatedAt: &createdAt,
+			Label:     fmt.Sprintf("%s@%d (%s)", v.Channel, v.ID, v.CreatedAt.Format("2006-01-02 15:04")),
+		},
+		channel: v.Channel,
+		content: v.Content,
+	}
+}
+
+// liveSource returns the current state of a workspace channel, which may
+// include edits that have not been auto-saved yet
+func liveSource(workspace *database.Workspace, channel string) *diffSource {
+	content, version, _ := wsHub.GetVersionManager().GetDocument(workspace.ID, channel).GetState()
+	return &diffSource{
+		side:    DiffSide{Version: version, Label: channel + "@current"},
+		channel: channel,
+		content: content,
+	}
+}
+
+// parseSince parses a date or timestamp in local time; a bare date means the start of that day
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	return t.Local(), err
+}
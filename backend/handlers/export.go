@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/export"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// Content types of the exports
+const (
+	pptxContentType = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	pdfContentType  = "application/pdf"
+	htmlContentType = "text/html; charset=utf-8"
+	csvContentType  = "text/csv; charset=utf-8"
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// renderFunc writes the projects of a workspace in an export format
+type renderFunc func(w io.Writer, title string, projects []parser.Project) error
+
+// tableFunc writes a project summary table in an export format
+type tableFunc func(w io.Writer, t export.Table) error
+
+// ExportPPTX handles GET /api/workspaces/:id/export.pptx
+func ExportPPTX(c *gin.Context) {
+	exportWorkspace(c, ".pptx", pptxContentType, export.PPTX)
+}
+
+// ExportPDF handles GET /api/workspaces/:id/export.pdf
+func ExportPDF(c *gin.Context) {
+	exportWorkspace(c, ".pdf", pdfContentType, export.PDF)
+}
+
+// ExportHTML handles GET /api/workspaces/:id/export.html
+func ExportHTML(c *gin.Context) {
+	exportWorkspace(c, ".html", htmlContentType, export.HTML)
+}
+
+// ExportCSV handles GET /api/workspaces/:id/export.csv
+func ExportCSV(c *gin.Context) {
+	exportWorkspaceTable(c, ".csv", csvContentType, export.CSV)
+}
+
+// ExportXLSX handles GET /api/workspaces/:id/export.xlsx
+func ExportXLSX(c *gin.Context) {
+	exportWorkspaceTable(c, ".xlsx", xlsxContentType, export.XLSX)
+}
+
+// ExportAllCSV handles GET /api/admin/export.csv
+func ExportAllCSV(c *gin.Context) {
+	exportAllTable(c, ".csv", csvContentType, export.CSV)
+}
+
+// ExportAllXLSX handles GET /api/admin/export.xlsx
+func ExportAllXLSX(c *gin.Context) {
+	exportAllTable(c, ".xlsx", xlsxContentType, export.XLSX)
+}
+
+// exportWorkspace renders the live content of a workspace as a download
+// named after the workspace
+func exportWorkspace(c *gin.Context, ext, contentType string, render renderFunc) {
+	id := c.Param("id")
+
+	ws, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := render(&buf, ws.Name, liveProjects(id)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export workspace"})
+		return
+	}
+
+	setAttachment(c, ws.Name+ext)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// setAttachment marks the response as a download; the UTF-8 filename* form
+// keeps Chinese workspace names intact
+func setAttachment(c *gin.Context, filename string) {
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="export%s"; filename*=UTF-8''%s`, path.Ext(filename), url.PathEscape(filename)))
+}
+
+// exportWorkspaceTable writes the summary table of a workspace as a download
+func exportWorkspaceTable(c *gin.Context, ext, contentType string, write tableFunc) {
+	id := c.Param("id")
+
+	ws, err := database.GetWorkspace(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	table := export.SummaryTable([]export.WorkspaceProjects{{
+		WorkspaceID:   ws.ID,
+		WorkspaceName: ws.Name,
+		Projects:      liveProjects(id),
+	}}, false)
+	writeTable(c, ws.Name+ext, contentType, table, write)
+}
+
+// exportAllTable writes the summary table of every workspace as a download
+func exportAllTable(c *gin.Context, ext, contentType string, write tableFunc) {
+	sources, err := allWorkspaceProjects(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+		return
+	}
+	writeTable(c, "sheltie"+ext, contentType, export.SummaryTable(sources, true), write)
+}
+
+func writeTable(c *gin.Context, filename, contentType string, table export.Table, write tableFunc) {
+	var buf bytes.Buffer
+	if err := write(&buf, table); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export projects"})
+		return
+	}
+
+	setAttachment(c, filename)
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// allWorkspaceProjects returns the saved projects of every workspace the
+// user can see, with collie phases merged in
+func allWorkspaceProjects(c *gin.Context) ([]export.WorkspaceProjects, error) {
+	workspaces, err := visibleWorkspaces(c)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]export.WorkspaceProjects, 0, len(workspaces))
+	for _, ws := range workspaces {
+		sources = append(sources, export.WorkspaceProjects{
+			WorkspaceID:   ws.ID,
+			WorkspaceName: ws.Name,
+			Projects:      parser.MergeColliePhases(parser.Parse(ws.Content), parser.ParseCollie(ws.CollieContent)),
+		})
+	}
+	return sources, nil
+}
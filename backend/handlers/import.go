@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/importer"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// maxImportSize limits the size of an uploaded spreadsheet
+const maxImportSize = 10 << 20
+
+// maxImportErrors limits the row errors in a response
+const maxImportErrors = 50
+
+// Import modes
+const (
+	importAppend = "append"
+	importMerge  = "merge"
+)
+
+// ImportWorkspaceProjects handles POST /api/workspaces/:id/import
+//
+// The multipart form has the spreadsheet in file, an optional JSON column
+// mapping in mapping, and mode: append (the default) adds every row as a new
+// project, merge updates the project of the same name and appends the rest.
+// The edit goes through the hub so live editors see it immediately.
+func ImportWorkspaceProjects(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	fh, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		return
+	}
+	f, err := fh.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
+	}
+	data, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file"})
+		return
+	}
+
+	var mapping importer.Mapping
+	if s := c.PostForm("mapping"); s != "" {
+		if err := json.Unmarshal([]byte(s), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mapping"})
+			return
+		}
+	}
+
+	mode := c.DefaultPostForm("mode", importAppend)
+	if mode != importAppend && mode != importMerge {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "mode must be append or merge"})
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = importer.DetectFormat(fh.Filename, data)
+	}
+	records, err := importer.Read(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	inputs, rowErrs := importer.Projects(records, mapping)
+	if len(rowErrs) > 0 {
+		if len(rowErrs) > maxImportErrors {
+			rowErrs = rowErrs[:maxImportErrors]
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": rowErrs[0].Error(), "rows": rowErrs})
+		return
+	}
+	if len(inputs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No projects to import"})
+		return
+	}
+
+	doc := wsHub.GetVersionManager().GetDocument(id, database.ChannelMarkdown)
+	base, version, _ := doc.GetState()
+
+	content := base
+	created, updated := []string{}, []string{}
+	if mode == importAppend {
+		content = parser.AppendProjects(content, inputs)
+		for _, in := range inputs {
+			created = append(created, in.Name)
+		}
+	} else {
+		for _, in := range inputs {
+			var existed bool
+			if content, existed, err = parser.MergeProject(content, in); err != nil {
+				if errors.Is(err, parser.ErrInvalidValue) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": in.Name + ": " + err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import projects"})
+				return
+			}
+			if existed {
+				updated = append(updated, in.Name)
+			} else {
+				created = append(created, in.Name)
+			}
+		}
+	}
+
+	result, err := wsHub.EditContent(id, database.ChannelMarkdown, base, content, version, requestAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Document changed, please retry"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"created": created,
+		"updated": updated,
+		"version": result.Version,
+		"hash":    result.Hash,
+	})
+}
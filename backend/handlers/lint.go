@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// LintRequest represents the optional request body for linting; without
+// content the live workspace document is checked
+type LintRequest struct {
+	Content *string `json:"content"`
+}
+
+// LintResponse represents the diagnostics for a document
+type LintResponse struct {
+	Version     int64               `json:"version,omitempty"` // version of the live document that was checked
+	Diagnostics []parser.Diagnostic `json:"diagnostics"`
+}
+
+// LintWorkspace handles POST /api/workspaces/:id/lint
+func LintWorkspace(c *gin.Context) {
+	id := c.Param("id")
+
+	var req LintRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	if req.Content != nil {
+		c.JSON(http.StatusOK, LintResponse{Diagnostics: parser.Lint(*req.Content)})
+		return
+	}
+
+	content, version, _ := wsHub.GetVersionManager().GetDocument(id, database.ChannelMarkdown).GetState()
+	c.JSON(http.StatusOK, LintResponse{Version: version, Diagnostics: parser.Lint(content)})
+}
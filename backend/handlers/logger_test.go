@@ -0,0 +1,22 @@
+package handlers
+
+import "testing"
+
+func TestRedactQuery(t *testing.T) {
+	tests := []struct {
+		path, want string
+	}{
+		{"/api/workspaces/w1", "/api/workspaces/w1"},
+		{"/api/workspaces/w1/diff?from=1&to=2", "/api/workspaces/w1/diff?from=1&to=2"},
+		{"/ws/w1?token=abc.def&username=Amy", "/ws/w1?token=REDACTED&username=Amy"},
+		{"/api/workspaces/w1/calendar.ics?share=s3cret", "/api/workspaces/w1/calendar.ics?share=REDACTED"},
+		{"/ws/w1?share=a&token=b&share=c", "/ws/w1?share=REDACTED&token=REDACTED"},
+		{"/api/auth/oidc/callback?state=xyz&code=c0de", "/api/auth/oidc/callback?code=REDACTED&state=xyz"},
+		{"/api/calendar.ics?token=%zz", "/api/calendar.ics?REDACTED"},
+	}
+	for _, tt := range tests {
+		if got := redactQuery(tt.path); got != tt.want {
+			t.Errorf("redactQuery(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
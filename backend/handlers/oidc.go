@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/oidc"
+)
+
+const (
+	// oidcLoginTimeout is how long a user has to log in at the provider
+	oidcLoginTimeout = 10 * time.Minute
+
+	// oidcStateCookie ties the callback to the browser that started the login
+	oidcStateCookie = "sheltie_oidc_state"
+
+	// oidcDefaultRedirect is where the browser returns after logging in
+	oidcDefaultRedirect = "/admin"
+)
+
+// oidcLogin is a login waiting for the provider's callback
+type oidcLogin struct {
+	nonce    string
+	verifier string
+	redirect string
+	expires  time.Time
+}
+
+var (
+	oidcProvider *oidc.Provider
+	oidcLogins   = make(map[string]oidcLogin) // by state
+	oidcMu       sync.Mutex
+)
+
+// SetupOIDC enables single sign-on if it is configured
+func SetupOIDC() {
+	if !cfg.OIDC.Enabled() {
+		return
+	}
+	oidcProvider = oidc.NewProvider(oidc.Config{
+		Issuer:       cfg.OIDC.Issuer,
+		ClientID:     cfg.OIDC.ClientID,
+		ClientSecret: cfg.OIDC.ClientSecret,
+		RedirectURL:  cfg.OIDC.RedirectURL,
+		Scopes:       cfg.OIDC.Scopes,
+	})
+}
+
+// GetAuthProviders handles GET /api/auth/providers, so the login page knows
+// which login methods to offer
+func GetAuthProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"password": true,
+		"ldap":     cfg.LDAP.Enabled(),
+		"oidc":     oidcProvider != nil,
+	})
+}
+
+// OIDCLogin handles GET /api/auth/oidc/login, redirecting the browser to the
+// provider. ?redirect= is the path to return to, /admin by default.
+func OIDCLogin(c *gin.Context) {
+	if oidcProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	redirect, _, _ := strings.Cut(c.Query("redirect"), "#")
+	if !localPath(redirect) {
+		redirect = oidcDefaultRedirect
+	}
+
+	var login oidcLogin
+	state, err := oidc.RandomString()
+	if err == nil {
+		login.nonce, err = oidc.RandomString()
+	}
+	if err == nil {
+		login.verifier, err = oidc.RandomString()
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+
+	authURL, err := oidcProvider.AuthCodeURL(c.Request.Context(), state, login.nonce, login.verifier)
+	if err != nil {
+		log.Printf("Error starting OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	login.redirect = redirect
+	login.expires = time.Now().Add(oidcLoginTimeout)
+	oidcMu.Lock()
+	for s, l := range oidcLogins {
+		if time.Now().After(l.expires) {
+			delete(oidcLogins, s)
+		}
+	}
+	oidcLogins[state] = login
+	oidcMu.Unlock()
+
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   int(oidcLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback handles GET /api/auth/oidc/callback. The browser is sent
+// back to the page that started the login with the session token, or an
+// error, in the URL fragment, where the admin page picks it up.
+func OIDCCallback(c *gin.Context) {
+	if oidcProvider == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Single sign-on is not configured"})
+		return
+	}
+
+	state := c.Query("state")
+	cookie, _ := c.Cookie(oidcStateCookie)
+	http.SetCookie(c.Writer, &http.Cookie{Name: oidcStateCookie, Path: "/", MaxAge: -1})
+
+	oidcMu.Lock()
+	login, ok := oidcLogins[state]
+	delete(oidcLogins, state)
+	oidcMu.Unlock()
+	if !ok || state == "" || cookie != state || time.Now().After(login.expires) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired, please try again"})
+		return
+	}
+
+	fail := func(msg string) {
+		c.Redirect(http.StatusFound, login.redirect+"#error="+url.QueryEscape(msg))
+	}
+	if e := c.Query("error"); e != "" {
+		fail("Login was cancelled: " + e)
+		return
+	}
+
+	claims, err := oidcProvider.Exchange(c.Request.Context(), c.Query("code"), login.verifier, login.nonce)
+	if err != nil {
+		log.Printf("Error completing OIDC login: %v", err)
+		fail("Login failed")
+		return
+	}
+
+	role := oidcRole(claims.Strings(cfg.OIDC.GroupsClaim))
+	if role == "" {
+		fail("Your account is not allowed to use Sheltie")
+		return
+	}
+	user, err := oidcUser(claims, role)
+	if err != nil {
+		log.Printf("Error saving OIDC user: %v", err)
+		fail("Login failed")
+		return
+	}
+
+	token, err := startSession(c, user)
+	if err != nil {
+		fail("Failed to create session")
+		return
+	}
+	auditAs(userAuthor(c, user), database.AuditLogin, user.ID, user.Username, "oidc")
+	c.Redirect(http.StatusFound, login.redirect+"#token="+url.QueryEscape(token))
+}
+
+// oidcRole maps a user's groups to a role, or "" if they are not allowed in
+func oidcRole(groups []string) string {
+	return groupRole(groups, cfg.OIDC.AdminGroups, cfg.OIDC.EditorGroups, cfg.OIDC.ViewerGroups, cfg.OIDC.DefaultRole)
+}
+
+// groupRole returns the role of the first group list, from admin to viewer,
+// that contains one of the groups; defaultRole if none does, or "" if that
+// is not a valid role
+func groupRole(groups, admin, editor, viewer []string, defaultRole string) string {
+	in := func(allowed []string) bool {
+		for _, g := range groups {
+			for _, a := range allowed {
+				if g == a {
+					return true
+				}
+			}
+		}
+		return false
+	}
+	switch {
+	case in(admin):
+		return database.RoleAdmin
+	case in(editor):
+		return database.RoleEditor
+	case in(viewer):
+		return database.RoleViewer
+	}
+	if database.ValidRole(defaultRole) {
+		return defaultRole
+	}
+	return ""
+}
+
+// oidcUser returns the user linked to the provider account, creating it on
+// first login
+func oidcUser(claims oidc.Claims, role string) (*database.User, error) {
+	username := claims.String("preferred_username")
+	if username == "" {
+		username = claims.String("email")
+	}
+	if username == "" || strings.ContainsAny(username, " \t\r\n") {
+		username = claims.String("sub")
+	}
+	return externalUser(claims.String("iss")+"|"+claims.String("sub"), username, claims.String("name"), role)
+}
+
+// externalUser returns the user linked to an account of an external login
+// method, creating it on first login. The role and display name follow the
+// external account on every login.
+func externalUser(externalID, username, name, role string) (*database.User, error) {
+	if user, err := database.GetUserByExternalID(externalID); err == nil {
+		if role != user.Role || (name != "" && name != user.DisplayName) {
+			user.Role = role
+			if name != "" {
+				user.DisplayName = name
+			}
+			if err := database.UpdateUser(user); err != nil {
+				return nil, err
+			}
+		}
+		return user, nil
+	}
+
+	// never take over a local account with the same name
+	if _, err := database.GetUserByUsername(username); err == nil {
+		sum := sha1.Sum([]byte(externalID))
+		username += "-" + hex.EncodeToString(sum[:3])
+	}
+	if name == "" {
+		name = username
+	}
+
+	user := &database.User{
+		ID:          uuid.New().String(),
+		Username:    username,
+		DisplayName: name,
+		Role:        role,
+		ExternalID:  externalID,
+	}
+	return user, database.CreateUser(user)
+}
+
+// localPath reports whether s is a path on this site, safe to redirect to
+func localPath(s string) bool {
+	return strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") && !strings.ContainsAny(s, "\\\r\n")
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// defaultGoLiveDays is how far ahead the portfolio looks for upcoming go-lives
+const defaultGoLiveDays = 30
+
+// PortfolioProject represents a project in the cross-workspace portfolio
+type PortfolioProject struct {
+	WorkspaceID   string `json:"workspaceId"`
+	WorkspaceName string `json:"workspaceName"`
+	Name          string `json:"name"`
+	Slug          string `json:"slug"`
+	Status        string `json:"status"`
+	CurrentState  string `json:"currentState"`
+	Progress      int    `json:"progress"`
+	Contact       string `json:"contact"`
+	Category      string `json:"category"`
+	GoLiveDate    string `json:"goLiveDate,omitempty"`
+}
+
+// PortfolioResponse aggregates the projects of every workspace
+type PortfolioResponse struct {
+	Workspaces  int                `json:"workspaces"`
+	Projects    int                `json:"projects"`
+	ByStatus    map[string]int     `json:"byStatus"`
+	ByState     map[string]int     `json:"byState"`
+	ByCategory  map[string]int     `json:"byCategory"`
+	ByContact   map[string]int     `json:"byContact"`
+	Red         []PortfolioProject `json:"red"`
+	GoingLive   []PortfolioProject `json:"goingLive"`
+	GoLiveFrom  string             `json:"goLiveFrom"`
+	GoLiveUntil string             `json:"goLiveUntil"`
+}
+
+// GetPortfolio handles GET /api/admin/portfolio?days=30
+func GetPortfolio(c *gin.Context) {
+	days := defaultGoLiveDays
+	if s := c.Query("days"); s != "" {
+		d, err := strconv.Atoi(s)
+		if err != nil || d < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days"})
+			return
+		}
+		days = d
+	}
+
+	workspaces, err := visibleWorkspaces(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
+		return
+	}
+
+	today := time.Now()
+	resp := PortfolioResponse{
+		Workspaces:  len(workspaces),
+		ByStatus:    map[string]int{},
+		ByState:     map[string]int{},
+		ByCategory:  map[string]int{},
+		ByContact:   map[string]int{},
+		Red:         []PortfolioProject{},
+		GoingLive:   []PortfolioProject{},
+		GoLiveFrom:  today.Format("2006-01-02"),
+		GoLiveUntil: today.AddDate(0, 0, days).Format("2006-01-02"),
+	}
+
+	for _, ws := range workspaces {
+		for _, p := range parser.SortProjects(liveProjects(ws.ID)) {
+			resp.Projects++
+			resp.ByStatus[p.Status]++
+			resp.ByState[p.CurrentState]++
+			resp.ByCategory[p.Category]++
+			resp.ByContact[p.Contact]++
+
+			item := PortfolioProject{
+				WorkspaceID:   ws.ID,
+				WorkspaceName: ws.Name,
+				Name:          p.Name,
+				Slug:          p.Slug,
+				Status:        p.Status,
+				CurrentState:  p.CurrentState,
+				Progress:      p.Progress,
+				Contact:       p.Contact,
+				Category:      p.Category,
+				GoLiveDate:    parser.GoLiveDate(p.Phases),
+			}
+			if p.Status == parser.StatusRed {
+				resp.Red = append(resp.Red, item)
+			}
+			if item.GoLiveDate >= resp.GoLiveFrom && item.GoLiveDate <= resp.GoLiveUntil {
+				resp.GoingLive = append(resp.GoingLive, item)
+			}
+		}
+	}
+
+	sort.SliceStable(resp.GoingLive, func(i, j int) bool {
+		return resp.GoingLive[i].GoLiveDate < resp.GoingLive[j].GoLiveDate
+	})
+
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	ws "github.com/kywk/sheltie/backend/websocket"
+)
+
+func TestGetPortfolioUsesColliePhases(t *testing.T) {
+	if err := database.Init(filepath.Join(t.TempDir(), "sheltie.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	saved := wsHub
+	t.Cleanup(func() { wsHub = saved })
+	wsHub = ws.NewHub()
+
+	goLive := time.Now().AddDate(0, 0, 10).Format("2006-01-02")
+	err := database.CreateWorkspace(&database.Workspace{
+		ID:   "w1",
+		Name: "PMO",
+		Content: "# 報表平台\n## 基本資訊\n- 燈號: 綠\n- 時程:\n" +
+			"  - PROD: 2020-01-01 ~ 2020-01-02\n",
+		CollieContent: "# 報表平台\n- PROD, " + goLive + ", " + goLive + ": Amy\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/admin/portfolio", nil)
+	c.Set(contextUser, &database.User{ID: "u1", Role: database.RoleAdmin})
+	GetPortfolio(c)
+
+	var resp PortfolioResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	// the collie schedule replaces the 時程 in the markdown, as on the slides
+	if len(resp.GoingLive) != 1 || resp.GoingLive[0].GoLiveDate != goLive {
+		t.Errorf("goingLive = %+v, want 報表平台 on %s", resp.GoingLive, goLive)
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// UpdateProjectRequest represents the request body for updating a project;
+// only the fields that are present are changed
+type UpdateProjectRequest struct {
+	Status       *string `json:"status"`
+	CurrentState *string `json:"currentState"`
+	Progress     *int    `json:"progress"`
+	Contact      *string `json:"contact"`
+	Category     *string `json:"category"`
+}
+
+// ProjectUpdateResponse represents a project after an update
+type ProjectUpdateResponse struct {
+	Project parser.Project `json:"project"`
+	Version int64          `json:"version"`
+	Hash    string         `json:"hash"`
+}
+
+// ListWorkspaceProjects handles GET /api/workspaces/:id/projects
+func ListWorkspaceProjects(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, parser.Parse(liveContent(id)))
+}
+
+// GetWorkspaceProject handles GET /api/workspaces/:id/projects/:slug
+func GetWorkspaceProject(c *gin.Context) {
+	id := c.Param("id")
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	projects := parser.Parse(liveContent(id))
+	i := parser.FindSlug(projects, c.Param("slug"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, projects[i])
+}
+
+// UpdateWorkspaceProject handles PATCH /api/workspaces/:id/projects/:slug
+//
+// Only the lines of the changed 基本資訊 fields are rewritten, and the edit
+// goes through the hub so live editors see it immediately.
+func UpdateWorkspaceProject(c *gin.Context) {
+	id := c.Param("id")
+
+	var req UpdateProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	doc := wsHub.GetVersionManager().GetDocument(id, database.ChannelMarkdown)
+	base, version, _ := doc.GetState()
+	before := parser.Parse(base)
+	i := parser.FindSlug(before, c.Param("slug"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+		return
+	}
+	old := before[i]
+
+	var progress *string
+	if req.Progress != nil {
+		p := parser.FormatProgress(*req.Progress)
+		progress = &p
+	}
+
+	updates := []struct {
+		field string
+		value *string
+	}{
+		{parser.FieldStatus, req.Status},
+		{parser.FieldCurrentState, req.CurrentState},
+		{parser.FieldProgress, progress},
+		{parser.FieldContact, req.Contact},
+		{parser.FieldCategory, req.Category},
+	}
+
+	content := base
+	for _, u := range updates {
+		if u.value == nil {
+			continue
+		}
+		var err error
+		if content, err = parser.SetField(content, i, u.field, *u.value); err != nil {
+			if errors.Is(err, parser.ErrInvalidValue) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+			return
+		}
+	}
+
+	result, err := wsHub.EditContent(id, database.ChannelMarkdown, base, content, version, requestAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Document changed, please retry"})
+		return
+	}
+
+	// Concurrent edits may have moved the project, so look it up again
+	projects := parser.Parse(result.Content)
+	if i = parser.FindSlug(projects, c.Param("slug")); i < 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Project was removed by a concurrent edit"})
+		return
+	}
+
+	var changes []string
+	for _, u := range updates {
+		if u.value == nil {
+			continue
+		}
+		if from, to := old.Field(u.field), projects[i].Field(u.field); from != to {
+			changes = append(changes, fmt.Sprintf("%s %q -> %q", u.field, from, to))
+		}
+	}
+	if len(changes) > 0 {
+		audit(c, database.AuditProjectUpdate, id, workspaceName(id),
+			fmt.Sprintf("project %s: %s", c.Param("slug"), strings.Join(changes, ", ")))
+	}
+
+	c.JSON(http.StatusOK, ProjectUpdateResponse{
+		Project: projects[i],
+		Version: result.Version,
+		Hash:    result.Hash,
+	})
+}
+
+// liveContent returns the current markdown of a workspace, including edits
+// that have not been auto-saved yet
+func liveContent(workspaceID string) string {
+	content, _, _ := wsHub.GetVersionManager().GetDocument(workspaceID, database.ChannelMarkdown).GetState()
+	return content
+}
+
+// liveProjects returns the projects of a workspace as the slides show them:
+// phases from the collie document replace the 時程 of the same project
+func liveProjects(workspaceID string) []parser.Project {
+	collie, _, _ := wsHub.GetVersionManager().GetDocument(workspaceID, database.ChannelCollie).GetState()
+	return parser.MergeColliePhases(parser.Parse(liveContent(workspaceID)), parser.ParseCollie(collie))
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+)
+
+// SessionResponse represents a session of the logged in user
+type SessionResponse struct {
+	*database.Session
+	Current bool `json:"current"`
+}
+
+// ListSessions handles GET /api/admin/sessions, the sessions of the logged in user
+func ListSessions(c *gin.Context) {
+	sessions, err := database.GetUserSessions(currentUser(c).ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	current := currentSession(c).ID
+	resp := make([]SessionResponse, len(sessions))
+	for i, s := range sessions {
+		resp[i] = SessionResponse{Session: s, Current: s.ID == current}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RevokeSession handles DELETE /api/admin/sessions/:sessionId, logging out
+// one of the logged in user's sessions
+func RevokeSession(c *gin.Context) {
+	user := currentUser(c)
+	deleted, err := database.DeleteSession(user.ID, c.Param("sessionId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	audit(c, database.AuditSessionRevoke, user.ID, user.Username, "session "+c.Param("sessionId"))
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
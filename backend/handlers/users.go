@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kywk/sheltie/backend/database"
+)
+
+// minPasswordLength is the shortest password accepted for a user
+const minPasswordLength = 8
+
+// CreateUserRequest represents the request body for creating a user
+type CreateUserRequest struct {
+	Username    string `json:"username" binding:"required"`
+	DisplayName string `json:"displayName"`
+	Role        string `json:"role" binding:"required"`
+	Password    string `json:"password" binding:"required"`
+}
+
+// UpdateUserRequest represents the request body for updating a user; only
+// the fields that are present are changed
+type UpdateUserRequest struct {
+	Username    *string `json:"username"`
+	DisplayName *string `json:"displayName"`
+	Role        *string `json:"role"`
+	Password    *string `json:"password"`
+}
+
+// ChangePasswordRequest represents the request body for changing one's own password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+// ListUsers handles GET /api/admin/users
+func ListUsers(c *gin.Context) {
+	users, err := database.GetAllUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list users"})
+		return
+	}
+	c.JSON(http.StatusOK, users)
+}
+
+// CreateUser handles POST /api/admin/users
+func CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Username, role and password are required"})
+		return
+	}
+
+	req.Username = strings.TrimSpace(req.Username)
+	if msg := validateUser(req.Username, req.Role); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if len(req.Password) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+	if _, err := database.GetUserByUsername(req.Username); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		return
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+	if req.DisplayName == "" {
+		req.DisplayName = req.Username
+	}
+
+	user := &database.User{
+		ID:           uuid.New().String(),
+		Username:     req.Username,
+		DisplayName:  req.DisplayName,
+		Role:         req.Role,
+		PasswordHash: hash,
+	}
+	if err := database.CreateUser(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+	audit(c, database.AuditUserCreate, user.ID, user.Username, "role "+user.Role)
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// UpdateUser handles PUT /api/admin/users/:userId
+//
+// Changing a user's role or password logs them out everywhere.
+func UpdateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	user, err := database.GetUser(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	old := *user
+
+	revoke := false
+	if req.Username != nil {
+		username := strings.TrimSpace(*req.Username)
+		if existing, err := database.GetUserByUsername(username); err == nil && existing.ID != user.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+			return
+		}
+		user.Username = username
+	}
+	if req.DisplayName != nil {
+		user.DisplayName = *req.DisplayName
+	}
+	if req.Role != nil && *req.Role != user.Role {
+		if user.Role == database.RoleAdmin && lastAdmin() {
+			c.JSON(http.StatusConflict, gin.H{"error": "Cannot demote the last admin"})
+			return
+		}
+		user.Role = *req.Role
+		revoke = true
+	}
+	if msg := validateUser(user.Username, user.Role); msg != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+	if req.Password != nil {
+		if len(*req.Password) < minPasswordLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+			return
+		}
+		if user.PasswordHash, err = hashPassword(*req.Password); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+			return
+		}
+		revoke = true
+	}
+
+	if err := database.UpdateUser(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+	if revoke {
+		revokeUserSessions(user.ID)
+	}
+
+	var changes []string
+	if user.Username != old.Username {
+		changes = append(changes, fmt.Sprintf("username %q -> %q", old.Username, user.Username))
+	}
+	if user.DisplayName != old.DisplayName {
+		changes = append(changes, fmt.Sprintf("display name %q -> %q", old.DisplayName, user.DisplayName))
+	}
+	if user.Role != old.Role {
+		changes = append(changes, fmt.Sprintf("role %s -> %s", old.Role, user.Role))
+	}
+	if req.Password != nil {
+		changes = append(changes, "password reset")
+	}
+	if len(changes) > 0 {
+		audit(c, database.AuditUserUpdate, user.ID, user.Username, strings.Join(changes, ", "))
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// DeleteUser handles DELETE /api/admin/users/:userId
+func DeleteUser(c *gin.Context) {
+	user, err := database.GetUser(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role == database.RoleAdmin && lastAdmin() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot delete the last admin"})
+		return
+	}
+	workspaces, err := database.GetMemberWorkspaces(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	for _, workspace := range workspaces {
+		if lastOwner(workspace.ID, user.ID) {
+			c.JSON(http.StatusConflict, gin.H{"error": "User is the only owner of workspace " + workspace.Name})
+			return
+		}
+	}
+
+	if err := database.DeleteUser(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		return
+	}
+	revokeUserSessions(user.ID)
+	audit(c, database.AuditUserDelete, user.ID, user.Username, "")
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+// GetCurrentUser handles GET /api/admin/me
+func GetCurrentUser(c *gin.Context) {
+	c.JSON(http.StatusOK, currentUser(c))
+}
+
+// ChangePassword handles PUT /api/admin/me/password
+func ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Current and new password are required"})
+		return
+	}
+
+	user := currentUser(c)
+	if !checkPassword(user.PasswordHash, req.CurrentPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid password"})
+		return
+	}
+	if len(req.NewPassword) < minPasswordLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password must be at least 8 characters"})
+		return
+	}
+
+	var err error
+	if user.PasswordHash, err = hashPassword(req.NewPassword); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	if err := database.UpdateUser(user); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	// Log out other devices, which may be why the password changed
+	if err := database.DeleteUserSessions(user.ID, currentSession(c).ID); err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
+// validateUser checks a username and role, returning an error message or ""
+func validateUser(username, role string) string {
+	if username == "" || strings.ContainsAny(username, " \t\r\n") {
+		return "Username must not be empty or contain spaces"
+	}
+	if !database.ValidRole(role) {
+		return "Role must be admin, editor or viewer"
+	}
+	return ""
+}
+
+// lastAdmin reports whether there is at most one admin left
+func lastAdmin() bool {
+	n, err := database.CountUsers(database.RoleAdmin)
+	return err != nil || n <= 1
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kywk/sheltie/backend/database"
+	ws "github.com/kywk/sheltie/backend/websocket"
+)
+
+// VersionListItem represents a snapshot without its content for listing
+type VersionListItem struct {
+	ID         int64     `json:"id"`
+	Channel    string    `json:"channel"`
+	Version    int64     `json:"version"`
+	AuthorID   string    `json:"authorId"`
+	AuthorName string    `json:"authorName"`
+	Size       int       `json:"size"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// VersionResponse represents a single snapshot including its content
+type VersionResponse struct {
+	ID          int64     `json:"id"`
+	WorkspaceID string    `json:"workspaceId"`
+	Channel     string    `json:"channel"`
+	Version     int64     `json:"version"`
+	AuthorID    string    `json:"authorId"`
+	AuthorName  string    `json:"authorName"`
+	Content     string    `json:"content"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// ListWorkspaceVersions handles GET /api/workspaces/:id/versions?channel=markdown|collie
+func ListWorkspaceVersions(c *gin.Context) {
+	id := c.Param("id")
+	channel := c.Query("channel")
+	if channel != "" && !validChannel(channel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid channel"})
+		return
+	}
+
+	if _, err := database.GetWorkspace(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
+		return
+	}
+
+	versions, err := database.GetWorkspaceVersions(id, channel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list versions"})
+		return
+	}
+
+	items := make([]VersionListItem, len(versions))
+	for i, v := range versions {
+		items[i] = VersionListItem{
+			ID:         v.ID,
+			Channel:    v.Channel,
+			Version:    v.Version,
+			AuthorID:   v.AuthorID,
+			AuthorName: v.AuthorName,
+			Size:       v.Size,
+			CreatedAt:  v.CreatedAt,
+		}
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// GetWorkspaceVersion handles GET /api/workspaces/:id/versions/:versionId
+func GetWorkspaceVersion(c *gin.Context) {
+	v, ok := loadVersion(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, VersionResponse{
+		ID:          v.ID,
+		WorkspaceID: v.WorkspaceID,
+		Channel:     v.Channel,
+		Version:     v.Version,
+		AuthorID:    v.AuthorID,
+		AuthorName:  v.AuthorName,
+		Content:     v.Content,
+		CreatedAt:   v.CreatedAt,
+	})
+}
+
+// RestoreWorkspaceVersion handles POST /api/workspaces/:id/versions/:versionId/restore
+func RestoreWorkspaceVersion(c *gin.Context) {
+	v, ok := loadVersion(c)
+	if !ok {
+		return
+	}
+
+	result, err := wsHub.RestoreContent(v.WorkspaceID, v.Channel, v.Content, requestAuthor(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore version"})
+		return
+	}
+	audit(c, database.AuditVersionRestore, v.WorkspaceID, workspaceName(v.WorkspaceID),
+		fmt.Sprintf("%s snapshot %d (version %d)", v.Channel, v.ID, v.Version))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Workspace restored",
+		"version": result.Version,
+		"hash":    result.Hash,
+	})
+}
+
+// validChannel reports whether channel names a workspace document channel
+func validChannel(channel string) bool {
+	return channel == database.ChannelMarkdown || channel == database.ChannelCollie
+}
+
+// requestAuthor identifies who makes a request from its login or share
+// link: a user by their ID and name, a share link guest by the link
+func requestAuthor(c *gin.Context) ws.Author {
+	if user := currentUser(c); user != nil {
+		return userAuthor(c, user)
+	}
+	if link := currentShareLink(c); link != nil {
+		return ws.Author{ID: "share:" + link.ID, Name: guestName, IP: c.ClientIP()}
+	}
+	return ws.Author{ID: "user_" + c.ClientIP(), Name: "Anonymous", IP: c.ClientIP()}
+}
+
+// userAuthor identifies a user making a request by their ID and name
+func userAuthor(c *gin.Context, user *database.User) ws.Author {
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+	return ws.Author{ID: user.ID, Name: name, IP: c.ClientIP()}
+}
+
+// loadVersion looks up the snapshot addressed by the :id and :versionId params,
+// writing an error response and returning false if it cannot be found
+func loadVersion(c *gin.Context) (*database.WorkspaceVersion, bool) {
+	versionID, err := strconv.ParseInt(c.Param("versionId"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return nil, false
+	}
+
+	v, err := database.GetWorkspaceVersion(c.Param("id"), versionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return nil, false
+	}
+	return v, true
+}
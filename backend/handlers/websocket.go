@@ -1,13 +1,15 @@
 package handlers
 
 import (
-	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
 	ws "github.com/kywk/sheltie/backend/websocket"
 )
 
@@ -27,18 +29,21 @@ func SetHub(hub *ws.Hub) {
 	wsHub = hub
 }
 
-// HandleWebSocket handles WebSocket connections at /ws/:id
+const (
+	// guestName is the name of a share link guest who does not give one
+	guestName = "Guest"
+
+	// maxGuestNameLength limits the name a share link guest may give
+	maxGuestNameLength = 64
+)
+
+// HandleWebSocket handles WebSocket connections at /ws/:id; it runs after
+// RequireWorkspaceAccess, and clients without edit access are read-only.
+// The user comes from the login or share link; a logged in user may only
+// pass their own username or display name as ?username=, and a share link
+// guest may pick a name that is not a user's.
 func HandleWebSocket(c *gin.Context) {
 	workspaceID := c.Param("id")
-	userId := c.Query("userId")
-	username := c.Query("username")
-
-	if userId == "" {
-		userId = "user_" + c.ClientIP()
-	}
-	if username == "" {
-		username = "Anonymous"
-	}
 
 	// Verify workspace exists
 	_, err := database.GetWorkspace(workspaceID)
@@ -47,6 +52,12 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	author, status, msg := connectionAuthor(c, strings.TrimSpace(c.Query("username")))
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
 	// Upgrade connection
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -54,30 +65,93 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
-	// Create client with userId
-	client := ws.NewClient(conn, wsHub, workspaceID, userId, username)
+	client := ws.NewClient(conn, wsHub, workspaceID, author.ID, author.Name)
+	client.ReadOnly = !database.AccessAtLeast(currentAccess(c), database.AccessEdit)
+	client.IP = c.ClientIP()
+	if link := currentShareLink(c); link != nil {
+		client.ShareLinkID = link.ID
+	}
 
 	// Register client
 	wsHub.Register <- client
 
-	// Send current content to new client
-	doc := wsHub.GetVersionManager().GetDocument(workspaceID)
-	content, version, hash := doc.GetState()
+	// Tell the client who it is, then send the current state of every
+	// document channel
+	initialMsgs := []*ws.Message{{
+		Type:         ws.MessageTypeWelcome,
+		ConnectionID: client.ID,
+		UserID:       client.UserID,
+		Username:     client.Username,
+		WorkspaceID:  workspaceID,
+	}}
+	for _, channel := range []string{database.ChannelMarkdown, database.ChannelCollie} {
+		doc := wsHub.GetVersionManager().GetDocument(workspaceID, channel)
+		content, version, hash := doc.GetState()
+		initialMsgs = append(initialMsgs, &ws.Message{
+			Type:        ws.MessageTypeContent,
+			Channel:     channel,
+			Content:     content,
+			WorkspaceID: workspaceID,
+			Version:     version,
+			Hash:        hash,
+		})
+		if channel == database.ChannelMarkdown {
+			initialMsgs = append(initialMsgs, &ws.Message{
+				Type:        ws.MessageTypeLint,
+				Channel:     channel,
+				WorkspaceID: workspaceID,
+				Version:     version,
+				Hash:        hash,
+				Diagnostics: parser.Lint(content),
+			})
+		}
+	}
 
-	initialMsg := &ws.Message{
-		Type:        ws.MessageTypeContent,
-		Content:     content,
-		WorkspaceID: workspaceID,
-		Version:     version,
-		Hash:        hash,
+	if alertScanner != nil {
+		alerts, _ := alertScanner.Alerts(workspaceID)
+		initialMsgs = append(initialMsgs, &ws.Message{
+			Type:        ws.MessageTypeAlerts,
+			WorkspaceID: workspaceID,
+			Alerts:      alerts,
+		})
 	}
 
-	go func() {
-		data, _ := json.Marshal(initialMsg)
-		client.Send <- data
-	}()
+	wsHub.SendTo(client, initialMsgs...)
 
 	// Start pumps
 	go client.WritePump()
 	client.ReadPump()
 }
+
+// connectionAuthor returns who a WebSocket connection acts as, or the status
+// and error to refuse it with when the requested name is not theirs
+func connectionAuthor(c *gin.Context, name string) (ws.Author, int, string) {
+	author := requestAuthor(c)
+	if name == "" || name == author.Name {
+		return author, http.StatusOK, ""
+	}
+
+	if user := currentUser(c); user != nil {
+		if !strings.EqualFold(name, user.Username) && !strings.EqualFold(name, author.Name) {
+			return author, http.StatusForbidden, "Name does not match your account"
+		}
+		return author, http.StatusOK, ""
+	}
+
+	if currentShareLink(c) == nil {
+		return author, http.StatusForbidden, "Name does not match your account"
+	}
+	if utf8.RuneCountInString(name) > maxGuestNameLength {
+		return author, http.StatusBadRequest, "Name is too long"
+	}
+	taken, err := database.UserNameTaken(name)
+	if err != nil {
+		log.Printf("Error checking guest name: %v", err)
+		return author, http.StatusInternalServerError, "Failed to check name"
+	}
+	if taken {
+		return author, http.StatusForbidden, "Name belongs to a registered user"
+	}
+	author.Name = name
+	return author, http.StatusOK, ""
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
@@ -18,6 +19,7 @@ type WorkspaceResponse struct {
 	CollieContent string    `json:"collieContent"`
 	CreatedAt     time.Time `json:"createdAt"`
 	UpdatedAt     time.Time `json:"updatedAt"`
+	Access        string    `json:"access,omitempty"` // The requester's access level
 }
 
 // WorkspaceListItem represents a simplified workspace for listing
@@ -46,6 +48,7 @@ func GetWorkspace(c *gin.Context) {
 		CollieContent: ws.CollieContent,
 		CreatedAt:     ws.CreatedAt,
 		UpdatedAt:     ws.UpdatedAt,
+		Access:        currentAccess(c),
 	})
 }
 
@@ -65,23 +68,28 @@ func UpdateWorkspace(c *gin.Context) {
 		return
 	}
 
-	ws, err := database.GetWorkspace(id)
-	if err != nil {
+	if _, err := database.GetWorkspace(id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Workspace not found"})
 		return
 	}
 
-	// Only update fields that are provided
-	if req.Content != "" {
-		ws.Content = req.Content
+	// Only update fields that are provided, through the hub so live editors see the change
+	updates := []struct{ channel, content string }{
+		{database.ChannelMarkdown, req.Content},
+		{database.ChannelCollie, req.CollieContent},
 	}
-	if req.CollieContent != "" {
-		ws.CollieContent = req.CollieContent
-	}
-
-	if err := database.UpdateWorkspace(ws); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workspace"})
-		return
+	for _, u := range updates {
+		if u.content == "" {
+			continue
+		}
+		current, _, _ := wsHub.GetVersionManager().GetDocument(id, u.channel).GetState()
+		if current == u.content {
+			continue
+		}
+		if _, err := wsHub.ApplyContent(id, u.channel, u.content, requestAuthor(c)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workspace"})
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Workspace updated"})
@@ -114,6 +122,14 @@ func CreateWorkspace(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
 		return
 	}
+	// The creator owns the new workspace; API keys are not members
+	if currentAPIKey(c) == nil {
+		if err := database.SetWorkspaceMember(ws.ID, currentUser(c).ID, database.MemberOwner); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workspace"})
+			return
+		}
+	}
+	audit(c, database.AuditWorkspaceCreate, ws.ID, ws.Name, "")
 
 	c.JSON(http.StatusCreated, WorkspaceResponse{
 		ID:            ws.ID,
@@ -126,9 +142,10 @@ func CreateWorkspace(c *gin.Context) {
 	})
 }
 
-// ListWorkspaces handles GET /api/admin/workspaces
+// ListWorkspaces handles GET /api/admin/workspaces, listing the workspaces
+// the user can see
 func ListWorkspaces(c *gin.Context) {
-	workspaces, err := database.GetAllWorkspaces()
+	workspaces, err := visibleWorkspaces(c)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list workspaces"})
 		return
@@ -169,6 +186,7 @@ func UpdateWorkspaceInfo(c *gin.Context) {
 		return
 	}
 
+	oldName := ws.Name
 	if req.Name != "" {
 		ws.Name = req.Name
 	}
@@ -180,6 +198,9 @@ func UpdateWorkspaceInfo(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workspace"})
 		return
 	}
+	if ws.Name != oldName {
+		audit(c, database.AuditWorkspaceRename, ws.ID, ws.Name, fmt.Sprintf("renamed from %q", oldName))
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Workspace updated"})
 }
@@ -187,11 +208,14 @@ func UpdateWorkspaceInfo(c *gin.Context) {
 // DeleteWorkspace handles DELETE /api/admin/workspaces/:id
 func DeleteWorkspace(c *gin.Context) {
 	id := c.Param("id")
+	name := workspaceName(id)
 
 	if err := database.DeleteWorkspace(id); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workspace"})
 		return
 	}
+	wsHub.CloseWorkspace(id)
+	audit(c, database.AuditWorkspaceDelete, id, name, "")
 
 	c.JSON(http.StatusOK, gin.H{"message": "Workspace deleted"})
 }
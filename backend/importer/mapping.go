@@ -0,0 +1,302 @@
+package importer
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kywk/sheltie/backend/export"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// Mapping names the spreadsheet column of each project field. A field that
+// is not mapped uses the column name of the summary table export, so an
+// exported CSV or XLSX file imports as is.
+type Mapping struct {
+	Name           string `json:"name"`
+	Status         string `json:"status"`
+	CurrentState   string `json:"currentState"`
+	Progress       string `json:"progress"`
+	Contact        string `json:"contact"`
+	Category       string `json:"category"`
+	Lead           string `json:"lead"`
+	Support        string `json:"support"`
+	MeetingDate    string `json:"meetingDate"`
+	MeetingContent string `json:"meetingContent"`
+	// Phases maps a phase name to its columns; without it, every pair of
+	// "<階段> 開始" and "<階段> 結束" columns is a phase
+	Phases map[string]PhaseColumns `json:"phases"`
+}
+
+// PhaseColumns names the columns of a phase's start and end dates
+type PhaseColumns struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// RowError is a problem with a row; Row counts from 1 like a spreadsheet,
+// with the header as row 1
+type RowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("Row %d: %s", e.Row, e.Message)
+}
+
+var (
+	listSplitRe  = regexp.MustCompile(`\s*[,，、;；\n]\s*`)
+	serialDateRe = regexp.MustCompile(`^\d{1,6}(\.\d+)?$`)
+	dateTimeRe   = regexp.MustCompile(`^(\d{4}[-/.]\d{1,2}[-/.]\d{1,2})[ T]`)
+)
+
+// statusAliases are the other ways a spreadsheet may write 燈號
+var statusAliases = map[string]string{
+	"🔴": parser.StatusRed, "紅": parser.StatusRed, "紅燈": parser.StatusRed, "red": parser.StatusRed,
+	"🟡": parser.StatusYellow, "黃": parser.StatusYellow, "黃燈": parser.StatusYellow, "yellow": parser.StatusYellow,
+	"🟢": parser.StatusGreen, "綠": parser.StatusGreen, "綠燈": parser.StatusGreen, "green": parser.StatusGreen,
+}
+
+// excelEpoch is day 0 of Excel's serial dates
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// column is a mapped field and the index of its column, -1 if missing
+type column struct {
+	name  string
+	index int
+}
+
+// Projects turns the records into projects; the first record is the header.
+// Rows without a project name are skipped. Every invalid value is reported,
+// and no projects are returned if there is any.
+func Projects(records [][]string, m Mapping) ([]parser.ProjectInput, []RowError) {
+	if len(records) == 0 {
+		return nil, []RowError{{Row: 1, Message: "missing header row"}}
+	}
+
+	header := map[string]int{}
+	for i, h := range records[0] {
+		h = strings.TrimSpace(h)
+		if _, ok := header[h]; !ok && h != "" {
+			header[h] = i
+		}
+	}
+
+	var errs []RowError
+	resolve := func(mapped, def string) column {
+		if mapped == "" {
+			i, ok := header[def]
+			if !ok {
+				i = -1
+			}
+			return column{def, i}
+		}
+		i, ok := header[mapped]
+		if !ok {
+			errs = append(errs, RowError{Row: 1, Message: fmt.Sprintf("column %q not found", mapped)})
+			i = -1
+		}
+		return column{mapped, i}
+	}
+
+	name := resolve(m.Name, export.ColumnName)
+	status := resolve(m.Status, export.ColumnStatus)
+	currentState := resolve(m.CurrentState, export.ColumnCurrentState)
+	progress := resolve(m.Progress, export.ColumnProgress)
+	contact := resolve(m.Contact, export.ColumnContact)
+	category := resolve(m.Category, export.ColumnCategory)
+	lead := resolve(m.Lead, export.ColumnLead)
+	support := resolve(m.Support, export.ColumnSupport)
+	meetingDate := resolve(m.MeetingDate, export.ColumnMeetingDate)
+	meetingContent := resolve(m.MeetingContent, export.ColumnMeetingContent)
+
+	type phaseColumn struct {
+		name       string
+		start, end column
+	}
+	var phases []phaseColumn
+	if m.Phases != nil {
+		names := make([]string, 0, len(m.Phases))
+		for n := range m.Phases {
+			names = append(names, n)
+		}
+		// keep the spreadsheet's column order
+		pos := func(n string) int {
+			if i, ok := header[m.Phases[n].Start]; ok {
+				return i
+			}
+			return header[m.Phases[n].End]
+		}
+		for i := 1; i < len(names); i++ {
+			for j := i; j > 0 && pos(names[j]) < pos(names[j-1]); j-- {
+				names[j], names[j-1] = names[j-1], names[j]
+			}
+		}
+		for _, n := range names {
+			cols := m.Phases[n]
+			phases = append(phases, phaseColumn{
+				name:  n,
+				start: resolve(cols.Start, ""),
+				end:   resolve(cols.End, ""),
+			})
+		}
+	} else {
+		for i, h := range records[0] {
+			h = strings.TrimSpace(h)
+			n, ok := strings.CutSuffix(h, export.ColumnPhaseStart)
+			if !ok || n == "" || header[h] != i {
+				continue
+			}
+			phases = append(phases, phaseColumn{
+				name:  n,
+				start: column{h, i},
+				end:   resolve("", n+export.ColumnPhaseEnd),
+			})
+		}
+	}
+
+	if name.index < 0 && m.Name == "" {
+		errs = append(errs, RowError{Row: 1, Message: fmt.Sprintf("column %q not found", name.name)})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var inputs []parser.ProjectInput
+	for r, record := range records[1:] {
+		row := r + 2
+		cell := func(c column) string {
+			if c.index < 0 || c.index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[c.index])
+		}
+		fail := func(c column, format string, args ...any) {
+			errs = append(errs, RowError{Row: row, Message: c.name + ": " + fmt.Sprintf(format, args...)})
+		}
+		singleLine := func(c column) string {
+			return strings.Join(strings.Fields(cell(c)), " ")
+		}
+
+		in := parser.ProjectInput{Name: singleLine(name)}
+		if in.Name == "" {
+			continue
+		}
+		in.CurrentState = singleLine(currentState)
+		in.Contact = singleLine(contact)
+		in.Category = singleLine(category)
+		in.Lead = splitList(cell(lead))
+		in.Support = splitList(cell(support))
+
+		if s := cell(status); s != "" {
+			if alias, ok := statusAliases[strings.ToLower(s)]; ok {
+				s = alias
+			}
+			if err := parser.ValidateField(parser.FieldStatus, s); err != nil {
+				fail(status, "must be one of %s, %s, %s", parser.StatusGreen, parser.StatusYellow, parser.StatusRed)
+			}
+			in.Status = s
+		}
+
+		if s := cell(progress); s != "" {
+			if p, ok := parseProgress(s); ok {
+				in.Progress = &p
+			} else {
+				fail(progress, "must be between 0%% and 100%%")
+			}
+		}
+
+		for _, pc := range phases {
+			start, end := cell(pc.start), cell(pc.end)
+			if start == "" && end == "" {
+				continue
+			}
+			phase := parser.Phase{Name: pc.name, StartDate: normalizeDate(start, false), EndDate: normalizeDate(end, true)}
+			if start != "" && phase.StartDate == "" {
+				fail(pc.start, "invalid date %q", start)
+				continue
+			}
+			if end != "" && phase.EndDate == "" {
+				fail(pc.end, "invalid date %q", end)
+				continue
+			}
+			if phase.StartDate == "" {
+				phase.StartDate = phase.EndDate
+			}
+			if phase.EndDate == "" {
+				phase.EndDate = phase.StartDate
+			}
+			if phase.EndDate < phase.StartDate {
+				fail(pc.end, "ends before it starts")
+				continue
+			}
+			in.Phases = append(in.Phases, phase)
+		}
+
+		if s := cell(meetingDate); s != "" {
+			if in.MeetingDate = normalizeDate(s, false); in.MeetingDate == "" {
+				fail(meetingDate, "invalid date %q", s)
+			}
+			for _, l := range strings.Split(cell(meetingContent), "\n") {
+				l = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(l), "-*•"))
+				if l != "" {
+					in.MeetingLines = append(in.MeetingLines, l)
+				}
+			}
+		}
+		inputs = append(inputs, in)
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return inputs, nil
+}
+
+// normalizeDate reads a date as written in a spreadsheet: the formats of
+// parser.NormalizeDate, with or without a time, or an Excel serial number
+func normalizeDate(s string, isEnd bool) string {
+	if m := dateTimeRe.FindStringSubmatch(s); m != nil {
+		s = m[1]
+	}
+	if serialDateRe.MatchString(s) {
+		days, err := strconv.ParseFloat(s, 64)
+		if err != nil || days < 1 {
+			return ""
+		}
+		return excelEpoch.AddDate(0, 0, int(days)).Format("2006-01-02")
+	}
+	return parser.NormalizeDate(s, isEnd)
+}
+
+// parseProgress reads 進度 as "50%", "50" or a fraction like 0.5, the way
+// Excel stores a percentage
+func parseProgress(s string) (int, bool) {
+	value := strings.TrimSpace(strings.TrimSuffix(s, "%"))
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, false
+	}
+	if !strings.HasSuffix(s, "%") && strings.Contains(value, ".") && f <= 1 {
+		f *= 100
+	}
+	p := int(f + 0.5)
+	if f < 0 || p > 100 {
+		return 0, false
+	}
+	return p, true
+}
+
+// splitList splits a list of departments
+func splitList(s string) []string {
+	var items []string
+	for _, item := range listSplitRe.Split(strings.TrimSpace(s), -1) {
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
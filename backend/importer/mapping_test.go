@@ -0,0 +1,176 @@
+package importer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+func progress(p int) *int { return &p }
+
+func TestProjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		records [][]string
+		mapping Mapping
+		want    []parser.ProjectInput
+	}{
+		{
+			name: "summary table export columns",
+			records: [][]string{
+				{"專案名稱", "燈號", "目前狀態", "進度", "窗口", "分類", "主辦", "協辦", "開發 開始", "開發 結束", "PROD 開始", "PROD 結束", "最新會辦日期", "最新會辦內容"},
+				{" 報表平台 ", "🔴", "SIT\n測試中", "50%", "Amy", "數據", "資訊處、營運部", "法務; 稽核", "2025-03-01", "2025-04", "", "2025/6/1", "2025-05-02 10:30", "- 確認範圍\n\n* 下週審查"},
+				{"", "紅", "no name, skipped"},
+				{"新專案", "Green", "", "0.5", "", "", "", "", "45658", "45688.5"},
+			},
+			want: []parser.ProjectInput{
+				{
+					Name: "報表平台", Status: parser.StatusRed, CurrentState: "SIT 測試中", Progress: progress(50),
+					Contact: "Amy", Category: "數據", Lead: []string{"資訊處", "營運部"}, Support: []string{"法務", "稽核"},
+					Phases: []parser.Phase{
+						{Name: "開發", StartDate: "2025-03-01", EndDate: "2025-04-30"},
+						{Name: "PROD", StartDate: "2025-06-01", EndDate: "2025-06-01"},
+					},
+					MeetingDate: "2025-05-02", MeetingLines: []string{"確認範圍", "下週審查"},
+				},
+				{
+					Name: "新專案", Status: parser.StatusGreen, Progress: progress(50),
+					Phases: []parser.Phase{{Name: "開發", StartDate: "2025-01-01", EndDate: "2025-01-31"}},
+				},
+			},
+		},
+		{
+			name: "mapped columns",
+			records: [][]string{
+				{"Project", "Owner", "UAT to", "SIT from", "SIT to", "UAT from", "Done"},
+				{"CRM", "Bob", "2025-08-31", "2025-07-01", "2025-07-31", "2025-08-01", "75"},
+			},
+			mapping: Mapping{
+				Name:     "Project",
+				Contact:  "Owner",
+				Progress: "Done",
+				Phases: map[string]PhaseColumns{
+					"UAT": {Start: "UAT from", End: "UAT to"},
+					"SIT": {Start: "SIT from", End: "SIT to"},
+				},
+			},
+			want: []parser.ProjectInput{{
+				Name: "CRM", Contact: "Bob", Progress: progress(75),
+				// in the order of their start columns, not of the mapping
+				Phases: []parser.Phase{
+					{Name: "SIT", StartDate: "2025-07-01", EndDate: "2025-07-31"},
+					{Name: "UAT", StartDate: "2025-08-01", EndDate: "2025-08-31"},
+				},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := Projects(tt.records, tt.mapping)
+			if errs != nil {
+				t.Fatalf("errors: %v", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Projects =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectsErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		records [][]string
+		mapping Mapping
+		want    []RowError
+	}{
+		{
+			name: "no header",
+			want: []RowError{{Row: 1, Message: "missing header row"}},
+		},
+		{
+			name:    "no name column",
+			records: [][]string{{"名稱", "燈號"}},
+			want:    []RowError{{Row: 1, Message: `column "專案名稱" not found`}},
+		},
+		{
+			name:    "mapped column missing",
+			records: [][]string{{"專案名稱", "Owner"}},
+			mapping: Mapping{Contact: "窗口人"},
+			want:    []RowError{{Row: 1, Message: `column "窗口人" not found`}},
+		},
+		{
+			name: "invalid values",
+			records: [][]string{
+				{"專案名稱", "燈號", "進度", "SIT 開始", "SIT 結束", "最新會辦日期"},
+				{"A", "藍", "120%", "2025-13-01", "", ""},
+				{"B", "綠", "x", "2025-05-01", "2025-04-01", "下週"},
+			},
+			want: []RowError{
+				{Row: 2, Message: "燈號: must be one of 綠, 黃, 紅"},
+				{Row: 2, Message: "進度: must be between 0% and 100%"},
+				{Row: 2, Message: `SIT 開始: invalid date "2025-13-01"`},
+				{Row: 3, Message: "進度: must be between 0% and 100%"},
+				{Row: 3, Message: "SIT 結束: ends before it starts"},
+				{Row: 3, Message: `最新會辦日期: invalid date "下週"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := Projects(tt.records, tt.mapping)
+			if got != nil {
+				t.Errorf("projects returned despite errors: %+v", got)
+			}
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("errors =\n%v\nwant\n%v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+		ok   bool
+	}{
+		{"50%", 50, true},
+		{"50", 50, true},
+		{"0.5", 50, true},
+		{"1", 1, true},
+		{"1.0", 100, true},
+		{"99.6%", 100, true},
+		{"0.5%", 1, true},
+		{"101", 0, false},
+		{"-1", 0, false},
+		{"half", 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseProgress(tt.s); got != tt.want || ok != tt.ok {
+			t.Errorf("parseProgress(%q) = %d, %v, want %d, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestImportedProjectsFormatAndParseBack(t *testing.T) {
+	records := [][]string{
+		{"專案名稱", "燈號", "進度", "主辦", "開發 開始", "開發 結束", "最新會辦日期", "最新會辦內容"},
+		{"報表平台", "黃", "30%", "資訊處", "2025-03-01", "2025-04-30", "2025-05-02", "確認範圍"},
+	}
+	inputs, errs := Projects(records, Mapping{})
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	projects := parser.Parse(parser.AppendProjects("", inputs))
+	if len(projects) != 1 {
+		t.Fatalf("parsed %d projects, want 1", len(projects))
+	}
+	p := projects[0]
+	if p.Name != "報表平台" || p.Status != parser.StatusYellow || p.Progress != 30 ||
+		!reflect.DeepEqual(p.Departments.Lead, []string{"資訊處"}) || len(p.Phases) != 1 ||
+		len(p.Meetings) != 1 || p.Meetings[0].Lines[0].Text != "確認範圍" {
+		t.Errorf("parsed back %+v", p)
+	}
+}
@@ -0,0 +1,223 @@
+// Package importer reads project spreadsheets, as kept in Excel by other
+// departments, into projects for a Sheltie document.
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// ErrUnreadable means the file is not a CSV or XLSX file that can be read
+var ErrUnreadable = errors.New("unreadable spreadsheet")
+
+// Format of an uploaded spreadsheet
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// XLSX limits
+const (
+	maxXLSXPart    = 64 << 20 // decompressed size of a part
+	maxXLSXColumns = 16384    // columns of a sheet, A to XFD
+)
+
+// Read reads the records of a CSV file, or of the first sheet of an XLSX file
+func Read(data []byte, format string) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return readCSV(data)
+	case FormatXLSX:
+		return readXLSX(data)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrUnreadable, format)
+	}
+}
+
+// DetectFormat guesses the format from a file name, falling back to the
+// content: an XLSX file is a zip archive
+func DetectFormat(filename string, data []byte) string {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return FormatCSV
+	case ".xlsx":
+		return FormatXLSX
+	}
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		return FormatXLSX
+	}
+	return FormatCSV
+}
+
+// readCSV reads a UTF-8 CSV file, with or without a byte order mark
+func readCSV(data []byte) ([][]string, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	return records, nil
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		ID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a string item, either plain or made of rich text runs
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	s := t.T
+	for _, r := range t.Runs {
+		s += r.T
+	}
+	return s
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cell text of the first sheet. Numbers are kept as
+// written, so dates come as Excel serial numbers unless stored as text.
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnreadable, err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+	decode := func(name string, v any) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("%w: missing %s", ErrUnreadable, name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrUnreadable, err)
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPart)).Decode(v); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrUnreadable, name, err)
+		}
+		return nil
+	}
+
+	var wb xlsxWorkbook
+	if err := decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, fmt.Errorf("%w: no sheets", ErrUnreadable)
+	}
+	var rels xlsxRelationships
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	sheet := ""
+	for _, r := range rels.Relationships {
+		if r.ID == wb.Sheets[0].ID {
+			sheet = r.Target
+		}
+	}
+	if strings.HasPrefix(sheet, "/") {
+		sheet = strings.TrimPrefix(sheet, "/")
+	} else if sheet != "" {
+		sheet = path.Join("xl", sheet)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decode("xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var ws xlsxWorksheet
+	if err := decode(sheet, &ws); err != nil {
+		return nil, err
+	}
+
+	records := make([][]string, 0, len(ws.Rows))
+	for _, row := range ws.Rows {
+		var record []string
+		for i, c := range row.Cells {
+			col := columnIndex(c.Ref)
+			if col < 0 {
+				col = i
+			}
+			if col >= maxXLSXColumns {
+				continue
+			}
+			text := c.Value
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared.Items) {
+					text = shared.Items[n].String()
+				}
+			case "inlineStr":
+				text = c.Inline.String()
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			record[col] = text
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// columnIndex returns the 0-based column of a cell reference like "AB12", or
+// -1 if there is none
+func columnIndex(ref string) int {
+	col := 0
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+		n++
+	}
+	if n == 0 {
+		return -1
+	}
+	return col - 1
+}
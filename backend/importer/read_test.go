@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// xlsxFile builds an XLSX file from its parts
+func xlsxFile(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// workbookParts returns the parts of a workbook whose first sheet is at target
+func workbookParts(target, sheet string) map[string]string {
+	return map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+			xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+			<sheets><sheet name="專案" sheetId="1" r:id="rId1"/><sheet name="其他" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+			<Relationship Id="rId2" Target="worksheets/sheet2.xml"/>
+			<Relationship Id="rId1" Target="` + target + `"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+			<si><t>專案名稱</t></si><si><r><t>報表</t></r><r><t>平台</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": sheet,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row><c r="A1" t="inlineStr"><is><t>wrong sheet</t></is></c></row></sheetData></worksheet>`,
+	}
+}
+
+const testSheet = `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+	<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>燈號</t></is></c><c r="D1" t="inlineStr"><is><t>進度</t></is></c></row>
+	<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2" t="str"><v>紅</v></c><c r="D2"><v>0.5</v></c></row>
+	<row r="3"><c t="inlineStr"><is><t>no refs</t></is></c><c><v>45658</v></c></row>
+</sheetData></worksheet>`
+
+func TestReadXLSX(t *testing.T) {
+	want := [][]string{
+		{"專案名稱", "燈號", "", "進度"},
+		{"報表平台", "紅", "", "0.5"},
+		{"no refs", "45658"},
+	}
+	for _, target := range []string{"worksheets/sheet1.xml", "/xl/worksheets/sheet1.xml"} {
+		records, err := Read(xlsxFile(t, workbookParts(target, testSheet)), FormatXLSX)
+		if err != nil {
+			t.Fatalf("target %s: %v", target, err)
+		}
+		if !reflect.DeepEqual(records, want) {
+			t.Errorf("target %s: records = %q, want %q", target, records, want)
+		}
+	}
+}
+
+func TestReadXLSXErrors(t *testing.T) {
+	missingSheet := workbookParts("worksheets/sheet1.xml", testSheet)
+	delete(missingSheet, "xl/worksheets/sheet1.xml")
+	badXML := workbookParts("worksheets/sheet1.xml", "<worksheet><sheetData><row>")
+
+	tests := map[string][]byte{
+		"not a zip":     []byte("專案名稱,燈號\n"),
+		"no workbook":   xlsxFile(t, map[string]string{"hello.txt": "hi"}),
+		"missing sheet": xlsxFile(t, missingSheet),
+		"broken xml":    xlsxFile(t, badXML),
+	}
+	for name, data := range tests {
+		if _, err := Read(data, FormatXLSX); !errors.Is(err, ErrUnreadable) {
+			t.Errorf("%s: error = %v, want ErrUnreadable", name, err)
+		}
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	data := []byte("\ufeff專案名稱,燈號,會辦\n報表平台,紅,\"第一行\n第二行\"\n短列\n")
+	want := [][]string{
+		{"專案名稱", "燈號", "會辦"},
+		{"報表平台", "紅", "第一行\n第二行"},
+		{"短列"},
+	}
+	records, err := Read(data, FormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+
+	if _, err := Read(data, "ods"); !errors.Is(err, ErrUnreadable) {
+		t.Errorf("unknown format: error = %v, want ErrUnreadable", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		data     string
+		want     string
+	}{
+		{"projects.CSV", "PK\x03\x04", FormatCSV},
+		{"projects.xlsx", "a,b", FormatXLSX},
+		{"upload", "PK\x03\x04...", FormatXLSX},
+		{"upload", "專案名稱,燈號", FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.filename, []byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %s, want %s", tt.filename, got, tt.want)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB12": 27, "XFD1": 16383, "12": -1, "": -1}
+	for ref, want := range tests {
+		if got := columnIndex(ref); got != want {
+			t.Errorf("columnIndex(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+// Package jobs runs periodic background work
+package jobs
+
+import (
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
+	"github.com/kywk/sheltie/backend/websocket"
+)
+
+// AlertScanner periodically looks for overdue phases and stale projects in
+// every workspace, keeping the results for the API and pushing changes to
+// connected clients
+type AlertScanner struct {
+	Interval  time.Duration
+	StaleDays int
+
+	hub       *websocket.Hub
+	results   map[string][]parser.Alert
+	scannedAt time.Time
+	mu        sync.RWMutex
+}
+
+// NewAlertScanner creates a scanner that pushes alerts through hub
+func NewAlertScanner(hub *websocket.Hub) *AlertScanner {
+	return &AlertScanner{
+		Interval:  time.Hour,
+		StaleDays: 30,
+		hub:       hub,
+		results:   make(map[string][]parser.Alert),
+	}
+}
+
+// Run scans immediately and then every Interval
+func (s *AlertScanner) Run() {
+	s.Scan()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.Scan()
+	}
+}
+
+// Scan checks all workspaces and pushes alerts that changed since the last scan
+func (s *AlertScanner) Scan() {
+	workspaces, err := database.GetAllWorkspaces()
+	if err != nil {
+		log.Printf("Error loading workspaces for alert scan: %v", err)
+		return
+	}
+
+	now := time.Now()
+	results := make(map[string][]parser.Alert, len(workspaces))
+	for _, ws := range workspaces {
+		results[ws.ID] = parser.DetectAlerts(parser.Parse(s.liveContent(ws)), now, s.StaleDays)
+	}
+
+	s.mu.Lock()
+	previous := s.results
+	s.results = results
+	s.scannedAt = now
+	s.mu.Unlock()
+
+	for id, alerts := range results {
+		if !reflect.DeepEqual(previous[id], alerts) {
+			s.hub.PushAlerts(id, alerts)
+		}
+	}
+}
+
+// Rescan recomputes the alerts of one workspace from its markdown, so that
+// their line numbers follow edits made between scans, and pushes them if
+// they changed. The hub calls it when edits pause.
+func (s *AlertScanner) Rescan(workspaceID, content string) {
+	alerts := parser.DetectAlerts(parser.Parse(content), time.Now(), s.StaleDays)
+
+	s.mu.Lock()
+	previous, ok := s.results[workspaceID]
+	changed := !ok || !reflect.DeepEqual(previous, alerts)
+	if changed {
+		// Copy, since All hands the map out to readers
+		results := make(map[string][]parser.Alert, len(s.results)+1)
+		for id, a := range s.results {
+			results[id] = a
+		}
+		results[workspaceID] = alerts
+		s.results = results
+	}
+	s.mu.Unlock()
+
+	if changed {
+		s.hub.PushAlerts(workspaceID, alerts)
+	}
+}
+
+// liveContent returns the markdown of a workspace, including edits the hub
+// has not saved yet
+func (s *AlertScanner) liveContent(ws *database.Workspace) string {
+	if doc, ok := s.hub.GetVersionManager().Loaded(ws.ID, database.ChannelMarkdown); ok {
+		content, _, _ := doc.GetState()
+		return content
+	}
+	return ws.Content
+}
+
+// Alerts returns the alerts of a workspace from the last scan and when it ran
+func (s *AlertScanner) Alerts(workspaceID string) ([]parser.Alert, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	alerts, ok := s.results[workspaceID]
+	if !ok {
+		alerts = []parser.Alert{}
+	}
+	return alerts, s.scannedAt
+}
+
+// All returns the alerts of every workspace from the last scan and when it ran
+func (s *AlertScanner) All() (map[string][]parser.Alert, time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.results, s.scannedAt
+}
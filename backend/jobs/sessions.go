@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+)
+
+// SessionSweeper periodically deletes expired login sessions
+type SessionSweeper struct {
+	Interval time.Duration
+}
+
+// NewSessionSweeper creates a sweeper that runs hourly
+func NewSessionSweeper() *SessionSweeper {
+	return &SessionSweeper{Interval: time.Hour}
+}
+
+// Run sweeps immediately and then every Interval
+func (s *SessionSweeper) Run() {
+	s.Sweep()
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.Sweep()
+	}
+}
+
+// Sweep deletes the sessions that have expired
+func (s *SessionSweeper) Sweep() {
+	n, err := database.DeleteExpiredSessions(time.Now())
+	if err != nil {
+		log.Printf("Error deleting expired sessions: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("Deleted %d expired sessions", n)
+	}
+}
@@ -0,0 +1,152 @@
+// Package ldapauth checks usernames and passwords against an LDAP directory
+// such as Active Directory: it looks the user up, with a service account if
+// one is configured, and binds as them with their password.
+package ldapauth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ErrInvalidCredentials means the directory does not accept the username and password
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// timeout limits connecting to the directory and each request
+const timeout = 10 * time.Second
+
+// Config locates the directory and its users
+type Config struct {
+	URL          string // ldap://host:389 or ldaps://host:636
+	StartTLS     bool   // upgrade an ldap:// connection to TLS
+	BindDN       string // service account to search with; empty searches anonymously
+	BindPassword string
+	BaseDN       string
+	UserFilter   string // %s is replaced by the escaped username
+	NameAttr     string // display name attribute
+	GroupAttr    string // attribute listing the user's groups, like memberOf
+}
+
+// Conn is the part of an LDAP connection that is used
+type Conn interface {
+	Bind(username, password string) error
+	Search(req *ldap.SearchRequest) (*ldap.SearchResult, error)
+	Close() error
+}
+
+// Entry is an authenticated user. Groups holds each group's DN and its
+// common name, so either can be used to map groups to roles.
+type Entry struct {
+	DN          string
+	Username    string
+	DisplayName string
+	Groups      []string
+}
+
+// Client authenticates users against one directory
+type Client struct {
+	config Config
+
+	// Dial opens a connection; it can be replaced to talk to a stub directory
+	Dial func() (Conn, error)
+}
+
+// NewClient creates a directory client
+func NewClient(config Config) *Client {
+	c := &Client{config: config}
+	c.Dial = c.dial
+	return c
+}
+
+// dial connects to the configured URL
+func (c *Client) dial() (Conn, error) {
+	conn, err := ldap.DialURL(c.config.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	if c.config.StartTLS {
+		host := strings.TrimPrefix(c.config.URL, "ldap://")
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// Authenticate checks a username and password, returning the user's entry.
+// It returns ErrInvalidCredentials when the user is unknown, ambiguous or
+// the password is wrong, and other errors when the directory cannot be used.
+func (c *Client) Authenticate(username, password string) (*Entry, error) {
+	// an empty password would be an unauthenticated bind, which succeeds
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := c.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close()
+
+	if c.config.BindDN != "" {
+		if err := conn.Bind(c.config.BindDN, c.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("service bind: %w", err)
+		}
+	}
+
+	req := ldap.NewSearchRequest(
+		c.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(timeout.Seconds()), false,
+		strings.ReplaceAll(c.config.UserFilter, "%s", ldap.EscapeFilter(username)),
+		[]string{c.config.NameAttr, c.config.GroupAttr}, nil,
+	)
+	result, err := conn.Search(req)
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("search: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	found := result.Entries[0]
+
+	if err := conn.Bind(found.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("user bind: %w", err)
+	}
+
+	entry := &Entry{
+		DN:          found.DN,
+		Username:    username,
+		DisplayName: found.GetAttributeValue(c.config.NameAttr),
+	}
+	for _, group := range found.GetAttributeValues(c.config.GroupAttr) {
+		entry.Groups = append(entry.Groups, group)
+		if cn := commonName(group); cn != "" {
+			entry.Groups = append(entry.Groups, cn)
+		}
+	}
+	return entry, nil
+}
+
+// commonName returns the value of the first RDN of a DN, or ""
+func commonName(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 || len(parsed.RDNs[0].Attributes) == 0 {
+		return ""
+	}
+	return parsed.RDNs[0].Attributes[0].Value
+}
@@ -0,0 +1,235 @@
+package ldapauth
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+const (
+	serviceDN       = "cn=svc,ou=services,dc=example,dc=com"
+	servicePassword = "svc-pass"
+	amyDN           = "uid=amy,ou=people,dc=example,dc=com"
+)
+
+// stubDirectory is an in-process directory. Searches are answered by their
+// exact filter, so a test sees the filter the client built.
+type stubDirectory struct {
+	passwords map[string]string        // password by DN
+	entries   map[string][]*ldap.Entry // search results by filter
+	searchErr error
+
+	binds    []string // DNs bound as, in order
+	searches []*ldap.SearchRequest
+	closed   bool
+}
+
+func (d *stubDirectory) Bind(username, password string) error {
+	d.binds = append(d.binds, username)
+	if want, ok := d.passwords[username]; !ok || want != password {
+		return ldap.NewError(ldap.LDAPResultInvalidCredentials, errors.New("invalid credentials"))
+	}
+	return nil
+}
+
+func (d *stubDirectory) Search(req *ldap.SearchRequest) (*ldap.SearchResult, error) {
+	d.searches = append(d.searches, req)
+	if _, err := ldap.CompileFilter(req.Filter); err != nil {
+		return nil, ldap.NewError(ldap.LDAPResultFilterError, err)
+	}
+	if d.searchErr != nil {
+		return nil, d.searchErr
+	}
+	return &ldap.SearchResult{Entries: d.entries[req.Filter]}, nil
+}
+
+func (d *stubDirectory) Close() error {
+	d.closed = true
+	return nil
+}
+
+// newStubClient returns a client of a directory holding amy, who is in two groups
+func newStubClient(config Config) (*Client, *stubDirectory) {
+	dir := &stubDirectory{
+		passwords: map[string]string{serviceDN: servicePassword, amyDN: "amy-pass"},
+		entries: map[string][]*ldap.Entry{
+			"(&(objectClass=person)(uid=amy))": {ldap.NewEntry(amyDN, map[string][]string{
+				"displayName": {"Amy Chen"},
+				"memberOf":    {"CN=PMO,OU=Groups,DC=example,DC=com", "cn=Sheltie Editors,ou=groups,dc=example,dc=com"},
+			})},
+		},
+	}
+	c := NewClient(config)
+	c.Dial = func() (Conn, error) { return dir, nil }
+	return c, dir
+}
+
+func testConfig() Config {
+	return Config{
+		URL:          "ldap://directory.example.com",
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "dc=example,dc=com",
+		UserFilter:   "(&(objectClass=person)(uid=%s))",
+		NameAttr:     "displayName",
+		GroupAttr:    "memberOf",
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	c, dir := newStubClient(testConfig())
+
+	entry, err := c.Authenticate("amy", "amy-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Entry{
+		DN:          amyDN,
+		Username:    "amy",
+		DisplayName: "Amy Chen",
+		Groups: []string{
+			"CN=PMO,OU=Groups,DC=example,DC=com", "PMO",
+			"cn=Sheltie Editors,ou=groups,dc=example,dc=com", "Sheltie Editors",
+		},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("Authenticate = %+v, want %+v", entry, want)
+	}
+
+	// searched as the service account, then bound as the user
+	if !reflect.DeepEqual(dir.binds, []string{serviceDN, amyDN}) {
+		t.Errorf("binds = %v", dir.binds)
+	}
+	req := dir.searches[0]
+	if req.BaseDN != "dc=example,dc=com" || req.Scope != ldap.ScopeWholeSubtree || req.SizeLimit != 2 {
+		t.Errorf("search = %+v", req)
+	}
+	if !reflect.DeepEqual(req.Attributes, []string{"displayName", "memberOf"}) {
+		t.Errorf("search attributes = %v", req.Attributes)
+	}
+	if !dir.closed {
+		t.Error("connection was not closed")
+	}
+}
+
+func TestAuthenticateAnonymousSearch(t *testing.T) {
+	config := testConfig()
+	config.BindDN, config.BindPassword = "", ""
+	c, dir := newStubClient(config)
+
+	if _, err := c.Authenticate("amy", "amy-pass"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dir.binds, []string{amyDN}) {
+		t.Errorf("binds = %v, want only the user", dir.binds)
+	}
+}
+
+func TestAuthenticateEscapesTheUsername(t *testing.T) {
+	tests := []struct {
+		username, filter string
+	}{
+		{"amy", "(&(objectClass=person)(uid=amy))"},
+		{"*", `(&(objectClass=person)(uid=\2a))`},
+		{"amy)(uid=*", `(&(objectClass=person)(uid=amy\29\28uid=\2a))`},
+		{`a\b`, `(&(objectClass=person)(uid=a\5cb))`},
+		{"amy\x00", `(&(objectClass=person)(uid=amy\00))`},
+	}
+	for _, tt := range tests {
+		c, dir := newStubClient(testConfig())
+		_, err := c.Authenticate(tt.username, "amy-pass")
+		if len(dir.searches) != 1 {
+			t.Fatalf("%q: %d searches", tt.username, len(dir.searches))
+		}
+		if got := dir.searches[0].Filter; got != tt.filter {
+			t.Errorf("%q: filter = %s, want %s", tt.username, got, tt.filter)
+		}
+		// only the real username finds amy
+		if tt.username != "amy" && !errors.Is(err, ErrInvalidCredentials) {
+			t.Errorf("%q: Authenticate error = %v, want ErrInvalidCredentials", tt.username, err)
+		}
+	}
+}
+
+func TestAuthenticateFailures(t *testing.T) {
+	ambiguous := func(d *stubDirectory) {
+		d.searchErr = ldap.NewError(ldap.LDAPResultSizeLimitExceeded, errors.New("size limit exceeded"))
+	}
+	tests := []struct {
+		name                    string
+		username                string
+		password                string
+		setup                   func(c *Client, d *stubDirectory)
+		wantInvalid             bool // ErrInvalidCredentials, rather than another error
+		wantBinds, wantSearches int
+	}{
+		{name: "wrong password", username: "amy", password: "nope", wantInvalid: true, wantBinds: 2, wantSearches: 1},
+		{name: "unknown user", username: "bob", password: "amy-pass", wantInvalid: true, wantBinds: 1, wantSearches: 1},
+		{name: "empty password", username: "amy", password: "", wantInvalid: true},
+		{name: "empty username", username: "", password: "amy-pass", wantInvalid: true},
+		{
+			name: "several users match", username: "amy", password: "amy-pass", wantInvalid: true, wantBinds: 1, wantSearches: 1,
+			setup: func(c *Client, d *stubDirectory) { ambiguous(d) },
+		},
+		{
+			name: "two entries returned", username: "amy", password: "amy-pass", wantInvalid: true, wantBinds: 1, wantSearches: 1,
+			setup: func(c *Client, d *stubDirectory) {
+				f := "(&(objectClass=person)(uid=amy))"
+				d.entries[f] = append(d.entries[f], ldap.NewEntry("uid=amy,ou=other,dc=example,dc=com", nil))
+			},
+		},
+		{
+			name: "service account rejected", username: "amy", password: "amy-pass", wantBinds: 1,
+			setup: func(c *Client, d *stubDirectory) { d.passwords[serviceDN] = "rotated" },
+		},
+		{
+			name: "search fails", username: "amy", password: "amy-pass", wantBinds: 1, wantSearches: 1,
+			setup: func(c *Client, d *stubDirectory) {
+				d.searchErr = ldap.NewError(ldap.LDAPResultBusy, errors.New("busy"))
+			},
+		},
+		{
+			name: "directory unreachable", username: "amy", password: "amy-pass",
+			setup: func(c *Client, d *stubDirectory) {
+				c.Dial = func() (Conn, error) { return nil, errors.New("connection refused") }
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, dir := newStubClient(testConfig())
+			if tt.setup != nil {
+				tt.setup(c, dir)
+			}
+			entry, err := c.Authenticate(tt.username, tt.password)
+			if err == nil {
+				t.Fatalf("Authenticate = %+v, want an error", entry)
+			}
+			if errors.Is(err, ErrInvalidCredentials) != tt.wantInvalid {
+				t.Errorf("Authenticate error = %v, want invalid credentials %v", err, tt.wantInvalid)
+			}
+			if len(dir.binds) != tt.wantBinds || len(dir.searches) != tt.wantSearches {
+				t.Errorf("%d binds and %d searches, want %d and %d", len(dir.binds), len(dir.searches), tt.wantBinds, tt.wantSearches)
+			}
+		})
+	}
+}
+
+func TestCommonName(t *testing.T) {
+	tests := []struct {
+		dn, want string
+	}{
+		{"CN=PMO,OU=Groups,DC=example,DC=com", "PMO"},
+		{`cn=R\2cD Team,ou=groups,dc=example,dc=com`, "R,D Team"},
+		{"Sheltie Admins", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := commonName(tt.dn); got != tt.want {
+			t.Errorf("commonName(%q) = %q, want %q", tt.dn, got, tt.want)
+		}
+	}
+}
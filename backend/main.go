@@ -10,6 +10,7 @@ import (
 	"github.com/kywk/sheltie/backend/config"
 	"github.com/kywk/sheltie/backend/database"
 	"github.com/kywk/sheltie/backend/handlers"
+	"github.com/kywk/sheltie/backend/jobs"
 	"github.com/kywk/sheltie/backend/websocket"
 )
 
@@ -17,27 +18,46 @@ func main() {
 	// Load configuration
 	cfg := config.Load()
 	handlers.SetConfig(cfg)
+	handlers.SetupOIDC()
+	handlers.SetupLDAP()
 
 	// Initialize database
 	if err := database.Init(cfg.DBPath); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.Close()
+	if err := handlers.BootstrapAdmin(); err != nil {
+		log.Fatalf("Failed to create admin user: %v", err)
+	}
 
 	// Create WebSocket hub
 	hub := websocket.NewHub()
 	hub.AutoSaveInterval = time.Duration(cfg.AutoSaveInterval) * time.Second
+	hub.GetVersionManager().History.Interval = time.Duration(cfg.SnapshotInterval) * time.Second
 	handlers.SetHub(hub)
+
+	// Start overdue/stale project detection, refreshed as edits pause
+	scanner := jobs.NewAlertScanner(hub)
+	scanner.Interval = time.Duration(cfg.AlertInterval) * time.Second
+	scanner.StaleDays = cfg.StaleDays
+	hub.EditsPaused = scanner.Rescan
+	handlers.SetAlertScanner(scanner)
+
 	go hub.Run()
+	go scanner.Run()
 
-	// Setup Gin router
-	r := gin.Default()
+	// Delete expired login sessions
+	go jobs.NewSessionSweeper().Run()
+
+	// Setup Gin router; the request log leaves out tokens sent in the query
+	r := gin.New()
+	r.Use(handlers.RequestLogger(), gin.Recovery())
 
 	// CORS configuration
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "X-Share-Token"},
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
 	}))
@@ -55,9 +75,53 @@ func main() {
 	// API routes
 	api := r.Group("/api")
 	{
-		// Public workspace access
-		api.GET("/workspaces/:id", handlers.GetWorkspace)
-		api.PUT("/workspaces/:id", handlers.UpdateWorkspace)
+		// Workspace access is checked per workspace, by membership or share link
+		workspace := api.Group("/workspaces/:id")
+		{
+			view := workspace.Group("", handlers.RequireWorkspaceAccess(database.AccessView))
+			view.GET("", handlers.GetWorkspace)
+			view.GET("/versions", handlers.ListWorkspaceVersions)
+			view.GET("/versions/:versionId", handlers.GetWorkspaceVersion)
+			view.GET("/diff", handlers.DiffWorkspaceVersions)
+			view.GET("/blame", handlers.GetWorkspaceBlame)
+			view.GET("/projects", handlers.ListWorkspaceProjects)
+			view.GET("/projects/:slug", handlers.GetWorkspaceProject)
+			view.POST("/lint", handlers.LintWorkspace)
+			view.GET("/alerts", handlers.GetWorkspaceAlerts)
+			view.GET("/export.pptx", handlers.ExportPPTX)
+			view.GET("/export.pdf", handlers.ExportPDF)
+			view.GET("/export.html", handlers.ExportHTML)
+			view.GET("/export.csv", handlers.ExportCSV)
+			view.GET("/export.xlsx", handlers.ExportXLSX)
+			view.GET("/calendar.ics", handlers.GetWorkspaceCalendar)
+
+			comment := workspace.Group("", handlers.RequireWorkspaceAccess(database.AccessComment))
+			comment.POST("/action-items/:itemId/resolve", handlers.ResolveActionItem)
+
+			edit := workspace.Group("", handlers.RequireWorkspaceAccess(database.AccessEdit))
+			edit.PUT("", handlers.UpdateWorkspace)
+			edit.POST("/versions/:versionId/restore", handlers.RestoreWorkspaceVersion)
+			edit.PATCH("/projects/:slug", handlers.UpdateWorkspaceProject)
+			edit.POST("/import", handlers.ImportWorkspaceProjects)
+
+			owner := workspace.Group("", handlers.RequireWorkspaceAccess(database.AccessOwner))
+			owner.GET("/members", handlers.ListWorkspaceMembers)
+			owner.PUT("/members", handlers.SetWorkspaceMember)
+			owner.DELETE("/members/:userId", handlers.RemoveWorkspaceMember)
+			owner.GET("/shares", handlers.ListShareLinks)
+			owner.POST("/shares", handlers.CreateShareLink)
+			owner.DELETE("/shares/:shareId", handlers.RevokeShareLink)
+		}
+		api.GET("/action-items", handlers.ListActionItems)
+		api.GET("/calendar.ics", handlers.GetCalendar)
+
+		// Login methods other than a password
+		auth := api.Group("/auth")
+		{
+			auth.GET("/providers", handlers.GetAuthProviders)
+			auth.GET("/oidc/login", handlers.OIDCLogin)
+			auth.GET("/oidc/callback", handlers.OIDCCallback)
+		}
 
 		// Admin routes
 		admin := api.Group("/admin")
@@ -69,16 +133,46 @@ func main() {
 			protected := admin.Group("")
 			protected.Use(handlers.AdminAuthMiddleware())
 			{
+				protected.GET("/me", handlers.GetCurrentUser)
 				protected.GET("/workspaces", handlers.ListWorkspaces)
-				protected.POST("/workspaces", handlers.CreateWorkspace)
-				protected.PUT("/workspaces/:id", handlers.UpdateWorkspaceInfo)
-				protected.DELETE("/workspaces/:id", handlers.DeleteWorkspace)
+				protected.GET("/portfolio", handlers.GetPortfolio)
+				protected.GET("/alerts", handlers.ListAlerts)
+				protected.GET("/export.csv", handlers.ExportAllCSV)
+				protected.GET("/export.xlsx", handlers.ExportAllXLSX)
+
+				// Logged in users manage their password and sessions
+				session := protected.Group("", handlers.RequireSession())
+				session.PUT("/me/password", handlers.ChangePassword)
+				session.GET("/sessions", handlers.ListSessions)
+				session.DELETE("/sessions/:sessionId", handlers.RevokeSession)
+
+				// Editors create workspaces
+				editor := protected.Group("", handlers.RequireRole(database.RoleEditor))
+				editor.POST("/workspaces", handlers.CreateWorkspace)
+
+				// Workspace owners rename their workspaces
+				protected.PUT("/workspaces/:id", handlers.RequireWorkspaceAccess(database.AccessOwner), handlers.UpdateWorkspaceInfo)
+
+				// Admins delete workspaces and manage users
+				adminOnly := protected.Group("", handlers.RequireRole(database.RoleAdmin))
+				adminOnly.DELETE("/workspaces/:id", handlers.DeleteWorkspace)
+				adminOnly.GET("/users", handlers.ListUsers)
+				adminOnly.POST("/users", handlers.CreateUser)
+				adminOnly.PUT("/users/:userId", handlers.UpdateUser)
+				adminOnly.DELETE("/users/:userId", handlers.DeleteUser)
+				adminOnly.GET("/audit", handlers.ListAuditEvents)
+
+				// API keys are managed by logged in admins, not by other keys
+				keys := adminOnly.Group("", handlers.RequireSession())
+				keys.GET("/api-keys", handlers.ListAPIKeys)
+				keys.POST("/api-keys", handlers.CreateAPIKey)
+				keys.DELETE("/api-keys/:keyId", handlers.RevokeAPIKey)
 			}
 		}
 	}
 
 	// WebSocket endpoint
-	r.GET("/ws/:id", handlers.HandleWebSocket)
+	r.GET("/ws/:id", handlers.RequireWorkspaceAccess(database.AccessView), handlers.HandleWebSocket)
 
 	// Start server
 	addr := ":" + cfg.Port
@@ -0,0 +1,452 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	testClientID     = "sheltie"
+	testClientSecret = "s3cret"
+	testRedirectURL  = "http://localhost/api/auth/oidc/callback"
+)
+
+// fakeProvider is an OpenID Connect provider serving discovery, keys and a
+// token endpoint that checks the code, client and PKCE verifier
+type fakeProvider struct {
+	*httptest.Server
+	t *testing.T
+
+	mu         sync.Mutex
+	keys       []map[string]string      // published JWKs
+	challenges map[string]string        // code challenge by authorization code
+	idToken    func(code string) string // ID token returned for a code
+	jwksHits   int
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	f := &fakeProvider{t: t, challenges: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 f.URL,
+			"authorization_endpoint": f.URL + "/authorize?prompt=login",
+			"token_endpoint":         f.URL + "/token",
+			"jwks_uri":               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.jwksHits++
+		json.NewEncoder(w).Encode(map[string]any{"keys": f.keys})
+	})
+	mux.HandleFunc("/token", f.token)
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// token implements the authorization code grant
+func (f *fakeProvider) token(w http.ResponseWriter, r *http.Request) {
+	fail := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": "rejected by test provider"})
+	}
+	id, secret, _ := r.BasicAuth()
+	if id != testClientID || secret != testClientSecret {
+		fail("invalid_client")
+		return
+	}
+	if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("redirect_uri") != testRedirectURL {
+		fail("invalid_request")
+		return
+	}
+
+	code := r.PostFormValue("code")
+	f.mu.Lock()
+	challenge, ok := f.challenges[code]
+	f.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != challenge {
+		fail("invalid_grant")
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"access_token": "at", "token_type": "Bearer", "id_token": f.idToken(code)})
+}
+
+// authorize plays the user logging in: it checks the login URL and issues a code for its PKCE challenge
+func (f *fakeProvider) authorize(p *Provider, verifier, nonce string) string {
+	f.t.Helper()
+	login, err := p.AuthCodeURL(context.Background(), "state-1", nonce, verifier)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	u, err := url.Parse(login)
+	if err != nil {
+		f.t.Fatal(err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("nonce") != nonce || q.Get("client_id") != testClientID {
+		f.t.Fatalf("login URL %s", login)
+	}
+	code := "code-" + verifier
+	f.mu.Lock()
+	f.challenges[code] = q.Get("code_challenge")
+	f.mu.Unlock()
+	return code
+}
+
+// keyFetches returns how many times the key set was fetched
+func (f *fakeProvider) keyFetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwksHits
+}
+
+// publish adds a key to the provider's key set
+func (f *fakeProvider) publish(kid string, key crypto.PublicKey) {
+	b64 := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	var jwk map[string]string
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		jwk = map[string]string{"kty": "RSA", "use": "sig", "n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		jwk = map[string]string{"kty": "EC", "crv": k.Curve.Params().Name, "x": b64(k.X.Bytes()), "y": b64(k.Y.Bytes())}
+	}
+	jwk["kid"] = kid
+	f.mu.Lock()
+	f.keys = append(f.keys, jwk)
+	f.mu.Unlock()
+}
+
+// sign returns a JWS of the claims signed with an RSA (RS256) or EC (ES256) key
+func sign(t *testing.T, key crypto.Signer, kid string, claims map[string]any) string {
+	t.Helper()
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+var (
+	keysOnce         sync.Once
+	rsaKey, rsaOther *rsa.PrivateKey
+	ecKey            *ecdsa.PrivateKey
+)
+
+// testKeys generates the signing keys once for all tests
+func testKeys(t *testing.T) {
+	keysOnce.Do(func() {
+		var err error
+		if rsaKey, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+		if rsaOther, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+			t.Fatal(err)
+		}
+		if ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func newTestProvider(f *fakeProvider) *Provider {
+	return NewProvider(Config{
+		Issuer:       f.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+		Scopes:       []string{"openid", "email"},
+	})
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	f := newFakeProvider(t)
+	p := newTestProvider(f)
+
+	login, err := p.AuthCodeURL(context.Background(), "the-state", "the-nonce", "the-verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(login, f.URL+"/authorize?prompt=login&") {
+		t.Errorf("login URL %s does not extend the authorization endpoint's query", login)
+	}
+	u, _ := url.Parse(login)
+	q := u.Query()
+	sum := sha256.Sum256([]byte("the-verifier"))
+	want := map[string]string{
+		"response_type":         "code",
+		"client_id":             testClientID,
+		"redirect_uri":          testRedirectURL,
+		"scope":                 "openid email",
+		"state":                 "the-state",
+		"nonce":                 "the-nonce",
+		"code_challenge":        base64.RawURLEncoding.EncodeToString(sum[:]),
+		"code_challenge_method": "S256",
+	}
+	for k, v := range want {
+		if got := q.Get(k); got != v {
+			t.Errorf("%s = %q, want %q", k, got, v)
+		}
+	}
+	if q.Has("code_verifier") {
+		t.Error("login URL leaks the code verifier")
+	}
+}
+
+func TestExchange(t *testing.T) {
+	testKeys(t)
+	now := time.Now()
+
+	// claims returns valid ID token claims for the provider, changed by edit
+	claims := func(f *fakeProvider, edit func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":   f.URL,
+			"sub":   "user-1",
+			"aud":   testClientID,
+			"exp":   now.Add(time.Hour).Unix(),
+			"iat":   now.Unix(),
+			"nonce": "n-1",
+			"email": "amy@example.com",
+		}
+		if edit != nil {
+			edit(c)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name  string
+		token func(f *fakeProvider) string
+		// verifier and nonce sent to Exchange; the login used "v-1" and "n-1"
+		verifier, nonce string
+		wantErr         string // substring of the error, "" for success
+	}{
+		{
+			name:  "RS256",
+			token: func(f *fakeProvider) string { return sign(t, rsaKey, "rsa-1", claims(f, nil)) },
+		},
+		{
+			name:  "ES256",
+			token: func(f *fakeProvider) string { return sign(t, ecKey, "ec-1", claims(f, nil)) },
+		},
+		{
+			name: "several audiences with azp",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) {
+					c["aud"] = []string{"other", testClientID}
+					c["azp"] = testClientID
+				}))
+			},
+		},
+		{
+			name: "expired within the clock skew",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { c["exp"] = now.Add(-30 * time.Second).Unix() }))
+			},
+		},
+		{
+			name:    "bad signature",
+			token:   func(f *fakeProvider) string { return sign(t, rsaOther, "rsa-1", claims(f, nil)) },
+			wantErr: "bad signature",
+		},
+		{
+			name: "tampered claims",
+			token: func(f *fakeProvider) string {
+				parts := strings.Split(sign(t, rsaKey, "rsa-1", claims(f, nil)), ".")
+				payload, _ := json.Marshal(claims(f, func(c map[string]any) { c["sub"] = "admin" }))
+				return parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+			},
+			wantErr: "bad signature",
+		},
+		{
+			name: "unsigned",
+			token: func(f *fakeProvider) string {
+				header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+				payload, _ := json.Marshal(claims(f, nil))
+				return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+			},
+			wantErr: "unsupported algorithm",
+		},
+		{
+			name:    "unknown key",
+			token:   func(f *fakeProvider) string { return sign(t, rsaKey, "rsa-9", claims(f, nil)) },
+			wantErr: "unknown key",
+		},
+		{
+			name: "wrong audience",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { c["aud"] = "another-client" }))
+			},
+			wantErr: "wrong audience",
+		},
+		{
+			name: "several audiences without azp",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { c["aud"] = []string{testClientID, "other"} }))
+			},
+			wantErr: "wrong audience",
+		},
+		{
+			name: "wrong issuer",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { c["iss"] = "https://evil.example" }))
+			},
+			wantErr: "wrong issuer",
+		},
+		{
+			name: "expired",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { c["exp"] = now.Add(-2 * time.Minute).Unix() }))
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "no expiry",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { delete(c, "exp") }))
+			},
+			wantErr: "expired",
+		},
+		{
+			name: "no subject",
+			token: func(f *fakeProvider) string {
+				return sign(t, rsaKey, "rsa-1", claims(f, func(c map[string]any) { delete(c, "sub") }))
+			},
+			wantErr: "missing subject",
+		},
+		{
+			name:    "nonce mismatch",
+			token:   func(f *fakeProvider) string { return sign(t, rsaKey, "rsa-1", claims(f, nil)) },
+			nonce:   "n-2",
+			wantErr: "nonce mismatch",
+		},
+		{
+			name:     "wrong PKCE verifier",
+			token:    func(f *fakeProvider) string { return sign(t, rsaKey, "rsa-1", claims(f, nil)) },
+			verifier: "v-2",
+			wantErr:  "invalid_grant",
+		},
+		{
+			name:    "malformed",
+			token:   func(f *fakeProvider) string { return "not-a-jwt" },
+			wantErr: "malformed",
+		},
+		{
+			name:    "no ID token",
+			token:   func(f *fakeProvider) string { return "" },
+			wantErr: "missing from token response",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeProvider(t)
+			f.publish("rsa-1", &rsaKey.PublicKey)
+			f.publish("ec-1", &ecKey.PublicKey)
+			f.idToken = func(string) string { return tt.token(f) }
+			p := newTestProvider(f)
+
+			code := f.authorize(p, "v-1", "n-1")
+			verifier, nonce := "v-1", "n-1"
+			if tt.verifier != "" {
+				verifier = tt.verifier
+			}
+			if tt.nonce != "" {
+				nonce = tt.nonce
+			}
+
+			got, err := p.Exchange(context.Background(), code, verifier, nonce)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Exchange: %v", err)
+				}
+				if got.String("sub") != "user-1" || got.String("email") != "amy@example.com" {
+					t.Errorf("claims = %v", got)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Exchange error = %v, want %q", err, tt.wantErr)
+			}
+			if tt.verifier == "" && !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("Exchange error = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	testKeys(t)
+	f := newFakeProvider(t)
+	f.publish("rsa-1", &rsaKey.PublicKey)
+	p := newTestProvider(f)
+
+	claims := map[string]any{"iss": f.URL, "sub": "user-1", "aud": testClientID, "exp": time.Now().Add(time.Hour).Unix()}
+	if _, err := p.verify(context.Background(), sign(t, rsaKey, "rsa-1", claims), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	// a second token with a known key does not refetch the set
+	if _, err := p.verify(context.Background(), sign(t, rsaKey, "rsa-1", claims), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if n := f.keyFetches(); n != 1 {
+		t.Errorf("fetched keys %d times, want 1", n)
+	}
+
+	// the provider rotates to a new key
+	f.publish("ec-2", &ecKey.PublicKey)
+	if _, err := p.verify(context.Background(), sign(t, ecKey, "ec-2", claims), time.Now()); err != nil {
+		t.Fatalf("token signed with the new key: %v", err)
+	}
+	if n := f.keyFetches(); n != 2 {
+		t.Errorf("fetched keys %d times, want 2", n)
+	}
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	f := newFakeProvider(t)
+	p := NewProvider(Config{Issuer: f.URL + "/", ClientID: testClientID})
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("AuthCodeURL error = %v, want issuer mismatch", err)
+	}
+}
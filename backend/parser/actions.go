@@ -0,0 +1,101 @@
+package parser
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ownerSplitRe separates the names a 窗口 lists, such as "Amy、Bob" or "Amy / Bob"
+var ownerSplitRe = regexp.MustCompile(`\s*[,，、/／;；&＆]\s*`)
+
+// ActionItem is a _待追蹤_ line from 會辦狀況
+//
+// Items are not stored; they are read from the document on every request.
+// The ID is derived from the project name, meeting date and text, so it
+// survives resolving the item and edits elsewhere in the document, but it
+// changes when any of those three is edited, and identical items under the
+// same meeting share one ID.
+type ActionItem struct {
+	ID       string `json:"id"`
+	Project  string `json:"project"`
+	Slug     string `json:"slug"`
+	Owner    string `json:"owner"` // the project's 窗口
+	Date     string `json:"date"`  // meeting date the item was raised
+	Age      int    `json:"age"`   // days since Date
+	Text     string `json:"text"`  // without the marker
+	Resolved bool   `json:"resolved"`
+	Line     int    `json:"line"`
+}
+
+// ActionItems extracts the open and resolved action items of a document
+func ActionItems(projects []Project, now time.Time) []ActionItem {
+	items := []ActionItem{}
+	today := now.Format("2006-01-02")
+
+	for _, p := range projects {
+		for _, m := range p.Meetings {
+			for _, l := range m.Lines {
+				resolved := strings.HasPrefix(l.Text, MarkerResolved)
+				if !l.IsTracking && !resolved {
+					continue
+				}
+				text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(l.Text, MarkerTracking), MarkerResolved))
+				items = append(items, ActionItem{
+					ID:       actionItemID(p.Name, m.Date, text),
+					Project:  p.Name,
+					Slug:     p.Slug,
+					Owner:    p.Contact,
+					Date:     m.Date,
+					Age:      daysBetween(m.Date, today),
+					Text:     text,
+					Resolved: resolved,
+					Line:     l.Line,
+				})
+			}
+		}
+	}
+	return items
+}
+
+// OwnedBy reports whether name is one of the names the item's 窗口 lists,
+// ignoring case
+func (item ActionItem) OwnedBy(name string) bool {
+	name = strings.TrimSpace(name)
+	for _, owner := range ownerSplitRe.Split(strings.TrimSpace(item.Owner), -1) {
+		if owner != "" && strings.EqualFold(owner, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveActionItem returns content with the action item's _待追蹤_ marker
+// replaced by _已完成_, leaving the rest of the document untouched
+func ResolveActionItem(content, id string) (string, error) {
+	found := false
+	for _, item := range ActionItems(Parse(content), time.Now()) {
+		if item.ID != id {
+			continue
+		}
+		found = true
+		if item.Resolved {
+			continue
+		}
+		lines := strings.Split(content, "\n")
+		lines[item.Line] = strings.Replace(lines[item.Line], MarkerTracking, MarkerResolved, 1)
+		return strings.Join(lines, "\n"), nil
+	}
+	if found {
+		return content, nil // already resolved
+	}
+	return "", ErrActionItemNotFound
+}
+
+// actionItemID derives an ID from the item's project, date and text
+func actionItemID(project, date, text string) string {
+	sum := sha1.Sum([]byte(project + "\x00" + date + "\x00" + text))
+	return hex.EncodeToString(sum[:6])
+}
@@ -0,0 +1,29 @@
+package parser
+
+import "testing"
+
+func TestActionItemOwnedBy(t *testing.T) {
+	tests := []struct {
+		owner string
+		name  string
+		want  bool
+	}{
+		{"Amy", "amy", true},
+		{"Amy、Bob", "Bob", true},
+		{"Amy, Bob", "bob", true},
+		{"Amy，Bob", "Amy", true},
+		{"Amy / Bob", "Bob", true},
+		{"Amy & Bob", "Bob", true},
+		{"Amy Chen", "Amy Chen", true},
+		{"Amy Chen", "Amy", false},
+		{"Amy、Bob", "Amy、Bob", false},
+		{"Amyx", "Amy", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		item := ActionItem{Owner: tt.owner}
+		if got := item.OwnedBy(tt.name); got != tt.want {
+			t.Errorf("ActionItem{Owner: %q}.OwnedBy(%q) = %v, want %v", tt.owner, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Alert kinds
+const (
+	AlertOverduePhase = "overdue-phase"
+	AlertStaleProject = "stale-project"
+)
+
+// Alert flags a project that needs attention
+type Alert struct {
+	Kind    string `json:"kind"`
+	Project string `json:"project"`
+	Slug    string `json:"slug"`
+	Line    int    `json:"line"`
+	Phase   string `json:"phase,omitempty"`
+	Date    string `json:"date,omitempty"` // phase end date, or the last meeting date
+	Days    int    `json:"days"`           // days overdue, or days since the last meeting
+	Message string `json:"message"`
+}
+
+// DetectAlerts flags phases whose end date has passed while 目前狀態 still
+// names that phase, and projects without a 會辦 entry in the last staleDays
+// days. The go-live phase is not flagged, since a project stays in it once live.
+func DetectAlerts(projects []Project, now time.Time, staleDays int) []Alert {
+	alerts := []Alert{}
+	today := now.Format("2006-01-02")
+
+	for _, p := range projects {
+		if phase, ok := currentPhase(p); ok && phase.EndDate < today && !IsGoLivePhase(phase.Name) {
+			days := daysBetween(phase.EndDate, today)
+			alerts = append(alerts, Alert{
+				Kind:    AlertOverduePhase,
+				Project: p.Name,
+				Slug:    p.Slug,
+				Line:    phase.Line,
+				Phase:   phase.Name,
+				Date:    phase.EndDate,
+				Days:    days,
+				Message: fmt.Sprintf("%s ended on %s (%d days ago) but 目前狀態 is still %s", phase.Name, phase.EndDate, days, p.CurrentState),
+			})
+		}
+
+		if staleDays <= 0 {
+			continue
+		}
+		latest, ok := LatestMeeting(p)
+		last, line := latest.Date, latest.Line
+		switch {
+		case !ok:
+			alerts = append(alerts, Alert{
+				Kind:    AlertStaleProject,
+				Project: p.Name,
+				Slug:    p.Slug,
+				Line:    p.NameLine,
+				Message: "No 會辦 entries recorded",
+			})
+		case daysBetween(last, today) > staleDays:
+			days := daysBetween(last, today)
+			alerts = append(alerts, Alert{
+				Kind:    AlertStaleProject,
+				Project: p.Name,
+				Slug:    p.Slug,
+				Line:    line,
+				Date:    last,
+				Days:    days,
+				Message: fmt.Sprintf("Last 會辦 entry was on %s (%d days ago)", last, days),
+			})
+		}
+	}
+	return alerts
+}
+
+// IsGoLivePhase reports whether a phase name means going into production (PROD, 上線 or 正式)
+func IsGoLivePhase(name string) bool {
+	return strings.Contains(strings.ToUpper(name), "PROD") || strings.Contains(name, "上線") || strings.Contains(name, "正式")
+}
+
+// phaseStateSuffixes may follow a phase name in 目前狀態, as in 開發中 or SIT階段
+var phaseStateSuffixes = []string{"", "中", "進行中", "階段"}
+
+// currentPhase returns the phase named by the project's 目前狀態: the phase
+// name itself, ignoring case, or the name with one of phaseStateSuffixes
+func currentPhase(p Project) (Phase, bool) {
+	state := strings.ToUpper(strings.TrimSpace(p.CurrentState))
+	if state == "" {
+		return Phase{}, false
+	}
+	for _, phase := range p.Phases {
+		name := strings.ToUpper(strings.TrimSpace(phase.Name))
+		if name == "" {
+			continue
+		}
+		for _, suffix := range phaseStateSuffixes {
+			if state == name+suffix {
+				return phase, true
+			}
+		}
+	}
+	return Phase{}, false
+}
+
+// LatestMeeting returns the 會辦 entry with the latest valid date, and false
+// if the project has none
+func LatestMeeting(p Project) (MeetingEntry, bool) {
+	latest, found := MeetingEntry{Line: -1}, false
+	for _, m := range p.Meetings {
+		if m.Date > latest.Date && validDate(m.Date) {
+			latest, found = m, true
+		}
+	}
+	return latest, found
+}
+
+// daysBetween returns the whole days from one YYYY-MM-DD date to another
+func daysBetween(from, to string) int {
+	a, errA := time.Parse("2006-01-02", from)
+	b, errB := time.Parse("2006-01-02", to)
+	if errA != nil || errB != nil {
+		return 0
+	}
+	return int(b.Sub(a).Hours() / 24)
+}
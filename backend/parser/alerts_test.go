@@ -0,0 +1,88 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCurrentPhase(t *testing.T) {
+	phases := []Phase{
+		{Name: "開發", EndDate: "2025-03-31", Line: 5},
+		{Name: "SIT", EndDate: "2025-04-30", Line: 6},
+		{Name: "QAS", EndDate: "2025-05-31", Line: 7},
+		{Name: "Q", EndDate: "2025-06-30", Line: 8},
+	}
+	tests := []struct {
+		state string
+		want  string // phase name, "" for none
+	}{
+		{"SIT", "SIT"},
+		{" sit ", "SIT"},
+		{"開發中", "開發"},
+		{"SIT階段", "SIT"},
+		{"QAS進行中", "QAS"},
+		{"Q", "Q"},
+		{"QAS前準備", ""},
+		{"SIT/QAS", ""},
+		{"提案準備中", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		phase, ok := currentPhase(Project{CurrentState: tt.state, Phases: phases})
+		if ok != (tt.want != "") || phase.Name != tt.want {
+			t.Errorf("currentPhase(%q) = %q, %v, want %q", tt.state, phase.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestDetectAlerts(t *testing.T) {
+	now := time.Date(2025, 5, 10, 9, 0, 0, 0, time.UTC)
+	doc := `# 報表平台
+## 基本資訊
+- 目前狀態: SIT中
+- 時程:
+  - 開發: 2025-03-01 ~ 2025-03-31
+  - SIT: 2025-04-01 ~ 2025-04-30
+## 會辦狀況
+- 2025-05-01
+  - 追蹤測試
+---
+# 上線專案
+## 基本資訊
+- 目前狀態: PROD
+- 時程:
+  - PROD: 2025-04-01 ~ 2025-04-02
+## 會辦狀況
+- 2025-03-01
+  - 上線
+---
+# 新專案
+## 基本資訊
+- 目前狀態: 開發
+- 時程:
+  - 開發: 2025-05-01 ~ 2025-06-30
+`
+	want := []Alert{
+		{
+			Kind: AlertOverduePhase, Project: "報表平台", Slug: "報表平台", Line: 5, Phase: "SIT", Date: "2025-04-30", Days: 10,
+			Message: "SIT ended on 2025-04-30 (10 days ago) but 目前狀態 is still SIT中",
+		},
+		{
+			Kind: AlertStaleProject, Project: "上線專案", Slug: "上線專案", Line: 16, Date: "2025-03-01", Days: 70,
+			Message: "Last 會辦 entry was on 2025-03-01 (70 days ago)",
+		},
+		{
+			Kind: AlertStaleProject, Project: "新專案", Slug: "新專案", Line: 19,
+			Message: "No 會辦 entries recorded",
+		},
+	}
+	if got := DetectAlerts(Parse(doc), now, 30); !reflect.DeepEqual(got, want) {
+		t.Errorf("DetectAlerts =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// staleDays 0 turns the stale project check off
+	if got := DetectAlerts(Parse(doc), now, 0); !reflect.DeepEqual(got, want[:1]) {
+		t.Errorf("DetectAlerts without stale check = %+v", got)
+	}
+}
@@ -0,0 +1,156 @@
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CollieProject is a project of the BorderCollie staffing document
+type CollieProject struct {
+	Name   string  `json:"name"`
+	Phases []Phase `json:"phases"`
+}
+
+var (
+	collieHeadingRe = regexp.MustCompile(`^#+\s*(.+?)\s*$`)
+	colliePhaseRe   = regexp.MustCompile(`^\s*[*-]\s*([^,，]+?)\s*[,，]\s*([^,，]*?)\s*[,，]\s*([^:：]+?)\s*(?:[:：]\s*(.*))?$`)
+	collieDateRe    = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})(?:[-/.](\d{1,2}))?$`)
+	collieSplitRe   = regexp.MustCompile(`\s*[,，、]\s*`)
+)
+
+// ParseCollie reads the phases of a BorderCollie document, following the
+// documented line format
+//
+//	# 專案名稱
+//	- 階段名, 開始日期, 結束日期: 人員指派
+//
+// A project line may also be a plain line that is not a list item. An empty
+// start date means the day after the previous phase ends. Dates may be
+// written as YYYY-MM-DD, YYYY/M/D or YYYY-MM for a whole month.
+func ParseCollie(content string) []CollieProject {
+	projects := []CollieProject{}
+	var current *CollieProject
+	prevEnd := ""
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+
+		if m := colliePhaseRe.FindStringSubmatch(line); m != nil {
+			if current == nil {
+				continue
+			}
+			end := NormalizeDate(m[3], true)
+			if end == "" {
+				continue
+			}
+			start := NormalizeDate(m[2], false)
+			if strings.TrimSpace(m[2]) == "" && prevEnd != "" {
+				start = addDays(prevEnd, 1)
+			}
+			if start == "" {
+				start = end
+			}
+			current.Phases = append(current.Phases, Phase{
+				Name:      m[1],
+				StartDate: start,
+				EndDate:   end,
+				Assignees: splitAssignees(m[4]),
+				Line:      i,
+			})
+			prevEnd = end
+			continue
+		}
+
+		if bulletRe.MatchString(trimmed) || indentedRe.MatchString(line) {
+			continue
+		}
+		name := trimmed
+		if m := collieHeadingRe.FindStringSubmatch(trimmed); m != nil {
+			name = m[1]
+		}
+		name = strings.TrimRight(name, ":：")
+		projects = append(projects, CollieProject{Name: name, Phases: []Phase{}})
+		current = &projects[len(projects)-1]
+		prevEnd = ""
+	}
+	return projects
+}
+
+// MergeColliePhases replaces the 時程 of each project with the phases of the
+// BorderCollie project of the same name, as mergeColliePhases does in the
+// editor; projects without a match keep their own 時程
+func MergeColliePhases(projects []Project, collie []CollieProject) []Project {
+	if len(collie) == 0 {
+		return projects
+	}
+
+	byName := map[string]CollieProject{}
+	for _, c := range collie {
+		if _, ok := byName[c.Name]; !ok {
+			byName[c.Name] = c
+		}
+	}
+
+	merged := append([]Project(nil), projects...)
+	for i := range merged {
+		if c, ok := byName[merged[i].Name]; ok && len(c.Phases) > 0 {
+			merged[i].Phases = c.Phases
+		}
+	}
+	return merged
+}
+
+// NormalizeDate returns a date as YYYY-MM-DD, or "" if it is not a date; a
+// month alone (YYYY-MM) becomes its first day, or its last day for an end date
+func NormalizeDate(s string, isEnd bool) string {
+	m := collieDateRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return ""
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	if month < 1 || month > 12 {
+		return ""
+	}
+
+	if m[3] == "" {
+		d := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+		if isEnd {
+			d = d.AddDate(0, 1, -1)
+		}
+		return d.Format("2006-01-02")
+	}
+
+	day, _ := strconv.Atoi(m[3])
+	d := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if d.Day() != day {
+		return "" // e.g. 2026-02-30
+	}
+	return d.Format("2006-01-02")
+}
+
+// addDays adds days to a YYYY-MM-DD date
+func addDays(date string, days int) string {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return ""
+	}
+	return d.AddDate(0, 0, days).Format("2006-01-02")
+}
+
+// splitAssignees splits a list of people
+func splitAssignees(s string) []string {
+	var people []string
+	for _, p := range collieSplitRe.Split(strings.TrimSpace(s), -1) {
+		if p != "" {
+			people = append(people, p)
+		}
+	}
+	return people
+}
@@ -0,0 +1,305 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Editable 基本資訊 fields
+const (
+	FieldStatus       = "燈號"
+	FieldCurrentState = "目前狀態"
+	FieldProgress     = "進度"
+	FieldContact      = "窗口"
+	FieldCategory     = "分類"
+)
+
+var listMarkerRe = regexp.MustCompile(`^\s*[*-]\s*`)
+
+var (
+	ErrProjectNotFound    = errors.New("project not found")
+	ErrActionItemNotFound = errors.New("action item not found")
+	ErrInvalidValue       = errors.New("invalid field value")
+)
+
+// Slugify turns a project name into a URL-friendly identifier, keeping
+// letters (including CJK) and digits and joining everything else with dashes
+func Slugify(name string) string {
+	var sb strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && sb.Len() > 0 {
+				sb.WriteByte('-')
+			}
+			sb.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if sb.Len() == 0 {
+		return "project"
+	}
+	return sb.String()
+}
+
+// assignSlugs gives every project a slug, numbering duplicates in document order
+func assignSlugs(projects []Project) {
+	seen := map[string]int{}
+	for i := range projects {
+		slug := Slugify(projects[i].Name)
+		seen[slug]++
+		if n := seen[slug]; n > 1 {
+			slug = fmt.Sprintf("%s-%d", slug, n)
+		}
+		projects[i].Slug = slug
+	}
+}
+
+// FindSlug returns the index of the project with the given slug or exact name, or -1
+func FindSlug(projects []Project, slug string) int {
+	for i, p := range projects {
+		if p.Slug == slug {
+			return i
+		}
+	}
+	for i, p := range projects {
+		if p.Name == slug {
+			return i
+		}
+	}
+	return -1
+}
+
+// FormatProgress formats a progress percentage as written in 進度
+func FormatProgress(progress int) string {
+	return strconv.Itoa(progress) + "%"
+}
+
+// ValidateField checks a value before it is written to a 基本資訊 field
+func ValidateField(field, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("%w: %s must be a single line", ErrInvalidValue, field)
+	}
+	switch field {
+	case FieldStatus:
+		if value != StatusGreen && value != StatusYellow && value != StatusRed {
+			return fmt.Errorf("%w: %s must be one of %s, %s, %s", ErrInvalidValue, field, StatusGreen, StatusYellow, StatusRed)
+		}
+	case FieldProgress:
+		if p, err := strconv.Atoi(strings.TrimSuffix(value, "%")); err != nil || p < 0 || p > 100 {
+			return fmt.Errorf("%w: %s must be between 0%% and 100%%", ErrInvalidValue, field)
+		}
+	case FieldCurrentState, FieldContact, FieldCategory:
+	default:
+		return fmt.Errorf("%w: unknown field %s", ErrInvalidValue, field)
+	}
+	return nil
+}
+
+// Field returns the parsed value of a 基本資訊 field, or "" if the project
+// leaves it out
+func (p Project) Field(field string) string {
+	if _, ok := p.fields[field]; !ok {
+		return ""
+	}
+	switch field {
+	case FieldStatus:
+		return p.Status
+	case FieldCurrentState:
+		return p.CurrentState
+	case FieldProgress:
+		return FormatProgress(p.Progress)
+	case FieldContact:
+		return p.Contact
+	case FieldCategory:
+		return p.Category
+	}
+	return ""
+}
+
+// SetField returns content with a 基本資訊 field of the index-th project set
+// to value. Only the field's line changes; a missing field is added after the
+// other fields of ## 基本資訊, and the heading is created if needed.
+func SetField(content string, index int, field, value string) (string, error) {
+	if err := ValidateField(field, value); err != nil {
+		return "", err
+	}
+
+	projects := Parse(content)
+	if index < 0 || index >= len(projects) {
+		return "", ErrProjectNotFound
+	}
+	project := projects[index]
+	lines := strings.Split(content, "\n")
+
+	if n, ok := project.fields[field]; ok {
+		lines[n] = replaceFieldValue(lines[n], value)
+		return strings.Join(lines, "\n"), nil
+	}
+
+	return addBasicInfo(lines, project, fmt.Sprintf("- %s: %s", field, value)), nil
+}
+
+// SetPhase returns content with the 時程 phase of the same name set to the
+// given dates, or the phase added after the other phases
+func SetPhase(content string, index int, phase Phase) (string, error) {
+	if phase.Name == "" || strings.ContainsAny(phase.Name, ":\r\n") {
+		return "", fmt.Errorf("%w: invalid phase name %q", ErrInvalidValue, phase.Name)
+	}
+	if !validDate(phase.StartDate) || (phase.EndDate != "" && !validDate(phase.EndDate)) {
+		return "", fmt.Errorf("%w: %s dates must be YYYY-MM-DD", ErrInvalidValue, phase.Name)
+	}
+
+	projects := Parse(content)
+	if index < 0 || index >= len(projects) {
+		return "", ErrProjectNotFound
+	}
+	project := projects[index]
+	lines := strings.Split(content, "\n")
+	item := FormatPhase(phase)
+
+	for _, p := range project.Phases {
+		if p.Name == phase.Name {
+			if p.StartDate == phase.StartDate && p.EndDate == phase.EndDate {
+				return content, nil
+			}
+			lines[p.Line] = replaceListItem(lines[p.Line], item)
+			return strings.Join(lines, "\n"), nil
+		}
+	}
+
+	switch {
+	case len(project.Phases) > 0:
+		last := project.Phases[len(project.Phases)-1].Line
+		return insertLines(lines, last+1, replaceListItem(lines[last], item)), nil
+	case project.timelineLine >= 0:
+		return insertLines(lines, project.timelineLine+1, "  - "+item), nil
+	default:
+		return addBasicInfo(lines, project, "- 時程:", "  - "+item), nil
+	}
+}
+
+// AddMeeting returns content with a 會辦 entry added to the index-th project,
+// in date order with the newest entry first. Content is returned unchanged if
+// the project already has an entry for that date.
+func AddMeeting(content string, index int, date string, texts []string) (string, error) {
+	if !validDate(date) {
+		return "", fmt.Errorf("%w: 會辦 date must be YYYY-MM-DD", ErrInvalidValue)
+	}
+	entry := []string{"- " + date}
+	for _, t := range texts {
+		if strings.ContainsAny(t, "\r\n") {
+			return "", fmt.Errorf("%w: 會辦 lines must be single lines", ErrInvalidValue)
+		}
+		entry = append(entry, "  - "+t)
+	}
+
+	projects := Parse(content)
+	if index < 0 || index >= len(projects) {
+		return "", ErrProjectNotFound
+	}
+	project := projects[index]
+	lines := strings.Split(content, "\n")
+
+	for _, m := range project.Meetings {
+		if m.Date == date {
+			return content, nil
+		}
+	}
+	for _, m := range project.Meetings {
+		if m.Date < date {
+			return insertLines(lines, m.Line, entry...), nil
+		}
+	}
+
+	if len(project.Meetings) > 0 {
+		// after the last entry, before the next section
+		last := project.Meetings[len(project.Meetings)-1].Line
+		n := last + 1
+		for n <= project.EndLine && !strings.HasPrefix(lines[n], "## ") {
+			n++
+		}
+		for n-1 > last && strings.TrimSpace(lines[n-1]) == "" {
+			n--
+		}
+		return insertLines(lines, n, entry...), nil
+	}
+
+	for n := project.NameLine + 1; n <= project.EndLine; n++ {
+		if strings.HasPrefix(lines[n], "## ") && strings.Contains(lines[n], SectionMeetings) {
+			return insertLines(lines, n+1, entry...), nil
+		}
+	}
+	return insertLines(lines, project.EndLine+1, append([]string{"", "## " + SectionMeetings}, entry...)...), nil
+}
+
+// FormatPhase formats a phase as written under 時程, without the list marker
+func FormatPhase(p Phase) string {
+	if p.EndDate == "" || p.EndDate == p.StartDate {
+		return p.Name + ": " + p.StartDate
+	}
+	return p.Name + ": " + p.StartDate + " ~ " + p.EndDate
+}
+
+// addBasicInfo inserts lines after the last 基本資訊 field of a project,
+// creating the ## 基本資訊 heading if needed
+func addBasicInfo(lines []string, project Project, inserted ...string) string {
+	if project.basicInfoLine < 0 {
+		return insertLines(lines, project.NameLine+1, append([]string{"", "## " + SectionBasicInfo}, inserted...)...)
+	}
+	last := project.basicInfoLine
+	for _, n := range project.fields {
+		last = max(last, n)
+	}
+	return insertLines(lines, last+1, inserted...)
+}
+
+// replaceListItem replaces the text of a list item, keeping its indentation,
+// marker and line ending
+func replaceListItem(line, text string) string {
+	cr := ""
+	if strings.HasSuffix(line, "\r") {
+		line, cr = strings.TrimSuffix(line, "\r"), "\r"
+	}
+	return listMarkerRe.FindString(line) + text + cr
+}
+
+// replaceFieldValue keeps everything up to the field's colon and any trailing
+// carriage return, replacing only the value
+func replaceFieldValue(line, value string) string {
+	cr := ""
+	if strings.HasSuffix(line, "\r") {
+		line, cr = strings.TrimSuffix(line, "\r"), "\r"
+	}
+	return line[:strings.Index(line, ":")+1] + " " + value + cr
+}
+
+// insertLines inserts new lines before line n, ending them with \r\n if
+// the document does
+func insertLines(lines []string, n int, inserted ...string) string {
+	out := make([]string, 0, len(lines)+len(inserted))
+	out = append(out, lines[:n]...)
+	out = append(out, inserted...)
+	out = append(out, lines[n:]...)
+
+	if strings.HasSuffix(lines[0], "\r") {
+		first, last := n, n+len(inserted)
+		if n == len(lines) {
+			// appending after a last line without a line ending; the new
+			// last line goes without one instead
+			first, last = n-1, n+len(inserted)-1
+		}
+		for i := first; i < last; i++ {
+			if !strings.HasSuffix(out[i], "\r") {
+				out[i] += "\r"
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,251 @@
+package parser
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const editDoc = `# 報表平台
+
+## 基本資訊
+- 燈號: 綠
+- 目前狀態: 開發中
+- 進度: 40%
+- 時程:
+  - 開發: 2025-03-01 ~ 2025-04-30
+- 分類: 數據
+
+## 會辦狀況
+- 2025-03-01
+  - 啟動會議
+`
+
+// variants returns editDoc with LF and CRLF endings, each with and without the final newline
+func variants(doc string) map[string]string {
+	crlf := strings.ReplaceAll(doc, "\n", "\r\n")
+	return map[string]string{
+		"lf":              doc,
+		"lf no newline":   strings.TrimSuffix(doc, "\n"),
+		"crlf":            crlf,
+		"crlf no newline": strings.TrimSuffix(crlf, "\r\n"),
+	}
+}
+
+// changedLines returns the indexes of the lines that differ between two documents with the same line count
+func changedLines(t *testing.T, before, after string) []int {
+	t.Helper()
+	a, b := strings.Split(before, "\n"), strings.Split(after, "\n")
+	if len(a) != len(b) {
+		t.Fatalf("line count changed from %d to %d:\n%q", len(a), len(b), after)
+	}
+	var changed []int
+	for i := range a {
+		if a[i] != b[i] {
+			changed = append(changed, i)
+		}
+	}
+	return changed
+}
+
+func TestSetFieldRewritesOnlyTheFieldLine(t *testing.T) {
+	tests := []struct {
+		field, value string
+		line         int
+		want         string
+	}{
+		{FieldStatus, StatusRed, 3, "- 燈號: 紅"},
+		{FieldCurrentState, "SIT 測試中", 4, "- 目前狀態: SIT 測試中"},
+		{FieldProgress, "85%", 5, "- 進度: 85%"},
+		{FieldCategory, "報表", 8, "- 分類: 報表"},
+	}
+	for name, doc := range variants(editDoc) {
+		cr := ""
+		if strings.Contains(doc, "\r") {
+			cr = "\r"
+		}
+		for _, tt := range tests {
+			got, err := SetField(doc, 0, tt.field, tt.value)
+			if err != nil {
+				t.Fatalf("%s: SetField(%s): %v", name, tt.field, err)
+			}
+			changed := changedLines(t, doc, got)
+			if len(changed) != 1 || changed[0] != tt.line {
+				t.Errorf("%s: SetField(%s) changed lines %v, want [%d]", name, tt.field, changed, tt.line)
+				continue
+			}
+			if line := strings.Split(got, "\n")[tt.line]; line != tt.want+cr {
+				t.Errorf("%s: SetField(%s) wrote %q, want %q", name, tt.field, line, tt.want+cr)
+			}
+			if strings.HasSuffix(got, "\n") != strings.HasSuffix(doc, "\n") {
+				t.Errorf("%s: SetField(%s) changed the final newline", name, tt.field)
+			}
+		}
+	}
+}
+
+func TestSetFieldRoundTrip(t *testing.T) {
+	for name, doc := range variants(editDoc) {
+		changed, err := SetField(doc, 0, FieldProgress, "90%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored, err := SetField(changed, 0, FieldProgress, "40%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored != doc {
+			t.Errorf("%s: round trip gave %q, want %q", name, restored, doc)
+		}
+		if p := Parse(changed)[0]; p.Progress != 90 || p.CurrentState != "開發中" {
+			t.Errorf("%s: parsed %d %q after SetField", name, p.Progress, p.CurrentState)
+		}
+	}
+}
+
+func TestSetFieldKeepsTrailingSpaces(t *testing.T) {
+	// Trailing spaces on every line; the value's own are replaced, the rest stay
+	doc := strings.ReplaceAll(editDoc, "\n", "  \n")
+	for name, doc := range map[string]string{"lf": doc, "crlf": strings.ReplaceAll(doc, "\n", "\r\n")} {
+		got, err := SetField(doc, 0, FieldProgress, "90%")
+		if err != nil {
+			t.Fatal(err)
+		}
+		changed := changedLines(t, doc, got)
+		if len(changed) != 1 || changed[0] != 5 {
+			t.Fatalf("%s: changed lines %v, want [5]", name, changed)
+		}
+		want := "- 進度: 90%"
+		if name == "crlf" {
+			want += "\r"
+		}
+		if line := strings.Split(got, "\n")[5]; line != want {
+			t.Errorf("%s: wrote %q, want %q", name, line, want)
+		}
+		if p := Parse(got)[0]; p.Name != "報表平台" || p.Progress != 90 || p.CurrentState != "開發中" {
+			t.Errorf("%s: parsed %q %d %q", name, p.Name, p.Progress, p.CurrentState)
+		}
+	}
+}
+
+func TestSetFieldAddsMissingField(t *testing.T) {
+	for name, doc := range variants(editDoc) {
+		got, err := SetField(doc, 0, FieldContact, "Bella")
+		if err != nil {
+			t.Fatal(err)
+		}
+		nl := "\n"
+		if strings.Contains(doc, "\r") {
+			nl = "\r\n"
+		}
+		// after 分類, the last field of 基本資訊
+		anchor := "- 分類: 數據" + nl
+		want := strings.Replace(doc, anchor, anchor+"- 窗口: Bella"+nl, 1)
+		if got != want {
+			t.Errorf("%s: SetField added\n%q\nwant\n%q", name, got, want)
+		}
+		if Parse(got)[0].Contact != "Bella" {
+			t.Errorf("%s: 窗口 not parsed after SetField", name)
+		}
+	}
+}
+
+func TestSetFieldAddsBasicInfo(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{
+			name: "lf",
+			doc:  "# 報表平台\n\n## 其他補充\n- 備註\n",
+			want: "# 報表平台\n\n## 基本資訊\n- 燈號: 黃\n\n## 其他補充\n- 備註\n",
+		},
+		{
+			name: "crlf",
+			doc:  "# 報表平台\r\n\r\n## 其他補充\r\n- 備註\r\n",
+			want: "# 報表平台\r\n\r\n## 基本資訊\r\n- 燈號: 黃\r\n\r\n## 其他補充\r\n- 備註\r\n",
+		},
+		{
+			name: "heading only, no newline",
+			doc:  "# 報表平台",
+			want: "# 報表平台\n\n## 基本資訊\n- 燈號: 黃",
+		},
+		{
+			name: "crlf heading only, no newline",
+			doc:  "# 報表平台\r\n",
+			want: "# 報表平台\r\n\r\n## 基本資訊\r\n- 燈號: 黃\r\n",
+		},
+		{
+			name: "field after the last line, crlf no newline",
+			doc:  "# 報表平台\r\n\r\n## 基本資訊\r\n- 進度: 10%",
+			want: "# 報表平台\r\n\r\n## 基本資訊\r\n- 進度: 10%\r\n- 燈號: 黃",
+		},
+	}
+	for _, tt := range tests {
+		got, err := SetField(tt.doc, 0, FieldStatus, StatusYellow)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: SetField gave\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetFieldPicksTheProject(t *testing.T) {
+	doc := "# A\n## 基本資訊\n- 燈號: 綠\n---\n# B\n## 基本資訊\n- 燈號: 綠\n"
+	got, err := SetField(doc, 1, FieldStatus, StatusRed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed := changedLines(t, doc, got); len(changed) != 1 || changed[0] != 6 {
+		t.Errorf("changed lines %v, want [6]", changed)
+	}
+	if _, err := SetField(doc, 2, FieldStatus, StatusRed); !errors.Is(err, ErrProjectNotFound) {
+		t.Errorf("SetField on a missing project = %v, want ErrProjectNotFound", err)
+	}
+}
+
+func TestProjectField(t *testing.T) {
+	p := Parse(editDoc)[0]
+	tests := []struct {
+		field, want string
+	}{
+		{FieldStatus, "綠"},
+		{FieldCurrentState, "開發中"},
+		{FieldProgress, "40%"},
+		{FieldContact, ""}, // left out, not an empty value
+		{FieldCategory, "數據"},
+		{"時程", ""},
+	}
+	for _, tt := range tests {
+		if got := p.Field(tt.field); got != tt.want {
+			t.Errorf("Field(%s) = %q, want %q", tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestValidateField(t *testing.T) {
+	tests := []struct {
+		field, value string
+		ok           bool
+	}{
+		{FieldStatus, StatusGreen, true},
+		{FieldStatus, "藍", false},
+		{FieldProgress, "100%", true},
+		{FieldProgress, "0", true},
+		{FieldProgress, "101%", false},
+		{FieldProgress, "半", false},
+		{FieldContact, "Andy", true},
+		{FieldContact, "Andy\n- 燈號: 紅", false},
+		{"負責人", "Andy", false},
+	}
+	for _, tt := range tests {
+		err := ValidateField(tt.field, tt.value)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateField(%s, %q) = %v, want ok=%v", tt.field, tt.value, err, tt.ok)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidValue) {
+			t.Errorf("ValidateField(%s, %q) = %v, want ErrInvalidValue", tt.field, tt.value, err)
+		}
+	}
+}
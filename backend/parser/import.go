@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProjectInput is a project to write into a document, e.g. a spreadsheet row.
+// Empty fields are left out; a nil Progress means no 進度.
+type ProjectInput struct {
+	Name         string
+	Status       string
+	CurrentState string
+	Progress     *int
+	Contact      string
+	Category     string
+	Lead         []string
+	Support      []string
+	Phases       []Phase
+	MeetingDate  string
+	MeetingLines []string
+}
+
+// projectSeparator separates project sections
+const projectSeparator = "---"
+
+// FormatProject writes a project section in the layout of docs/sample.md
+func FormatProject(in ProjectInput) string {
+	lines := []string{"# " + in.Name, "", "## " + SectionBasicInfo}
+	field := func(name, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("- %s: %s", name, value))
+		}
+	}
+	field(FieldStatus, in.Status)
+	field(FieldCurrentState, in.CurrentState)
+	if in.Progress != nil {
+		field(FieldProgress, FormatProgress(*in.Progress))
+	}
+	field(FieldContact, in.Contact)
+	if len(in.Phases) > 0 {
+		lines = append(lines, "- 時程:")
+		for _, p := range in.Phases {
+			lines = append(lines, "  - "+FormatPhase(p))
+		}
+	}
+	if len(in.Lead) > 0 || len(in.Support) > 0 {
+		lines = append(lines, "- 相關單位:")
+		if len(in.Lead) > 0 {
+			lines = append(lines, "  - 主辦: "+strings.Join(in.Lead, ", "))
+		}
+		if len(in.Support) > 0 {
+			lines = append(lines, "  - 協辦: "+strings.Join(in.Support, ", "))
+		}
+	}
+	field(FieldCategory, in.Category)
+
+	lines = append(lines, "", "## "+SectionMeetings)
+	if in.MeetingDate != "" {
+		lines = append(lines, "", "- "+in.MeetingDate)
+		for _, l := range in.MeetingLines {
+			lines = append(lines, "  - "+l)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// AppendProjects returns content with the projects added as new sections at the end
+func AppendProjects(content string, inputs []ProjectInput) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(content, " \t\r\n"))
+	for _, in := range inputs {
+		if b.Len() > 0 {
+			b.WriteString("\n\n" + projectSeparator + "\n\n")
+		}
+		b.WriteString(FormatProject(in))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// MergeProject updates the project with the same name, or appends the project
+// if there is none. The non-empty 基本資訊 fields and the phases of the input
+// are set and its 會辦 entry is added; other lines, including 相關單位, are
+// kept as they are.
+func MergeProject(content string, in ProjectInput) (string, bool, error) {
+	projects := Parse(content)
+	index := -1
+	for i, p := range projects {
+		if p.Name == in.Name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return AppendProjects(content, []ProjectInput{in}), false, nil
+	}
+	existing := projects[index]
+
+	// unchanged values are skipped, so a default like 燈號 綠 is not written
+	// into a project that leaves it out
+	fields := [][3]string{
+		{FieldStatus, in.Status, existing.Status},
+		{FieldCurrentState, in.CurrentState, existing.CurrentState},
+		{FieldContact, in.Contact, existing.Contact},
+		{FieldCategory, in.Category, existing.Category},
+	}
+	if in.Progress != nil {
+		fields = append(fields, [3]string{FieldProgress, FormatProgress(*in.Progress), FormatProgress(existing.Progress)})
+	}
+
+	var err error
+	for _, f := range fields {
+		if f[1] == "" || f[1] == f[2] {
+			continue
+		}
+		if content, err = SetField(content, index, f[0], f[1]); err != nil {
+			return "", true, err
+		}
+	}
+	for _, p := range in.Phases {
+		if content, err = SetPhase(content, index, p); err != nil {
+			return "", true, err
+		}
+	}
+	if in.MeetingDate != "" {
+		if content, err = AddMeeting(content, index, in.MeetingDate, in.MeetingLines); err != nil {
+			return "", true, err
+		}
+	}
+	return content, true, nil
+}
@@ -0,0 +1,182 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// inputOf returns the fields of a parsed project that FormatProject writes
+func inputOf(p Project) ProjectInput {
+	progress := p.Progress
+	in := ProjectInput{
+		Name:         p.Name,
+		Status:       p.Status,
+		CurrentState: p.CurrentState,
+		Progress:     &progress,
+		Contact:      p.Contact,
+		Category:     p.Category,
+	}
+	if len(p.Departments.Lead) > 0 {
+		in.Lead = p.Departments.Lead
+	}
+	if len(p.Departments.Support) > 0 {
+		in.Support = p.Departments.Support
+	}
+	for _, ph := range p.Phases {
+		in.Phases = append(in.Phases, Phase{Name: ph.Name, StartDate: ph.StartDate, EndDate: ph.EndDate})
+	}
+	if len(p.Meetings) > 0 {
+		in.MeetingDate = p.Meetings[0].Date
+		for _, l := range p.Meetings[0].Lines {
+			in.MeetingLines = append(in.MeetingLines, l.Text)
+		}
+	}
+	return in
+}
+
+func progress(p int) *int { return &p }
+
+func TestFormatProjectRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		in   ProjectInput
+	}{
+		{
+			name: "every field",
+			in: ProjectInput{
+				Name:         "報表平台",
+				Status:       StatusYellow,
+				CurrentState: "SIT 測試中",
+				Progress:     progress(65),
+				Contact:      "Amy、Bob",
+				Category:     "數據",
+				Lead:         []string{"資訊處", "營運部"},
+				Support:      []string{"法務"},
+				Phases: []Phase{
+					{Name: "開發", StartDate: "2025-03-01", EndDate: "2025-04-30"},
+					{Name: "PROD", StartDate: "2025-06-01", EndDate: "2025-06-01"},
+				},
+				MeetingDate:  "2025-05-02",
+				MeetingLines: []string{"確認測試範圍", "下週上線審查"},
+			},
+		},
+		{
+			name: "only support units and no meeting",
+			in: ProjectInput{
+				Name:     "新專案",
+				Status:   StatusGreen,
+				Progress: progress(0),
+				Support:  []string{"稽核室"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			projects := Parse(FormatProject(tt.in))
+			if len(projects) != 1 {
+				t.Fatalf("parsed %d projects, want 1", len(projects))
+			}
+			if got := inputOf(projects[0]); !reflect.DeepEqual(got, tt.in) {
+				t.Errorf("Parse(FormatProject(in)) =\n%+v\nwant\n%+v", got, tt.in)
+			}
+		})
+	}
+}
+
+func TestFormatProjectLeavesOutEmptyFields(t *testing.T) {
+	content := FormatProject(ProjectInput{Name: "新專案"})
+	for _, field := range []string{FieldStatus, FieldProgress, FieldContact, "時程", "相關單位"} {
+		if strings.Contains(content, field) {
+			t.Errorf("empty %s was written:\n%s", field, content)
+		}
+	}
+}
+
+const mergeDoc = `# 報表平台
+
+## 基本資訊
+- 目前狀態: 開發中
+- 進度: 40%
+- 時程:
+  - 開發: 2025-03-01 ~ 2025-04-30
+- 相關單位:
+  - 主辦: 資訊處
+
+## 會辦狀況
+
+- 2025-03-01
+  - 啟動會議
+
+---
+
+# 其他專案
+
+## 基本資訊
+- 燈號: 紅
+`
+
+func TestMergeProject(t *testing.T) {
+	in := ProjectInput{
+		Name:         "報表平台",
+		Status:       StatusGreen, // the default, which the project leaves out
+		CurrentState: "SIT",
+		Progress:     progress(40), // unchanged
+		Contact:      "Amy",
+		Lead:         []string{"營運部"}, // 相關單位 are kept as they are
+		Phases: []Phase{
+			{Name: "開發", StartDate: "2025-03-01", EndDate: "2025-05-15"},
+			{Name: "SIT", StartDate: "2025-05-16", EndDate: "2025-06-15"},
+		},
+		MeetingDate:  "2025-05-02",
+		MeetingLines: []string{"延後一週"},
+	}
+
+	content, merged, err := MergeProject(mergeDoc, in)
+	if err != nil || !merged {
+		t.Fatalf("MergeProject = %v, %v", merged, err)
+	}
+	if strings.Contains(content, FieldStatus+": "+StatusGreen) {
+		t.Errorf("unchanged default 燈號 was written:\n%s", content)
+	}
+	if strings.Count(content, FieldProgress) != 1 {
+		t.Errorf("unchanged 進度 was written again:\n%s", content)
+	}
+
+	projects := Parse(content)
+	if len(projects) != 2 {
+		t.Fatalf("parsed %d projects, want 2", len(projects))
+	}
+	want := in
+	want.Lead = []string{"資訊處"}
+	if got := inputOf(projects[0]); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged project =\n%+v\nwant\n%+v", got, want)
+	}
+	if n := len(projects[0].Meetings); n != 2 {
+		t.Errorf("merged project has %d 會辦 entries, want the new one and the old one", n)
+	}
+	if !strings.HasSuffix(content, mergeDoc[strings.Index(mergeDoc, "---"):]) {
+		t.Errorf("other project changed:\n%s", content)
+	}
+
+	// merging the same input again changes nothing
+	again, _, err := MergeProject(content, in)
+	if err != nil || again != content {
+		t.Errorf("second merge changed the document:\n%s", again)
+	}
+}
+
+func TestMergeProjectAppendsNewProject(t *testing.T) {
+	in := ProjectInput{Name: "新專案", Status: StatusRed, Progress: progress(10), MeetingDate: "2025-05-02", MeetingLines: []string{"立案"}}
+	content, merged, err := MergeProject(mergeDoc, in)
+	if err != nil || merged {
+		t.Fatalf("MergeProject = %v, %v, want an appended project", merged, err)
+	}
+	projects := Parse(content)
+	if len(projects) != 3 {
+		t.Fatalf("parsed %d projects, want 3", len(projects))
+	}
+	if got := inputOf(projects[2]); !reflect.DeepEqual(got, in) {
+		t.Errorf("appended project =\n%+v\nwant\n%+v", got, in)
+	}
+}
@@ -0,0 +1,339 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic codes
+const (
+	CodeInvalidStatus     = "invalid-status"
+	CodeInvalidProgress   = "invalid-progress"
+	CodeInvalidDate       = "invalid-date"
+	CodeInvalidPhase      = "invalid-phase"
+	CodePhaseRange        = "phase-range"
+	CodeMeetingDate       = "meeting-date"
+	CodeMeetingIndent     = "meeting-indent"
+	CodeMeetingWithoutDay = "meeting-without-date"
+)
+
+// Diagnostic is a problem found in a workspace document. Line is 0-indexed
+// like the rest of this package; columns are 0-indexed UTF-16 offsets into the
+// line, so they can be used directly by the editor.
+type Diagnostic struct {
+	Line      int    `json:"line"`
+	Column    int    `json:"column"`
+	EndColumn int    `json:"endColumn"`
+	Severity  string `json:"severity"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Fix       *Fix   `json:"fix,omitempty"`
+}
+
+// Fix is a suggested replacement for the whole line of a diagnostic, without its line ending
+type Fix struct {
+	Title string `json:"title"`
+	Text  string `json:"text"`
+}
+
+var (
+	dateRe         = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+	looseDateRe    = regexp.MustCompile(`^(\d{4})[/.\-年](\d{1,2})[/.\-月](\d{1,2})日?`)
+	shortDateRe    = regexp.MustCompile(`^\d{1,2}[/.\-月]\d{1,2}日?`)
+	listItemRe     = regexp.MustCompile(`^([*-])\s*(.*)$`)
+	percentRe      = regexp.MustCompile(`^(\d+)\s*%$`)
+	numberRe       = regexp.MustCompile(`^\d+(\.\d+)?$`)
+	placeholderRe  = regexp.MustCompile(`^[*-]\s*[^:]+:\s*$`)
+	knownFieldRe   = regexp.MustCompile(`^[*-]\s*(燈號|(目前)?狀態|進度|窗口|分類|時程|相關單位|承辦|主辦|協辦[^:]*):`)
+	statusAliases  = map[string]string{"green": StatusGreen, "g": StatusGreen, "綠燈": StatusGreen, "綠色": StatusGreen, "绿": StatusGreen, "yellow": StatusYellow, "y": StatusYellow, "黃燈": StatusYellow, "黃色": StatusYellow, "黄": StatusYellow, "red": StatusRed, "r": StatusRed, "紅燈": StatusRed, "紅色": StatusRed, "红": StatusRed}
+	validStatusSet = map[string]bool{StatusGreen: true, StatusYellow: true, StatusRed: true}
+)
+
+// Lint checks a workspace document for common format mistakes
+func Lint(content string) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	lines := strings.Split(content, "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSuffix(lines[i], "\r")
+	}
+
+	for _, project := range Parse(content) {
+		diagnostics = append(diagnostics, lintBasicInfo(lines, &project)...)
+		diagnostics = append(diagnostics, lintSections(lines, &project)...)
+	}
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].Line != diagnostics[j].Line {
+			return diagnostics[i].Line < diagnostics[j].Line
+		}
+		return diagnostics[i].Column < diagnostics[j].Column
+	})
+	return diagnostics
+}
+
+// lintBasicInfo checks the 燈號, 進度 and phase values the parser picked up
+func lintBasicInfo(lines []string, project *Project) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	if n, ok := project.fields[FieldStatus]; ok && !validStatusSet[project.Status] {
+		d := valueDiagnostic(lines[n], n, SeverityError, CodeInvalidStatus,
+			fmt.Sprintf("Unknown 燈號 %q, expected %s, %s or %s", project.Status, StatusGreen, StatusYellow, StatusRed))
+		if status, ok := statusAliases[strings.ToLower(project.Status)]; ok {
+			d.Fix = &Fix{Title: "Use " + status, Text: replaceFieldValue(lines[n], status)}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	if n, ok := project.fields[FieldProgress]; ok {
+		if d, bad := lintProgress(lines[n], n); bad {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	for _, phase := range project.Phases {
+		line := lines[phase.Line]
+		switch {
+		case !validDate(phase.StartDate) || !validDate(phase.EndDate):
+			diagnostics = append(diagnostics, valueDiagnostic(line, phase.Line, SeverityError, CodeInvalidDate,
+				fmt.Sprintf("Phase %s has a date that does not exist", phase.Name)))
+		case phase.EndDate < phase.StartDate:
+			d := valueDiagnostic(line, phase.Line, SeverityError, CodePhaseRange,
+				fmt.Sprintf("Phase %s ends (%s) before it starts (%s)", phase.Name, phase.EndDate, phase.StartDate))
+			d.Fix = &Fix{Title: "Swap start and end dates", Text: swapDates(line, phase.StartDate, phase.EndDate)}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// lintProgress checks that a 進度 line holds a percentage between 0% and 100%
+func lintProgress(line string, n int) (Diagnostic, bool) {
+	value := fieldValue(progressRe, strings.TrimSpace(line))
+
+	if m := percentRe.FindStringSubmatch(value); m != nil {
+		p, _ := strconv.Atoi(m[1])
+		if p <= 100 {
+			if m[0] == m[1]+"%" {
+				return Diagnostic{}, false
+			}
+			d := valueDiagnostic(line, n, SeverityWarning, CodeInvalidProgress, "進度 should be written like 50%")
+			d.Fix = &Fix{Title: "Use " + FormatProgress(p), Text: replaceFieldValue(line, FormatProgress(p))}
+			return d, true
+		}
+		return valueDiagnostic(line, n, SeverityError, CodeInvalidProgress, "進度 must be between 0% and 100%"), true
+	}
+
+	d := valueDiagnostic(line, n, SeverityError, CodeInvalidProgress, fmt.Sprintf("進度 %q is not a percentage", value))
+	if numberRe.MatchString(value) {
+		if f, err := strconv.ParseFloat(value, 64); err == nil && f <= 100 {
+			p := FormatProgress(int(f))
+			d.Fix = &Fix{Title: "Use " + p, Text: replaceFieldValue(line, p)}
+		}
+	}
+	return d, true
+}
+
+// lintSections walks the lines of a project for problems the parser silently skips
+func lintSections(lines []string, project *Project) []Diagnostic {
+	var diagnostics []Diagnostic
+
+	phaseLines := map[int]bool{}
+	for _, phase := range project.Phases {
+		phaseLines[phase.Line] = true
+	}
+
+	section := ""
+	inTimeline := false
+	hasMeeting := false
+	for n := project.NameLine + 1; n <= project.EndLine; n++ {
+		line := lines[n]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "## ") {
+			section = strings.TrimSpace(strings.TrimPrefix(line, "## "))
+			inTimeline, hasMeeting = false, false
+			continue
+		}
+		if trimmed == "" {
+			continue
+		}
+
+		switch {
+		case section == SectionBasicInfo:
+			switch {
+			case timelineRe.MatchString(trimmed):
+				inTimeline = true
+			case knownFieldRe.MatchString(trimmed):
+				// Another top-level field ends the 時程 list
+				if line == trimmed {
+					inTimeline = false
+				}
+			case inTimeline && listItemRe.MatchString(trimmed) && !phaseLines[n] && !placeholderRe.MatchString(trimmed):
+				diagnostics = append(diagnostics, lintPhaseLine(line, n))
+			}
+
+		case strings.Contains(section, SectionMeetings):
+			if d, ok := lintMeetingLine(line, n, hasMeeting); ok {
+				diagnostics = append(diagnostics, d)
+			}
+			if meetingRe.MatchString(trimmed) {
+				hasMeeting = true
+			}
+		}
+	}
+	return diagnostics
+}
+
+// lintPhaseLine reports a 時程 item that the parser could not read as a phase
+func lintPhaseLine(line string, n int) Diagnostic {
+	d := lineDiagnostic(line, n, SeverityWarning, CodeInvalidPhase,
+		"Phase is not recognized, expected 名稱: YYYY-MM-DD ~ YYYY-MM-DD")
+
+	// Offer a fix when the dates are merely written loosely
+	if i := strings.Index(line, ":"); i >= 0 {
+		var dates []string
+		for _, part := range strings.Split(line[i+1:], "~") {
+			date, ok := normalizeDate(strings.TrimSpace(part))
+			if !ok {
+				return d
+			}
+			dates = append(dates, date)
+		}
+		if len(dates) <= 2 && len(dates) > 0 {
+			d.Fix = &Fix{Title: "Use YYYY-MM-DD dates", Text: line[:i+1] + " " + strings.Join(dates, " ~ ")}
+		}
+	}
+	return d
+}
+
+// lintMeetingLine checks a line of 會辦狀況; hasMeeting tells whether a date line came before it
+func lintMeetingLine(line string, n int, hasMeeting bool) (Diagnostic, bool) {
+	trimmed := strings.TrimSpace(line)
+	indented := line != strings.TrimLeft(line, " \t")
+	item := listItemRe.FindStringSubmatch(trimmed)
+
+	switch {
+	case item == nil:
+		return lineDiagnostic(line, n, SeverityWarning, CodeMeetingIndent,
+			"Line is not a list item and will be ignored"), true
+
+	case indented:
+		if !hasMeeting {
+			return lineDiagnostic(line, n, SeverityWarning, CodeMeetingWithoutDay,
+				"Meeting content has no date above it and will be ignored"), true
+		}
+		return Diagnostic{}, false
+
+	case meetingRe.MatchString(trimmed):
+		date := meetingRe.FindStringSubmatch(trimmed)[1]
+		if !validDate(date) {
+			return valueRangeDiagnostic(line, n, date, SeverityError, CodeInvalidDate,
+				fmt.Sprintf("Meeting date %s does not exist", date)), true
+		}
+		return Diagnostic{}, false
+	}
+
+	text := item[2]
+	if m := looseDateRe.FindString(text); m != "" {
+		d := valueRangeDiagnostic(line, n, m, SeverityError, CodeMeetingDate, "Meeting date must be written as YYYY-MM-DD")
+		if date, ok := normalizeDate(m); ok {
+			d.Fix = &Fix{Title: "Use " + date, Text: strings.Replace(line, m, date, 1)}
+		}
+		return d, true
+	}
+	if m := shortDateRe.FindString(text); m != "" {
+		return valueRangeDiagnostic(line, n, m, SeverityError, CodeMeetingDate,
+			"Meeting date must be written as YYYY-MM-DD, including the year"), true
+	}
+
+	d := lineDiagnostic(line, n, SeverityWarning, CodeMeetingIndent, "Meeting content must be indented under a date")
+	if hasMeeting {
+		d.Fix = &Fix{Title: "Indent under the previous date", Text: "  " + line}
+	} else {
+		d.Message = "Meeting content has no date above it and will be ignored"
+		d.Code = CodeMeetingWithoutDay
+	}
+	return d, true
+}
+
+// lineDiagnostic covers the text of a whole line, without leading indentation
+func lineDiagnostic(line string, n int, severity, code, message string) Diagnostic {
+	start := len(line) - len(strings.TrimLeft(line, " \t"))
+	return Diagnostic{
+		Line:      n,
+		Column:    utf16Len(line[:start]),
+		EndColumn: utf16Len(strings.TrimRight(line, " \t\r")),
+		Severity:  severity,
+		Code:      code,
+		Message:   message,
+	}
+}
+
+// valueDiagnostic covers the value after a field's colon, or the whole line if it has none
+func valueDiagnostic(line string, n int, severity, code, message string) Diagnostic {
+	d := lineDiagnostic(line, n, severity, code, message)
+	if i := strings.Index(line, ":"); i >= 0 {
+		value := strings.TrimSpace(strings.TrimRight(line[i+1:], "\r"))
+		if value != "" {
+			d.Column = utf16Len(line[:i+1+strings.Index(line[i+1:], value)])
+			d.EndColumn = d.Column + utf16Len(value)
+		}
+	}
+	return d
+}
+
+// valueRangeDiagnostic covers the first occurrence of text in the line
+func valueRangeDiagnostic(line string, n int, text, severity, code, message string) Diagnostic {
+	d := lineDiagnostic(line, n, severity, code, message)
+	if i := strings.Index(line, text); i >= 0 {
+		d.Column = utf16Len(line[:i])
+		d.EndColumn = d.Column + utf16Len(text)
+	}
+	return d
+}
+
+// validDate reports whether a YYYY-MM-DD date exists on the calendar
+func validDate(date string) bool {
+	_, err := time.Parse("2006-01-02", date)
+	return err == nil
+}
+
+// normalizeDate rewrites dates like 2025/1/5 or 2025年1月5日 as YYYY-MM-DD
+func normalizeDate(s string) (string, bool) {
+	if dateRe.MatchString(s) && len(s) == 10 {
+		return s, validDate(s)
+	}
+	m := looseDateRe.FindStringSubmatch(s)
+	if m == nil || len(m[0]) != len(s) {
+		return "", false
+	}
+	month, _ := strconv.Atoi(m[2])
+	day, _ := strconv.Atoi(m[3])
+	date := fmt.Sprintf("%s-%02d-%02d", m[1], month, day)
+	return date, validDate(date)
+}
+
+// swapDates exchanges the start and end dates of a phase line
+func swapDates(line, start, end string) string {
+	i := strings.Index(line, start)
+	j := strings.LastIndex(line, end)
+	if i < 0 || j <= i {
+		return line
+	}
+	return line[:i] + end + line[i+len(start):j] + start + line[j+len(end):]
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
@@ -0,0 +1,202 @@
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want []Diagnostic
+	}{
+		{
+			name: "clean document",
+			doc:  "# 專案\n## 基本資訊\n- 燈號: 綠\n- 進度: 50%\n- 時程:\n  - 開發: 2025-03-01 ~ 2025-03-31\n  - SIT:\n## 會辦狀況\n- 2025-03-01 啟動\n  - 內容\n",
+			want: []Diagnostic{},
+		},
+		{
+			name: "unknown 燈號 with an alias",
+			doc:  "# 專案\n## 基本資訊\n- 燈號: Green\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 11, Severity: SeverityError, Code: CodeInvalidStatus,
+				Message: `Unknown 燈號 "Green", expected 綠, 黃 or 紅`,
+				Fix:     &Fix{Title: "Use 綠", Text: "- 燈號: 綠"},
+			}},
+		},
+		{
+			name: "unknown 燈號",
+			doc:  "# 專案\n## 基本資訊\n* 燈號:藍\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 5, EndColumn: 6, Severity: SeverityError, Code: CodeInvalidStatus,
+				Message: `Unknown 燈號 "藍", expected 綠, 黃 or 紅`,
+			}},
+		},
+		{
+			name: "進度 without a percent sign",
+			doc:  "# 專案\n## 基本資訊\n- 進度: 75\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 8, Severity: SeverityError, Code: CodeInvalidProgress,
+				Message: `進度 "75" is not a percentage`,
+				Fix:     &Fix{Title: "Use 75%", Text: "- 進度: 75%"},
+			}},
+		},
+		{
+			name: "進度 with a space before the percent sign",
+			doc:  "# 專案\n## 基本資訊\n- 進度: 75 %\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 10, Severity: SeverityWarning, Code: CodeInvalidProgress,
+				Message: "進度 should be written like 50%",
+				Fix:     &Fix{Title: "Use 75%", Text: "- 進度: 75%"},
+			}},
+		},
+		{
+			name: "進度 over 100%",
+			doc:  "# 專案\n## 基本資訊\n- 進度: 120%\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 10, Severity: SeverityError, Code: CodeInvalidProgress,
+				Message: "進度 must be between 0% and 100%",
+			}},
+		},
+		{
+			name: "進度 in words",
+			doc:  "# 專案\n## 基本資訊\n- 進度: 過半\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 8, Severity: SeverityError, Code: CodeInvalidProgress,
+				Message: `進度 "過半" is not a percentage`,
+			}},
+		},
+		{
+			name: "phase ends before it starts",
+			doc:  "# 專案\n## 基本資訊\n- 時程:\n  - SIT: 2025-05-10 ~ 2025-05-01\n",
+			want: []Diagnostic{{
+				Line: 3, Column: 9, EndColumn: 32, Severity: SeverityError, Code: CodePhaseRange,
+				Message: "Phase SIT ends (2025-05-01) before it starts (2025-05-10)",
+				Fix:     &Fix{Title: "Swap start and end dates", Text: "  - SIT: 2025-05-01 ~ 2025-05-10"},
+			}},
+		},
+		{
+			name: "phase date that does not exist",
+			doc:  "# 專案\n## 基本資訊\n- 時程:\n  - 開發: 2025-02-30\n",
+			want: []Diagnostic{{
+				Line: 3, Column: 8, EndColumn: 18, Severity: SeverityError, Code: CodeInvalidDate,
+				Message: "Phase 開發 has a date that does not exist",
+			}},
+		},
+		{
+			name: "phase with loose dates",
+			doc:  "# 專案\n## 基本資訊\n- 時程:\n  - 開發: 2025/3/1 ~ 2025年4月30日\n- 窗口: Andy\n  - 不是階段\n",
+			want: []Diagnostic{{
+				Line: 3, Column: 2, EndColumn: 29, Severity: SeverityWarning, Code: CodeInvalidPhase,
+				Message: "Phase is not recognized, expected 名稱: YYYY-MM-DD ~ YYYY-MM-DD",
+				Fix:     &Fix{Title: "Use YYYY-MM-DD dates", Text: "  - 開發: 2025-03-01 ~ 2025-04-30"},
+			}},
+		},
+		{
+			name: "會辦 dates",
+			doc:  "# 專案\n## 會辦狀況\n- 2025/3/5 討論\n- 3/6 追蹤\n- 2025-02-30\n",
+			want: []Diagnostic{
+				{
+					Line: 2, Column: 2, EndColumn: 10, Severity: SeverityError, Code: CodeMeetingDate,
+					Message: "Meeting date must be written as YYYY-MM-DD",
+					Fix:     &Fix{Title: "Use 2025-03-05", Text: "- 2025-03-05 討論"},
+				},
+				{
+					Line: 3, Column: 2, EndColumn: 5, Severity: SeverityError, Code: CodeMeetingDate,
+					Message: "Meeting date must be written as YYYY-MM-DD, including the year",
+				},
+				{
+					Line: 4, Column: 2, EndColumn: 12, Severity: SeverityError, Code: CodeInvalidDate,
+					Message: "Meeting date 2025-02-30 does not exist",
+				},
+			},
+		},
+		{
+			name: "unindented meeting lines",
+			doc:  "# 專案\n## 會辦狀況 (日期反序)\n  - 沒有日期\n- 也沒有日期\n- 2025-03-01\n- 忘了縮排\n隨手記\n",
+			want: []Diagnostic{
+				{
+					Line: 2, Column: 2, EndColumn: 8, Severity: SeverityWarning, Code: CodeMeetingWithoutDay,
+					Message: "Meeting content has no date above it and will be ignored",
+				},
+				{
+					Line: 3, Column: 0, EndColumn: 7, Severity: SeverityWarning, Code: CodeMeetingWithoutDay,
+					Message: "Meeting content has no date above it and will be ignored",
+				},
+				{
+					Line: 5, Column: 0, EndColumn: 6, Severity: SeverityWarning, Code: CodeMeetingIndent,
+					Message: "Meeting content must be indented under a date",
+					Fix:     &Fix{Title: "Indent under the previous date", Text: "  - 忘了縮排"},
+				},
+				{
+					Line: 6, Column: 0, EndColumn: 3, Severity: SeverityWarning, Code: CodeMeetingIndent,
+					Message: "Line is not a list item and will be ignored",
+				},
+			},
+		},
+		{
+			name: "columns count UTF-16 units",
+			doc:  "# 專案\n## 基本資訊\n- 燈號: 😀\n",
+			want: []Diagnostic{{
+				Line: 2, Column: 6, EndColumn: 8, Severity: SeverityError, Code: CodeInvalidStatus,
+				Message: `Unknown 燈號 "😀", expected 綠, 黃 or 紅`,
+			}},
+		},
+		{
+			name: "diagnostics are ordered across projects",
+			doc:  "# A\n## 會辦狀況\n- 忘了日期\n---\n# B\n## 基本資訊\n- 進度: 75 %\n- 燈號: red\n",
+			want: []Diagnostic{
+				{
+					Line: 2, Column: 0, EndColumn: 6, Severity: SeverityWarning, Code: CodeMeetingWithoutDay,
+					Message: "Meeting content has no date above it and will be ignored",
+				},
+				{
+					Line: 6, Column: 6, EndColumn: 10, Severity: SeverityWarning, Code: CodeInvalidProgress,
+					Message: "進度 should be written like 50%",
+					Fix:     &Fix{Title: "Use 75%", Text: "- 進度: 75%"},
+				},
+				{
+					Line: 7, Column: 6, EndColumn: 9, Severity: SeverityError, Code: CodeInvalidStatus,
+					Message: `Unknown 燈號 "red", expected 綠, 黃 or 紅`,
+					Fix:     &Fix{Title: "Use 紅", Text: "- 燈號: 紅"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Lint(tt.doc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+			// CRLF documents give the same diagnostics and fixes
+			if got := Lint(strings.ReplaceAll(tt.doc, "\n", "\r\n")); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() with CRLF =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"2025-03-01", "2025-03-01", true},
+		{"2025/3/1", "2025-03-01", true},
+		{"2025.12.31", "2025-12-31", true},
+		{"2025年1月5日", "2025-01-05", true},
+		{"2025/2/30", "2025-02-30", false},
+		{"2025/3/1 下午", "", false},
+		{"3/1", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizeDate(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("normalizeDate(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Package parser reads the Sheltie project Markdown format.
+//
+// It follows frontend/src/utils/parser.ts so the backend sees the same
+// projects as the editor preview. All line numbers are 0-indexed lines of the
+// whole document, like Project.LineNumber on the frontend.
+package parser
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Status values (燈號)
+const (
+	StatusGreen  = "綠"
+	StatusYellow = "黃"
+	StatusRed    = "紅"
+)
+
+// Markers at the start of a meeting or note line
+const (
+	MarkerTracking = "_待追蹤_"
+	MarkerPlanned  = "_預計_"
+	MarkerResolved = "_已完成_" // a resolved _待追蹤_ item
+)
+
+// Section headings
+const (
+	SectionBasicInfo = "基本資訊"
+	SectionMeetings  = "會辦狀況"
+	SectionNotes     = "其他補充"
+)
+
+// Project is one project section of a workspace document
+type Project struct {
+	Name         string         `json:"name"`
+	Slug         string         `json:"slug"` // unique within the document
+	Status       string         `json:"status"`
+	CurrentState string         `json:"currentState"`
+	Progress     int            `json:"progress"`
+	Contact      string         `json:"contact"`
+	Phases       []Phase        `json:"phases"`
+	Departments  Departments    `json:"departments"`
+	Category     string         `json:"category"`
+	Meetings     []MeetingEntry `json:"meetings"`
+	Notes        []NoteLine     `json:"notes"`
+	LineNumber   int            `json:"lineNumber"` // first line of the section
+	NameLine     int            `json:"nameLine"`   // line of the # heading
+	EndLine      int            `json:"endLine"`    // last line of the section
+
+	fields        map[string]int // line of each 基本資訊 field, see SetField
+	basicInfoLine int            // line of the ## 基本資訊 heading, -1 if missing
+	timelineLine  int            // line of the 時程 field, -1 if missing
+}
+
+// Departments lists the units involved in a project
+type Departments struct {
+	Lead    []string `json:"承辦"` // also written as 主辦
+	Support []string `json:"協辦"`
+}
+
+// Phase is a named date range from 時程, or from the collie document
+type Phase struct {
+	Name      string   `json:"name"`
+	StartDate string   `json:"startDate"`
+	EndDate   string   `json:"endDate"`
+	Assignees []string `json:"assignees,omitempty"` // collie phases only
+	Line      int      `json:"line"`                // line in the document the phase came from
+}
+
+// MeetingEntry is a dated entry under 會辦狀況
+type MeetingEntry struct {
+	Date  string        `json:"date"`
+	Lines []MeetingLine `json:"lines"`
+	IsOld bool          `json:"isOld"` // more than a month ago
+	Line  int           `json:"line"`
+}
+
+// MeetingLine is a single line of a meeting entry
+type MeetingLine struct {
+	Text       string `json:"text"`
+	IsTracking bool   `json:"isTracking"`
+	IsPlanned  bool   `json:"isPlanned"`
+	Line       int    `json:"line"`
+}
+
+// NoteLine is a list item under 其他補充
+type NoteLine struct {
+	Text       string     `json:"text"`
+	IsTracking bool       `json:"isTracking"`
+	IsPlanned  bool       `json:"isPlanned"`
+	Line       int        `json:"line"`
+	Children   []NoteLine `json:"children,omitempty"`
+}
+
+var (
+	statusRe     = regexp.MustCompile(`^[*-]\s*燈號:`)
+	stateRe      = regexp.MustCompile(`^[*-]\s*(目前)?狀態:`)
+	progressRe   = regexp.MustCompile(`^[*-]\s*進度:`)
+	contactRe    = regexp.MustCompile(`^[*-]\s*窗口:`)
+	categoryRe   = regexp.MustCompile(`^[*-]\s*分類:`)
+	timelineRe   = regexp.MustCompile(`^[*-]\s*時程:`)
+	deptsRe      = regexp.MustCompile(`^[*-]\s*相關單位:`)
+	leadRe       = regexp.MustCompile(`^[*-]\s*(承辦|主辦):`)
+	supportRe    = regexp.MustCompile(`^[*-]\s*協辦`)
+	supportKeyRe = regexp.MustCompile(`^[*-]\s*協辦[^:]*:`)
+	phaseRe      = regexp.MustCompile(`^[*-]\s*([^:]+):\s*(\d{4}-\d{2}-\d{2})(?:\s*~\s*(\d{4}-\d{2}-\d{2}))?$`)
+	indentItemRe = regexp.MustCompile(`^\s+[*-]\s*(.+)$`)
+	meetingRe    = regexp.MustCompile(`^[*-]\s*(\d{4}-\d{2}-\d{2})(.*)$`)
+	indentedRe   = regexp.MustCompile(`^\s+[*-].+`)
+	bulletRe     = regexp.MustCompile(`^[*-]\s*`)
+	noteRe       = regexp.MustCompile(`^[*-]\s+`)
+	noteChildRe  = regexp.MustCompile(`^\s+[*-]\s+`)
+	leadingIntRe = regexp.MustCompile(`^[+-]?\d+`)
+)
+
+// Parse splits a document on --- lines and parses each section that has a
+// # heading into a Project. CRLF line endings are accepted, as the editor
+// turns them into LF before its own parser sees the document.
+func Parse(content string) []Project {
+	projects := []Project{}
+	lines := strings.Split(content, "\n")
+
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && strings.TrimSuffix(lines[i], "\r") != "---" {
+			continue
+		}
+		if i > start {
+			if project, ok := parseSection(lines[start:i], start); ok {
+				projects = append(projects, project)
+			}
+		}
+		start = i + 1
+	}
+
+	assignSlugs(projects)
+	return projects
+}
+
+// Find returns the project with the given name
+func Find(projects []Project, name string) (*Project, bool) {
+	for i := range projects {
+		if projects[i].Name == name {
+			return &projects[i], true
+		}
+	}
+	return nil, false
+}
+
+// sectionState tracks where the parser is inside a project section
+type sectionState struct {
+	section        string
+	meeting        int // index of the current meeting entry, -1 if none
+	inTimeline     bool
+	inDepartments  bool
+	departmentType string
+}
+
+// parseSection parses the lines of one section starting at document line offset
+func parseSection(lines []string, start int) (Project, bool) {
+	lines, offset := trimSection(lines, start)
+
+	project := Project{
+		Status:     StatusGreen,
+		Phases:     []Phase{},
+		Meetings:   []MeetingEntry{},
+		Notes:      []NoteLine{},
+		LineNumber: start,
+		EndLine:    offset + len(lines) - 1,
+
+		fields:        map[string]int{},
+		basicInfoLine: -1,
+		timelineLine:  -1,
+	}
+	project.Departments.Lead = []string{}
+	project.Departments.Support = []string{}
+
+	found := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "# ") {
+			project.Name = strings.TrimSpace(strings.Replace(line, "# ", "", 1))
+			project.NameLine = offset + i
+			found = true
+			break
+		}
+	}
+	if !found {
+		return project, false
+	}
+
+	state := &sectionState{meeting: -1}
+	for i, line := range lines {
+		n := offset + i
+		if strings.HasPrefix(line, "## ") {
+			state = &sectionState{section: strings.TrimSpace(strings.Replace(line, "## ", "", 1)), meeting: -1}
+			if state.section == SectionBasicInfo && project.basicInfoLine < 0 {
+				project.basicInfoLine = n
+			}
+			continue
+		}
+
+		switch {
+		case state.section == SectionBasicInfo:
+			parseBasicInfo(line, n, &project, state)
+		case strings.Contains(state.section, SectionMeetings):
+			parseMeeting(line, n, &project, state)
+		case state.section == SectionNotes:
+			parseNote(line, n, &project)
+		}
+	}
+
+	return project, true
+}
+
+// trimSection drops leading and trailing blank lines and the surrounding
+// whitespace of the section, as String.prototype.trim does on the frontend
+func trimSection(lines []string, offset int) ([]string, int) {
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+		offset++
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 0 {
+		return []string{""}, offset
+	}
+
+	lines = append([]string(nil), lines...)
+	lines[0] = strings.TrimLeft(lines[0], " \t\r\f\v")
+	lines[len(lines)-1] = strings.TrimRight(lines[len(lines)-1], " \t\r\f\v")
+	return lines, offset
+}
+
+// parseBasicInfo handles a line of the 基本資訊 section
+func parseBasicInfo(line string, n int, project *Project, state *sectionState) {
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case statusRe.MatchString(trimmed):
+		project.Status = fieldValue(statusRe, trimmed)
+		project.fields[FieldStatus] = n
+	case stateRe.MatchString(trimmed):
+		project.CurrentState = fieldValue(stateRe, trimmed)
+		project.fields[FieldCurrentState] = n
+	case progressRe.MatchString(trimmed):
+		project.Progress = ParseProgress(fieldValue(progressRe, trimmed))
+		project.fields[FieldProgress] = n
+	case contactRe.MatchString(trimmed):
+		project.Contact = fieldValue(contactRe, trimmed)
+		project.fields[FieldContact] = n
+	case categoryRe.MatchString(trimmed):
+		project.Category = fieldValue(categoryRe, trimmed)
+		project.fields[FieldCategory] = n
+	case timelineRe.MatchString(trimmed):
+		project.timelineLine = n
+		state.inTimeline = true
+		state.inDepartments = false
+	case deptsRe.MatchString(trimmed):
+		state.inTimeline = false
+		state.inDepartments = true
+	case leadRe.MatchString(trimmed):
+		if value := fieldValue(leadRe, trimmed); value != "" {
+			project.Departments.Lead = splitList(value)
+		}
+		state.departmentType = "承辦"
+		state.inDepartments = true
+	case supportRe.MatchString(trimmed):
+		if value := fieldValue(supportKeyRe, trimmed); value != "" {
+			project.Departments.Support = splitList(value)
+		}
+		state.departmentType = "協辦"
+		state.inDepartments = true
+	case state.inTimeline:
+		if m := phaseRe.FindStringSubmatch(trimmed); m != nil {
+			project.Phases = append(project.Phases, newPhase(m, n))
+		}
+	default:
+		m := indentItemRe.FindStringSubmatch(line)
+		if m == nil || !state.inDepartments {
+			return
+		}
+		switch state.departmentType {
+		case "承辦":
+			project.Departments.Lead = append(project.Departments.Lead, strings.TrimSpace(m[1]))
+		case "協辦":
+			project.Departments.Support = append(project.Departments.Support, strings.TrimSpace(m[1]))
+		}
+	}
+}
+
+// parseMeeting handles a line of the 會辦狀況 section
+func parseMeeting(line string, n int, project *Project, state *sectionState) {
+	trimmed := strings.TrimSpace(line)
+
+	if m := meetingRe.FindStringSubmatch(trimmed); m != nil {
+		entry := MeetingEntry{Date: m[1], Lines: []MeetingLine{}, IsOld: IsOverOneMonth(m[1], time.Now()), Line: n}
+		if inline := strings.TrimSpace(m[2]); inline != "" {
+			entry.Lines = append(entry.Lines, newMeetingLine(inline, n))
+		}
+		project.Meetings = append(project.Meetings, entry)
+		state.meeting = len(project.Meetings) - 1
+		return
+	}
+
+	if state.meeting >= 0 && indentedRe.MatchString(line) {
+		if text := strings.TrimSpace(bulletRe.ReplaceAllString(trimmed, "")); text != "" {
+			entry := &project.Meetings[state.meeting]
+			entry.Lines = append(entry.Lines, newMeetingLine(text, n))
+		}
+	}
+}
+
+// parseNote handles a line of the 其他補充 section
+func parseNote(line string, n int, project *Project) {
+	if noteRe.MatchString(line) && utf8.RuneCountInString(line) > 2 {
+		text := strings.TrimSpace(noteRe.ReplaceAllString(line, ""))
+		project.Notes = append(project.Notes, NoteLine{
+			Text:       text,
+			IsTracking: strings.HasPrefix(text, MarkerTracking),
+			IsPlanned:  strings.HasPrefix(text, MarkerPlanned),
+			Line:       n,
+		})
+		return
+	}
+
+	if noteChildRe.MatchString(line) && len(project.Notes) > 0 {
+		text := strings.TrimSpace(noteRe.ReplaceAllString(strings.TrimSpace(line), ""))
+		if text == "" {
+			return
+		}
+		parent := &project.Notes[len(project.Notes)-1]
+		parent.Children = append(parent.Children, NoteLine{
+			Text:       text,
+			IsTracking: strings.HasPrefix(text, MarkerTracking),
+			IsPlanned:  strings.HasPrefix(text, MarkerPlanned),
+			Line:       n,
+		})
+	}
+}
+
+// fieldValue returns the trimmed text after a field prefix
+func fieldValue(re *regexp.Regexp, line string) string {
+	return strings.TrimSpace(re.ReplaceAllString(line, ""))
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(value string) []string {
+	items := []string{}
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			items = append(items, s)
+		}
+	}
+	return items
+}
+
+// newPhase builds a phase from a phase regexp match; the end date defaults to the start
+func newPhase(m []string, n int) Phase {
+	end := m[3]
+	if end == "" {
+		end = m[2]
+	}
+	return Phase{Name: strings.TrimSpace(m[1]), StartDate: m[2], EndDate: end, Line: n}
+}
+
+// newMeetingLine builds a meeting line, detecting its marker
+func newMeetingLine(text string, n int) MeetingLine {
+	return MeetingLine{
+		Text:       text,
+		IsTracking: strings.HasPrefix(text, MarkerTracking),
+		IsPlanned:  strings.HasPrefix(text, MarkerPlanned),
+		Line:       n,
+	}
+}
+
+// ParseProgress reads a 進度 value like "50%" the way parseInt does, returning 0 if there is no number
+func ParseProgress(value string) int {
+	value = strings.TrimSpace(strings.Replace(value, "%", "", 1))
+	progress, err := strconv.Atoi(leadingIntRe.FindString(value))
+	if err != nil {
+		return 0
+	}
+	return progress
+}
+
+// IsOverOneMonth reports whether a YYYY-MM-DD date is more than a month before now
+func IsOverOneMonth(date string, now time.Time) bool {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	oneMonthAgo := time.Date(now.Year(), now.Month()-1, now.Day(), 0, 0, 0, 0, now.Location())
+	return d.Before(oneMonthAgo)
+}
@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// readFixture returns the contents of a file in testdata
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseSample(t *testing.T) {
+	projects := Parse(readFixture(t, "sample.md"))
+	if len(projects) != 1 {
+		t.Fatalf("got %d projects, want 1", len(projects))
+	}
+	p := projects[0]
+
+	if p.Name != "AP 2.0 KM" || p.Slug != "ap-2-0-km" {
+		t.Errorf("name = %q slug = %q", p.Name, p.Slug)
+	}
+	if p.Status != StatusGreen || p.CurrentState != "提案準備中" || p.Progress != 50 || p.Contact != "Andy" || p.Category != "AP" {
+		t.Errorf("fields = %q %q %d %q %q", p.Status, p.CurrentState, p.Progress, p.Contact, p.Category)
+	}
+	if p.LineNumber != 0 || p.NameLine != 0 || p.EndLine != 31 {
+		t.Errorf("lines = %d %d %d, want 0 0 31", p.LineNumber, p.NameLine, p.EndLine)
+	}
+
+	wantPhases := []Phase{
+		{Name: "開發", StartDate: "2025-12-20", EndDate: "2025-12-25", Line: 8},
+		{Name: "SIT", StartDate: "2025-12-26", EndDate: "2025-12-27", Line: 9},
+		{Name: "QAS", StartDate: "2025-12-28", EndDate: "2025-12-29", Line: 10},
+		{Name: "REG", StartDate: "2025-12-30", EndDate: "2025-12-31", Line: 11},
+		{Name: "PROD", StartDate: "2026-01-01", EndDate: "2026-01-02", Line: 12},
+	}
+	if !reflect.DeepEqual(p.Phases, wantPhases) {
+		t.Errorf("phases = %+v", p.Phases)
+	}
+
+	if !reflect.DeepEqual(p.Departments.Lead, []string{"AAAA"}) || !reflect.DeepEqual(p.Departments.Support, []string{"BBBB"}) {
+		t.Errorf("departments = %+v", p.Departments)
+	}
+
+	if len(p.Meetings) != 3 {
+		t.Fatalf("got %d meetings, want 3", len(p.Meetings))
+	}
+	first := p.Meetings[0]
+	if first.Date != "2025-12-20" || first.Line != 20 || !first.IsOld {
+		t.Errorf("first meeting = %+v", first)
+	}
+	wantLines := []MeetingLine{
+		{Text: "_待追蹤_ 需要確認規格文件", IsTracking: true, Line: 21},
+		{Text: "完成初步設計評審", Line: 22},
+	}
+	if !reflect.DeepEqual(first.Lines, wantLines) {
+		t.Errorf("first meeting lines = %+v", first.Lines)
+	}
+	if planned := p.Meetings[1].Lines[0]; !planned.IsPlanned || planned.Line != 24 {
+		t.Errorf("planned line = %+v", planned)
+	}
+
+	wantNotes := []NoteLine{{Text: "專案進度正常", Line: 31}}
+	if !reflect.DeepEqual(p.Notes, wantNotes) {
+		t.Errorf("notes = %+v", p.Notes)
+	}
+}
+
+// TestParseGolden parses every testdata/*.md and compares the projects with
+// the matching .golden.json; run with -update after a deliberate change.
+// Dates in the fixtures are long past or far ahead so IsOld does not drift.
+func TestParseGolden(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".md")
+		t.Run(name, func(t *testing.T) {
+			got, err := json.MarshalIndent(Parse(readFixture(t, name+".md")), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", name+".golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("Parse(%s) differs from %s:\n%s", file, golden, got)
+			}
+		})
+	}
+}
+
+func TestParseCRLF(t *testing.T) {
+	for _, name := range []string{"sample.md", "workspace.md"} {
+		lf := readFixture(t, name)
+		want, err := json.Marshal(Parse(lf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := json.Marshal(Parse(strings.ReplaceAll(lf, "\n", "\r\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s with CRLF =\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	tests := []struct {
+		value string
+		want  int
+	}{
+		{"50%", 50},
+		{" 75 % ", 75},
+		{"100", 100},
+		{"30%完成", 30},
+		{"-5%", -5},
+		{"過半", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := ParseProgress(tt.value); got != tt.want {
+			t.Errorf("ParseProgress(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestIsOverOneMonth(t *testing.T) {
+	now := time.Date(2026, 3, 31, 15, 0, 0, 0, time.UTC)
+	tests := []struct {
+		date string
+		want bool
+	}{
+		{"2026-02-28", true},
+		{"2026-03-01", true}, // one month before 03-31 normalizes to 03-03
+		{"2026-03-03", false},
+		{"2026-03-30", false},
+		{"2026-04-15", false},
+		{"not a date", false},
+	}
+	for _, tt := range tests {
+		if got := IsOverOneMonth(tt.date, now); got != tt.want {
+			t.Errorf("IsOverOneMonth(%q) = %v, want %v", tt.date, got, tt.want)
+		}
+	}
+}
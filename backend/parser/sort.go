@@ -0,0 +1,92 @@
+package parser
+
+import "sort"
+
+// noDate sorts projects without any phase dates last
+const noDate = "9999-99-99"
+
+// statusOrder ranks 燈號 for sorting; a missing or unknown status counts as green
+var statusOrder = map[string]int{StatusRed: 0, StatusYellow: 1, StatusGreen: 2}
+
+// SortProjects returns the projects in slide order: red, yellow, then green,
+// grouped by 窗口, and then by go-live date
+func SortProjects(projects []Project) []Project {
+	sorted := append([]Project(nil), projects...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if sa, sb := rank(a.Status), rank(b.Status); sa != sb {
+			return sa < sb
+		}
+		if a.Contact != b.Contact {
+			return a.Contact < b.Contact
+		}
+		return sortDate(a.Phases) < sortDate(b.Phases)
+	})
+	return sorted
+}
+
+// rank returns the sort rank of a status
+func rank(status string) int {
+	if r, ok := statusOrder[status]; ok {
+		return r
+	}
+	return statusOrder[StatusGreen]
+}
+
+// GoLiveDate returns the end date of the PROD phase (a phase named PROD, 上線
+// or 正式), or the latest phase end date if there is none. It returns an empty
+// string when no phase has dates.
+func GoLiveDate(phases []Phase) string {
+	for _, p := range phases {
+		if IsGoLivePhase(p.Name) {
+			return p.EndDate
+		}
+	}
+
+	latest := ""
+	for _, p := range phases {
+		if p.EndDate > latest {
+			latest = p.EndDate
+		}
+	}
+	return latest
+}
+
+// sortDate is the go-live date used for ordering projects
+func sortDate(phases []Phase) string {
+	if date := GoLiveDate(phases); date != "" {
+		return date
+	}
+	return noDate
+}
+
+// Slide types
+const (
+	SlideSummary = "summary"
+	SlideProject = "project"
+)
+
+// SummaryPageSize is the number of projects on each summary slide
+const SummaryPageSize = 10
+
+// Slide is one page of a presentation: a summary table of projects or the details of one project
+type Slide struct {
+	Type     string    `json:"type"`
+	Projects []Project `json:"projects,omitempty"`
+	Project  *Project  `json:"project,omitempty"`
+}
+
+// GenerateSlides sorts the projects and lays them out as summary slides
+// followed by one slide per project
+func GenerateSlides(projects []Project) []Slide {
+	sorted := SortProjects(projects)
+	slides := []Slide{}
+
+	for i := 0; i < len(sorted); i += SummaryPageSize {
+		slides = append(slides, Slide{Type: SlideSummary, Projects: sorted[i:min(i+SummaryPageSize, len(sorted))]})
+	}
+	for i := range sorted {
+		slides = append(slides, Slide{Type: SlideProject, Project: &sorted[i]})
+	}
+	return slides
+}
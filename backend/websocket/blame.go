@@ -0,0 +1,178 @@
+package websocket
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/diff"
+)
+
+// BlameTracker keeps the last author of every line of the markdown documents.
+// Lines are attributed as edits are applied and saved to the workspace_blame
+// table by Flush.
+type BlameTracker struct {
+	docs map[string]*blameDoc
+	mu   sync.Mutex
+}
+
+// blameDoc is the tracked state of one workspace's markdown
+type blameDoc struct {
+	lines   []database.BlameLine
+	content string // what lines were last brought in line with
+	dirty   bool
+}
+
+// NewBlameTracker creates an empty blame tracker
+func NewBlameTracker() *BlameTracker {
+	return &BlameTracker{docs: make(map[string]*blameDoc)}
+}
+
+// Record attributes the lines changed from previous to content to author
+func (t *BlameTracker) Record(workspaceID, previous, content string, author Author, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	doc := t.load(workspaceID, previous)
+	at = at.UTC()
+	doc.lines = attributeLines(doc.lines, content, database.BlameLine{
+		AuthorID:   author.ID,
+		AuthorName: author.Name,
+		UpdatedAt:  &at,
+	})
+	doc.content = content
+	doc.dirty = true
+}
+
+// Lines returns the lines of a workspace's current markdown with their authors
+func (t *BlameTracker) Lines(workspaceID, content string) []database.BlameLine {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	doc := t.load(workspaceID, content)
+	return append([]database.BlameLine{}, doc.lines...)
+}
+
+// Flush saves the documents changed since the last flush
+func (t *BlameTracker) Flush() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for workspaceID, doc := range t.docs {
+		if !doc.dirty {
+			continue
+		}
+		if err := database.SaveBlame(workspaceID, doc.lines); err != nil {
+			log.Printf("Error saving blame for workspace %s: %v", workspaceID, err)
+			continue
+		}
+		doc.dirty = false
+	}
+}
+
+// Forget drops a workspace, such as a deleted one, so it is not saved again
+func (t *BlameTracker) Forget(workspaceID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.docs, workspaceID)
+}
+
+// load returns the tracked lines of a workspace, loading them from the
+// database on first use, and brings them in line with content. Lines
+// changed without being recorded, such as before an unsaved restart, lose
+// their author. Caller must hold t.mu.
+func (t *BlameTracker) load(workspaceID, content string) *blameDoc {
+	doc, ok := t.docs[workspaceID]
+	if !ok {
+		lines, err := database.GetBlame(workspaceID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error loading blame for workspace %s: %v", workspaceID, err)
+		}
+		doc = &blameDoc{lines: lines, content: blameText(lines)}
+		t.docs[workspaceID] = doc
+	}
+
+	// Usually content is what the last Record left, and this is cheap
+	if doc.content != content {
+		doc.lines = attributeLines(doc.lines, content, database.BlameLine{})
+		doc.content = content
+		doc.dirty = true
+	}
+	return doc
+}
+
+// attributeLines returns the lines of content, keeping the authors of lines
+// unchanged from lines and giving the rest author's. Only the lines between
+// the common prefix and suffix are diffed, so a keystroke costs a scan of
+// the document rather than a full diff.
+func attributeLines(lines []database.BlameLine, content string, author database.BlameLine) []database.BlameLine {
+	texts := contentLines(content)
+	prefix := 0
+	for prefix < len(lines) && prefix < len(texts) && lines[prefix].Text == texts[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(lines)-prefix && suffix < len(texts)-prefix && lines[len(lines)-1-suffix].Text == texts[len(texts)-1-suffix] {
+		suffix++
+	}
+	old := lines[prefix : len(lines)-suffix]
+	changed := texts[prefix : len(texts)-suffix]
+
+	result := make([]database.BlameLine, 0, len(texts))
+	result = append(result, lines[:prefix]...)
+	if len(old) == 0 || len(changed) == 0 {
+		for _, text := range changed {
+			line := author
+			line.Text = text
+			result = append(result, line)
+		}
+	} else {
+		for _, l := range diff.Lines(blameText(old), joinLines(changed)) {
+			switch l.Kind {
+			case diff.KindContext:
+				line := old[l.OldLine-1]
+				line.Text = l.Text
+				result = append(result, line)
+			case diff.KindAdd:
+				line := author
+				line.Text = l.Text
+				result = append(result, line)
+			}
+		}
+	}
+	return append(result, lines[len(lines)-suffix:]...)
+}
+
+// contentLines splits text into lines; a final newline does not start
+// another line, so that "a\n" and "a" are both one line
+func contentLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// joinLines joins lines into text, ending in a newline so that empty lines
+// at the end survive splitting it again
+func joinLines(texts []string) string {
+	var sb strings.Builder
+	for _, text := range texts {
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// blameText joins tracked lines back into text, as joinLines does
+func blameText(lines []database.BlameLine) string {
+	var sb strings.Builder
+	for _, l := range lines {
+		sb.WriteString(l.Text)
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
@@ -0,0 +1,205 @@
+package websocket
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+)
+
+var (
+	amy = Author{ID: "u1", Name: "amy"}
+	bob = Author{ID: "u2", Name: "bob"}
+)
+
+// authors returns the text and author name of each line, "" for unknown
+func authors(lines []database.BlameLine) [][2]string {
+	result := [][2]string{}
+	for _, l := range lines {
+		result = append(result, [2]string{l.Text, l.AuthorName})
+	}
+	return result
+}
+
+// newTestTracker returns a tracker that already tracks content by author,
+// so it does not read the database
+func newTestTracker(workspaceID, content string, author Author) *BlameTracker {
+	t := NewBlameTracker()
+	t.docs[workspaceID] = &blameDoc{}
+	t.Record(workspaceID, "", content, author, time.Now())
+	return t
+}
+
+func TestAttributeLines(t *testing.T) {
+	at := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	later := at.Add(time.Hour)
+	old := []database.BlameLine{
+		{Text: "# 專案", AuthorID: "u1", AuthorName: "amy", UpdatedAt: &at},
+		{Text: "- 燈號: 綠", AuthorID: "u1", AuthorName: "amy", UpdatedAt: &at},
+		{Text: "- 進度: 40%", AuthorID: "u1", AuthorName: "amy", UpdatedAt: &at},
+	}
+	by := database.BlameLine{AuthorID: "u2", AuthorName: "bob", UpdatedAt: &later}
+
+	got := attributeLines(old, "# 專案\n- 燈號: 黃\n- 進度: 40%\n- 窗口: Andy\n", by)
+	want := []database.BlameLine{
+		old[0],
+		{Text: "- 燈號: 黃", AuthorID: "u2", AuthorName: "bob", UpdatedAt: &later},
+		old[2],
+		{Text: "- 窗口: Andy", AuthorID: "u2", AuthorName: "bob", UpdatedAt: &later},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("attributeLines =\n%+v\nwant\n%+v", got, want)
+	}
+
+	// deleted lines are dropped without touching the others
+	got = attributeLines(old, "# 專案\n- 進度: 40%\n", by)
+	if !reflect.DeepEqual(got, []database.BlameLine{old[0], old[2]}) {
+		t.Errorf("after deleting a line = %+v", got)
+	}
+}
+
+func TestBlameRecord(t *testing.T) {
+	tests := []struct {
+		name string
+		edit string // bob's edit of amy's "a\nb\nc\n"
+		want [][2]string
+	}{
+		{
+			name: "unchanged lines keep their author",
+			edit: "a\nB\nc\n",
+			want: [][2]string{{"a", "amy"}, {"B", "bob"}, {"c", "amy"}},
+		},
+		{
+			name: "inserted line",
+			edit: "a\nb\nnew\nc\n",
+			want: [][2]string{{"a", "amy"}, {"b", "amy"}, {"new", "bob"}, {"c", "amy"}},
+		},
+		{
+			name: "trailing empty line",
+			edit: "a\nb\nc\n\n",
+			want: [][2]string{{"a", "amy"}, {"b", "amy"}, {"c", "amy"}, {"", "bob"}},
+		},
+		{
+			name: "final newline removed",
+			edit: "a\nb\nc",
+			want: [][2]string{{"a", "amy"}, {"b", "amy"}, {"c", "amy"}},
+		},
+		{
+			name: "everything deleted",
+			edit: "",
+			want: [][2]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newTestTracker("w1", "a\nb\nc\n", amy)
+			tracker.Record("w1", "a\nb\nc\n", tt.edit, bob, time.Now())
+			if got := authors(tracker.Lines("w1", tt.edit)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlameKeepsAnAuthorThroughSeveralEdits(t *testing.T) {
+	tracker := newTestTracker("w1", "a\nb\n", amy)
+	tracker.Record("w1", "a\nb\n", "a\nb\nc\n", bob, time.Now())
+	tracker.Record("w1", "a\nb\nc\n", "a\nB\nc\n", amy, time.Now())
+
+	want := [][2]string{{"a", "amy"}, {"B", "amy"}, {"c", "bob"}}
+	if got := authors(tracker.Lines("w1", "a\nB\nc\n")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+}
+
+func TestBlameLoadResyncs(t *testing.T) {
+	tracker := newTestTracker("w1", "a\nb\nc\n", amy)
+
+	// a change that was never recorded, such as one lost in a restart,
+	// is attributed to nobody
+	want := [][2]string{{"a", "amy"}, {"x", ""}, {"c", "amy"}, {"d", ""}}
+	if got := authors(tracker.Lines("w1", "a\nx\nc\nd")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines = %q, want %q", got, want)
+	}
+	if !tracker.docs["w1"].dirty {
+		t.Error("resynced document is not marked dirty")
+	}
+
+	// recording against the resynced lines attributes from there on
+	tracker.Record("w1", "a\nx\nc\nd", "a\nx\nC\nd", bob, time.Now())
+	want = [][2]string{{"a", "amy"}, {"x", ""}, {"C", "bob"}, {"d", ""}}
+	if got := authors(tracker.Lines("w1", "a\nx\nC\nd")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines after Record = %q, want %q", got, want)
+	}
+}
+
+func TestBlameFlush(t *testing.T) {
+	if err := database.Init(filepath.Join(t.TempDir(), "sheltie.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+	if err := database.CreateWorkspace(&database.Workspace{ID: "w1", Name: "w1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := NewBlameTracker()
+	tracker.Record("w1", "", "a\nb\n", amy, time.Now())
+	tracker.Record("w1", "a\nb\n", "a\nB\n", bob, time.Now())
+	tracker.Flush()
+	if tracker.docs["w1"].dirty {
+		t.Error("document is still dirty after Flush")
+	}
+
+	// a new tracker, as after a restart, loads the saved authors
+	want := [][2]string{{"a", "amy"}, {"B", "bob"}}
+	if got := authors(NewBlameTracker().Lines("w1", "a\nB\n")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lines after reload = %q, want %q", got, want)
+	}
+}
+
+func TestBlameFlushAfterDelete(t *testing.T) {
+	initTestDB(t)
+	if err := database.CreateWorkspace(&database.Workspace{ID: "w1", Name: "w1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker := NewBlameTracker()
+	tracker.Record("w1", "", "a\n", amy, time.Now())
+	if err := database.DeleteWorkspace("w1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// a tick racing the delete must not bring the rows back
+	tracker.Flush()
+	if _, err := database.GetBlame("w1"); err == nil {
+		t.Error("blame of a deleted workspace was saved")
+	}
+
+	tracker.Record("w1", "a\n", "a\nb\n", amy, time.Now())
+	tracker.Forget("w1")
+	if _, ok := tracker.docs["w1"]; ok {
+		t.Error("forgotten workspace is still tracked")
+	}
+}
+
+func TestAttributeLinesLargeDocument(t *testing.T) {
+	var sb strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	content := sb.String()
+	tracker := newTestTracker("w1", content, amy)
+
+	// one keystroke in the middle of a large document
+	edited := strings.Replace(content, "line 10000\n", "line 10000x\n", 1)
+	tracker.Record("w1", content, edited, bob, time.Now())
+
+	lines := tracker.Lines("w1", edited)
+	if len(lines) != 20000 || lines[10000].AuthorName != "bob" || lines[9999].AuthorName != "amy" || lines[10001].AuthorName != "amy" {
+		t.Errorf("lines around the edit = %+v", authors(lines[9999:10002]))
+	}
+}
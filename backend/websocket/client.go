@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
 
@@ -22,10 +23,11 @@ const (
 	maxMessageSize = 1024 * 1024 // 1MB
 )
 
-// NewClient creates a new client
-func NewClient(conn *websocket.Conn, hub *Hub, workspaceID, userId, username string) *Client {
+// NewClient creates a new client for a user, with a new connection ID
+func NewClient(conn *websocket.Conn, hub *Hub, workspaceID, userID, username string) *Client {
 	return &Client{
-		ID:          userId,
+		ID:          uuid.New().String(),
+		UserID:      userID,
 		Username:    username,
 		WorkspaceID: workspaceID,
 		Conn:        conn,
@@ -63,9 +65,25 @@ func (c *Client) ReadPump() {
 			continue
 		}
 
+		// Presence, lint and alerts come from the server only
+		switch msg.Type {
+		case MessageTypeContent, MessageTypeOperation:
+			if c.ReadOnly {
+				log.Printf("Dropping edit from read-only client %s", c.ID)
+				continue
+			}
+		case MessageTypeCursor:
+		default:
+			log.Printf("Dropping %q message from client %s", msg.Type, c.ID)
+			continue
+		}
+
+		// Identity comes from the connection, never from the message
 		msg.WorkspaceID = c.WorkspaceID
-		msg.UserID = c.ID
+		msg.ConnectionID = c.ID
+		msg.UserID = c.UserID
 		msg.Username = c.Username
+		msg.IP = c.IP
 
 		c.Hub.Broadcast <- &msg
 	}
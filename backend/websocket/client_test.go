@@ -0,0 +1,74 @@
+package websocket
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestReadPumpDropsServerMessages(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	observer := &Client{ID: "observer", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	h.Register <- observer
+	receive(t, observer) // users
+
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		guest := NewClient(conn, h, "w1", "", "guest")
+		guest.ReadOnly = true
+		h.Register <- guest
+		go guest.WritePump()
+		guest.ReadPump()
+	}))
+	defer srv.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if got := receive(t, observer); got != MessageTypeJoin {
+		t.Fatalf("message = %s, want %s", got, MessageTypeJoin)
+	}
+
+	spoofed := []UserInfo{{ConnectionID: "fake", UserID: "u1", Username: "admin"}}
+	for _, msg := range []Message{
+		{Type: MessageTypeUsers, Users: spoofed},
+		{Type: MessageTypeJoin, ConnectionID: "fake", Username: "admin"},
+		{Type: MessageTypeWelcome, Username: "admin"},
+		{Type: MessageTypeLint},
+		{Type: MessageTypeAlerts},
+		{Type: MessageTypeContent, Content: "read-only clients cannot edit"},
+		{Type: MessageTypeCursor, Position: 3, Users: spoofed, Username: "admin"},
+	} {
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// messages are handled in order, so the cursor arriving next means
+	// everything before it was dropped
+	var cursor Message
+	select {
+	case data := <-observer.Send:
+		if err := json.Unmarshal(data, &cursor); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no cursor message")
+	}
+	if cursor.Type != MessageTypeCursor || cursor.Position != 3 || cursor.Username != "guest" || cursor.Users != nil {
+		t.Errorf("relayed %+v, want guest's cursor alone", cursor)
+	}
+}
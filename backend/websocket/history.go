@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+)
+
+const (
+	// Default minimum time between two snapshots of the same workspace.
+	defaultSnapshotInterval = 10 * time.Minute
+
+	// Changes touching at least this many bytes are snapshotted regardless of interval.
+	snapshotMinChange = 500
+)
+
+// Author identifies who made a change to a document
+type Author struct {
+	ID   string
+	Name string
+	IP   string // Address the change came from, for the audit log
+}
+
+// SnapshotRecorder writes throttled content snapshots to the workspace_versions table
+type SnapshotRecorder struct {
+	Interval time.Duration
+
+	last map[documentKey]snapshotState
+	mu   sync.Mutex
+}
+
+// snapshotState remembers the last snapshot taken for a workspace
+type snapshotState struct {
+	content string
+	at      time.Time
+}
+
+// NewSnapshotRecorder creates a snapshot recorder with the default interval
+func NewSnapshotRecorder() *SnapshotRecorder {
+	return &SnapshotRecorder{
+		Interval: defaultSnapshotInterval,
+		last:     make(map[documentKey]snapshotState),
+	}
+}
+
+// Record stores a snapshot if enough time has passed or the change is significant
+func (r *SnapshotRecorder) Record(workspaceID, channel, content string, version int64, author Author) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := documentKey{workspaceID, channel}
+	state, ok := r.lastSnapshot(key)
+	if ok {
+		if state.content == content {
+			return
+		}
+		if time.Since(state.at) < r.Interval && changedBytes(state.content, content) < snapshotMinChange {
+			return
+		}
+	}
+
+	r.save(key, content, version, author)
+}
+
+// Force stores a snapshot unless the content is identical to the last one
+func (r *SnapshotRecorder) Force(workspaceID, channel, content string, version int64, author Author) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := documentKey{workspaceID, channel}
+	if state, ok := r.lastSnapshot(key); ok && state.content == content {
+		return
+	}
+
+	r.save(key, content, version, author)
+}
+
+// Forget drops the cached last snapshots of a workspace
+func (r *SnapshotRecorder) Forget(workspaceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key := range r.last {
+		if key.workspaceID == workspaceID {
+			delete(r.last, key)
+		}
+	}
+}
+
+// save writes a snapshot to the database; caller must hold r.mu
+func (r *SnapshotRecorder) save(key documentKey, content string, version int64, author Author) {
+	v := &database.WorkspaceVersion{
+		WorkspaceID: key.workspaceID,
+		Channel:     key.channel,
+		Content:     content,
+		Version:     version,
+		AuthorID:    author.ID,
+		AuthorName:  author.Name,
+	}
+	if err := database.CreateWorkspaceVersion(v); err != nil {
+		log.Printf("Error recording %s snapshot for workspace %s: %v", key.channel, key.workspaceID, err)
+		return
+	}
+	r.last[key] = snapshotState{content: content, at: v.CreatedAt}
+}
+
+// lastSnapshot returns the cached last snapshot, loading it from the database on first use
+func (r *SnapshotRecorder) lastSnapshot(key documentKey) (snapshotState, bool) {
+	if state, ok := r.last[key]; ok {
+		return state, true
+	}
+	latest, err := database.GetLatestWorkspaceVersion(key.workspaceID, key.channel)
+	if err != nil {
+		return snapshotState{}, false
+	}
+	state := snapshotState{content: latest.Content, at: latest.CreatedAt}
+	r.last[key] = state
+	return state, true
+}
+
+// changedBytes estimates how many bytes differ between two strings
+// by trimming their common prefix and suffix
+func changedBytes(a, b string) int {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return max(len(a)-prefix-suffix, len(b)-prefix-suffix)
+}
@@ -1,13 +1,17 @@
 package websocket
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
 )
 
 // Message types
@@ -19,40 +23,74 @@ const (
 	MessageTypeLeave     = "leave"
 	MessageTypeCursor    = "cursor"
 	MessageTypeUsers     = "users"
+	MessageTypeLint      = "lint"
+	MessageTypeAlerts    = "alerts"
+	MessageTypeWelcome   = "welcome" // Tells a new connection its ID and identity
 )
 
 // Message represents a WebSocket message
 type Message struct {
-	Type           string     `json:"type"`
-	Content        string     `json:"content,omitempty"`
-	WorkspaceID    string     `json:"workspaceId,omitempty"`
-	UserID         string     `json:"userId,omitempty"`
-	Username       string     `json:"username,omitempty"`
-	Position       int        `json:"position,omitempty"`
-	SelectionStart int        `json:"selectionStart,omitempty"`
-	SelectionEnd   int        `json:"selectionEnd,omitempty"`
-	Users          []UserInfo `json:"users,omitempty"`
-	Version        int64      `json:"version,omitempty"`
-	Hash           string     `json:"hash,omitempty"`
-	Conflict       bool       `json:"conflict,omitempty"`
+	Type           string              `json:"type"`
+	Channel        string              `json:"channel,omitempty"` // Document channel; empty means markdown
+	Content        string              `json:"content,omitempty"`
+	WorkspaceID    string              `json:"workspaceId,omitempty"`
+	ConnectionID   string              `json:"connectionId,omitempty"` // Connection the message came from
+	UserID         string              `json:"userId,omitempty"`
+	Username       string              `json:"username,omitempty"`
+	Position       int                 `json:"position,omitempty"`
+	SelectionStart int                 `json:"selectionStart,omitempty"`
+	SelectionEnd   int                 `json:"selectionEnd,omitempty"`
+	Users          []UserInfo          `json:"users,omitempty"`
+	Version        int64               `json:"version,omitempty"`
+	Hash           string              `json:"hash,omitempty"`
+	Conflict       bool                `json:"conflict,omitempty"`
+	Ops            []Operation         `json:"ops,omitempty"`
+	Diagnostics    []parser.Diagnostic `json:"diagnostics,omitempty"`
+	Alerts         []parser.Alert      `json:"alerts,omitempty"`
+	IP             string              `json:"-"` // Address of the connection, for the audit log
 }
 
 // UserInfo represents basic user data for list
 type UserInfo struct {
+	ConnectionID   string `json:"connectionId"`
 	UserID         string `json:"userId"`
 	Username       string `json:"username"`
 	CursorPosition *int   `json:"cursorPosition,omitempty"`
 }
 
-// Client represents a connected WebSocket client
+// Client represents a connected WebSocket client. ID is unique per
+// connection; one user may have several connections.
 type Client struct {
 	ID             string
+	UserID         string
 	Username       string
 	WorkspaceID    string
 	Conn           *websocket.Conn
 	Hub            *Hub
 	Send           chan []byte
 	CursorPosition *int
+	ReadOnly       bool   // Edits from the client are dropped
+	IP             string // Address the client connected from
+	ShareLinkID    string // Share link the client connected with, if any
+}
+
+// directMessage is a batch of messages queued for one client
+type directMessage struct {
+	client   *Client
+	messages []*Message
+}
+
+// roomMessage is a message queued for every client in a room
+type roomMessage struct {
+	workspaceID string
+	message     *Message
+}
+
+// disconnectRequest asks Run to disconnect the clients of a room that match
+type disconnectRequest struct {
+	workspaceID string             // "" for every room
+	match       func(*Client) bool // nil matches every client
+	done        chan struct{}
 }
 
 // Hub maintains active clients and broadcasts messages
@@ -69,15 +107,36 @@ type Hub struct {
 	// Unregister requests
 	Unregister chan *Client
 
+	// Messages for a single client, see SendTo
+	direct chan directMessage
+
+	// Messages from outside the hub loop for a whole room, see queueBroadcast.
+	// Only Run sends on or closes a client's Send channel.
+	outbound chan roomMessage
+
+	// Requests to disconnect clients, see Disconnect
+	disconnect chan disconnectRequest
+
 	// Mutex for room operations
 	mu sync.RWMutex
 
-	// Last content per workspace for auto-save
-	lastContent      map[string]string
-	lastContentTime  map[string]time.Time
+	// Last content per workspace document for auto-save
+	lastContent      map[documentKey]string
+	lastContentTime  map[documentKey]time.Time
+	lastAuthor       map[documentKey]Author
+	editors          map[documentKey]map[string]Author // Authors since the last audit, by ID
 	contentMu        sync.RWMutex
 	AutoSaveInterval time.Duration
 
+	// Pending lint runs per markdown document, delayed until edits pause
+	lintTimers map[documentKey]*time.Timer
+	lintMu     sync.Mutex
+	LintDelay  time.Duration
+
+	// EditsPaused, if set, is called with a workspace's markdown after it is
+	// linted, to refresh other results derived from it; set it before Run
+	EditsPaused func(workspaceID, content string)
+
 	// Version manager
 	versionManager *VersionManager
 }
@@ -89,9 +148,16 @@ func NewHub() *Hub {
 		Broadcast:        make(chan *Message, 256),
 		Register:         make(chan *Client),
 		Unregister:       make(chan *Client),
-		lastContent:      make(map[string]string),
-		lastContentTime:  make(map[string]time.Time),
+		direct:           make(chan directMessage, 256),
+		outbound:         make(chan roomMessage, 256),
+		disconnect:       make(chan disconnectRequest),
+		lastContent:      make(map[documentKey]string),
+		lastContentTime:  make(map[documentKey]time.Time),
+		lastAuthor:       make(map[documentKey]Author),
+		editors:          make(map[documentKey]map[string]Author),
 		AutoSaveInterval: 60 * time.Second, // Default 60 seconds
+		lintTimers:       make(map[documentKey]*time.Timer),
+		LintDelay:        time.Second,
 		versionManager:   NewVersionManager(),
 	}
 	return hub
@@ -118,12 +184,13 @@ func (h *Hub) Run() {
 
 			// Notify others about new user
 			h.broadcastToRoom(client.WorkspaceID, &Message{
-				Type:     MessageTypeJoin,
-				UserID:   client.ID,
-				Username: client.Username,
+				Type:         MessageTypeJoin,
+				ConnectionID: client.ID,
+				UserID:       client.UserID,
+				Username:     client.Username,
 			}, client)
 
-			log.Printf("Client %s (%s) joined workspace %s", client.ID, client.Username, client.WorkspaceID)
+			log.Printf("Client %s (%s, %s) joined workspace %s", client.ID, client.UserID, client.Username, client.WorkspaceID)
 
 		case client := <-h.Unregister:
 			h.mu.Lock()
@@ -140,69 +207,43 @@ func (h *Hub) Run() {
 
 			// Notify others about user leaving
 			h.broadcastToRoom(client.WorkspaceID, &Message{
-				Type:     MessageTypeLeave,
-				UserID:   client.ID,
-				Username: client.Username,
+				Type:         MessageTypeLeave,
+				ConnectionID: client.ID,
+				UserID:       client.UserID,
+				Username:     client.Username,
 			}, nil)
 
 			log.Printf("Client %s left workspace %s", client.ID, client.WorkspaceID)
 
+		case d := <-h.direct:
+			h.sendToClient(d.client, d.messages)
+
+		case m := <-h.outbound:
+			h.broadcastToRoom(m.workspaceID, m.message, nil)
+
+		case req := <-h.disconnect:
+			h.disconnectClients(req.workspaceID, req.match)
+			close(req.done)
+
 		case message := <-h.Broadcast:
+			// Drop what a client sent before it was disconnected
+			if !h.connected(message.WorkspaceID, message.ConnectionID) {
+				break
+			}
+
 			switch message.Type {
 			case MessageTypeContent:
-				// Use version control for content updates
-				doc := h.versionManager.GetDocument(message.WorkspaceID)
-				result := doc.UpdateContent(message.Content, message.Version, message.Hash)
-
-				if result.Success {
-					// Update successful
-					h.contentMu.Lock()
-					h.lastContent[message.WorkspaceID] = result.Content
-					h.lastContentTime[message.WorkspaceID] = time.Now()
-					h.contentMu.Unlock()
-
-					// Broadcast full content to all OTHER clients
-					remoteMsg := &Message{
-						Type:        MessageTypeContent,
-						Content:     result.Content,
-						UserID:      message.UserID,
-						WorkspaceID: message.WorkspaceID,
-						Version:     result.Version,
-						Hash:        result.Hash,
-						Conflict:    false,
-					}
-					h.broadcastToRoomExcludeUser(message.WorkspaceID, remoteMsg, message.UserID)
-
-					// Send ACK (no content) back to the sender so it can update version/hash
-					ackMsg := &Message{
-						Type:        MessageTypeAck,
-						UserID:      message.UserID,
-						WorkspaceID: message.WorkspaceID,
-						Version:     result.Version,
-						Hash:        result.Hash,
-					}
-					h.sendToUser(message.WorkspaceID, message.UserID, ackMsg)
-				} else {
-					// Conflict detected - send current state back to sender so it can re-sync
-					conflictResponse := &Message{
-						Type:        MessageTypeContent,
-						Content:     result.Content,
-						UserID:      "",
-						WorkspaceID: message.WorkspaceID,
-						Version:     result.Version,
-						Hash:        result.Hash,
-						Conflict:    true,
-					}
-					// Send only to the client that caused the conflict
-					h.sendToUser(message.WorkspaceID, message.UserID, conflictResponse)
-				}
+				h.handleContent(message)
+
+			case MessageTypeOperation:
+				h.handleOperation(message)
 
 			case MessageTypeCursor:
 				// Update client's cursor position
 				h.mu.RLock()
 				if room, ok := h.Rooms[message.WorkspaceID]; ok {
 					for client := range room {
-						if client.ID == message.UserID {
+						if client.ID == message.ConnectionID {
 							client.CursorPosition = &message.Position
 							break
 						}
@@ -210,11 +251,18 @@ func (h *Hub) Run() {
 				}
 				h.mu.RUnlock()
 
-				// Broadcast cursor to other users
-				h.broadcastToRoom(message.WorkspaceID, message, nil)
-
-			default:
-				h.broadcastToRoom(message.WorkspaceID, message, nil)
+				// Broadcast cursor to other users, with nothing but the position
+				h.broadcastToRoom(message.WorkspaceID, &Message{
+					Type:           MessageTypeCursor,
+					Channel:        message.Channel,
+					WorkspaceID:    message.WorkspaceID,
+					ConnectionID:   message.ConnectionID,
+					UserID:         message.UserID,
+					Username:       message.Username,
+					Position:       message.Position,
+					SelectionStart: message.SelectionStart,
+					SelectionEnd:   message.SelectionEnd,
+				}, nil)
 			}
 
 		case <-autoSaveTicker.C:
@@ -223,6 +271,172 @@ func (h *Hub) Run() {
 	}
 }
 
+// handleContent applies a full-document update using version control
+func (h *Hub) handleContent(message *Message) {
+	doc, ok := h.messageDocument(message)
+	if !ok {
+		return
+	}
+	author := Author{ID: message.UserID, Name: message.Username, IP: message.IP}
+	result := doc.UpdateContent(message.Content, message.Version, message.Hash, author)
+
+	if !result.Success {
+		h.sendConflict(message, result)
+		return
+	}
+
+	h.markPending(doc, result.Content, author)
+
+	// Broadcast full content to all OTHER clients
+	remoteMsg := &Message{
+		Type:         MessageTypeContent,
+		Channel:      doc.Channel,
+		Content:      result.Content,
+		ConnectionID: message.ConnectionID,
+		UserID:       message.UserID,
+		WorkspaceID:  message.WorkspaceID,
+		Version:      result.Version,
+		Hash:         result.Hash,
+		Conflict:     false,
+	}
+	h.broadcastToRoomExcludeConnection(message.WorkspaceID, remoteMsg, message.ConnectionID)
+
+	h.sendAck(message, result)
+}
+
+// handleOperation applies insert/delete operations, transforming them against
+// concurrent edits so they are not rejected for being made on an older version
+func (h *Hub) handleOperation(message *Message) {
+	doc, ok := h.messageDocument(message)
+	if !ok {
+		return
+	}
+	author := Author{ID: message.UserID, Name: message.Username, IP: message.IP}
+	result := doc.ApplyOperations(message.Ops, message.Version, author)
+
+	if !result.Success {
+		h.sendConflict(message, result.UpdateResult)
+		return
+	}
+
+	h.markPending(doc, result.Content, author)
+
+	// Broadcast the transformed operations to all OTHER clients
+	remoteMsg := &Message{
+		Type:         MessageTypeOperation,
+		Channel:      doc.Channel,
+		Ops:          result.Ops,
+		ConnectionID: message.ConnectionID,
+		UserID:       message.UserID,
+		WorkspaceID:  message.WorkspaceID,
+		Version:      result.Version,
+		Hash:         result.Hash,
+	}
+	h.broadcastToRoomExcludeConnection(message.WorkspaceID, remoteMsg, message.ConnectionID)
+
+	h.sendAck(message, result.UpdateResult)
+}
+
+// messageDocument returns the document addressed by a message's workspace and channel
+func (h *Hub) messageDocument(message *Message) (*VersionedDoc, bool) {
+	channel := message.Channel
+	if channel == "" {
+		channel = database.ChannelMarkdown
+	}
+	if channel != database.ChannelMarkdown && channel != database.ChannelCollie {
+		log.Printf("Ignoring message for unknown channel %q from %s", channel, message.ConnectionID)
+		return nil, false
+	}
+	message.Channel = channel
+	return h.versionManager.GetDocument(message.WorkspaceID, channel), true
+}
+
+// markPending records accepted content for the next auto-save and schedules a lint run
+func (h *Hub) markPending(doc *VersionedDoc, content string, author Author) {
+	key := documentKey{doc.WorkspaceID, doc.Channel}
+	h.contentMu.Lock()
+	h.lastContent[key] = content
+	h.lastContentTime[key] = time.Now()
+	h.lastAuthor[key] = author
+	if h.editors[key] == nil {
+		h.editors[key] = make(map[string]Author)
+	}
+	h.editors[key][author.ID] = author
+	h.contentMu.Unlock()
+
+	if doc.Channel == database.ChannelMarkdown {
+		h.scheduleLint(doc)
+	}
+}
+
+// scheduleLint lints a document once edits have paused for LintDelay
+func (h *Hub) scheduleLint(doc *VersionedDoc) {
+	key := documentKey{doc.WorkspaceID, doc.Channel}
+	h.lintMu.Lock()
+	defer h.lintMu.Unlock()
+
+	if timer, ok := h.lintTimers[key]; ok {
+		timer.Reset(h.LintDelay)
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(h.LintDelay, func() {
+		// Forget the timer so the map only holds documents being edited
+		h.lintMu.Lock()
+		if h.lintTimers[key] == timer {
+			delete(h.lintTimers, key)
+		}
+		h.lintMu.Unlock()
+		h.pushLint(doc)
+	})
+	h.lintTimers[key] = timer
+}
+
+// pushLint sends the diagnostics for the current document state to its room;
+// it runs on a timer goroutine, so the message is queued for Run
+func (h *Hub) pushLint(doc *VersionedDoc) {
+	content, version, hash := doc.GetState()
+	h.queueBroadcast(doc.WorkspaceID, &Message{
+		Type:        MessageTypeLint,
+		Channel:     doc.Channel,
+		WorkspaceID: doc.WorkspaceID,
+		Version:     version,
+		Hash:        hash,
+		Diagnostics: parser.Lint(content),
+	})
+	if h.EditsPaused != nil {
+		h.EditsPaused(doc.WorkspaceID, content)
+	}
+}
+
+// sendAck sends an ACK (no content) back to the sender so it can update version/hash
+func (h *Hub) sendAck(message *Message, result UpdateResult) {
+	ackMsg := &Message{
+		Type:        MessageTypeAck,
+		Channel:     message.Channel,
+		UserID:      message.UserID,
+		WorkspaceID: message.WorkspaceID,
+		Version:     result.Version,
+		Hash:        result.Hash,
+	}
+	h.sendToConnection(message.WorkspaceID, message.ConnectionID, ackMsg)
+}
+
+// sendConflict sends the current state back to the sender of a rejected update so it can re-sync
+func (h *Hub) sendConflict(message *Message, result UpdateResult) {
+	conflictResponse := &Message{
+		Type:        MessageTypeContent,
+		Channel:     message.Channel,
+		Content:     result.Content,
+		UserID:      "",
+		WorkspaceID: message.WorkspaceID,
+		Version:     result.Version,
+		Hash:        result.Hash,
+		Conflict:    true,
+	}
+	h.sendToConnection(message.WorkspaceID, message.ConnectionID, conflictResponse)
+}
+
 // sendUserList sends the current user list to a specific client
 func (h *Hub) sendUserList(client *Client) {
 	users := h.getUsersInRoom(client.WorkspaceID)
@@ -254,7 +468,8 @@ func (h *Hub) getUsersInRoom(workspaceID string) []UserInfo {
 	if room, ok := h.Rooms[workspaceID]; ok {
 		for client := range room {
 			users = append(users, UserInfo{
-				UserID:         client.ID,
+				ConnectionID:   client.ID,
+				UserID:         client.UserID,
 				Username:       client.Username,
 				CursorPosition: client.CursorPosition,
 			})
@@ -263,8 +478,8 @@ func (h *Hub) getUsersInRoom(workspaceID string) []UserInfo {
 	return users
 }
 
-// sendToUser sends a message to a specific user in a workspace
-func (h *Hub) sendToUser(workspaceID, userID string, message *Message) {
+// sendToConnection sends a message to one connection in a workspace
+func (h *Hub) sendToConnection(workspaceID, connectionID string, message *Message) {
 	data, err := json.Marshal(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
@@ -275,7 +490,7 @@ func (h *Hub) sendToUser(workspaceID, userID string, message *Message) {
 	room := h.Rooms[workspaceID]
 	var targetClient *Client
 	for client := range room {
-		if client.ID == userID {
+		if client.ID == connectionID {
 			targetClient = client
 			break
 		}
@@ -286,12 +501,71 @@ func (h *Hub) sendToUser(workspaceID, userID string, message *Message) {
 		select {
 		case targetClient.Send <- data:
 		default:
-			log.Printf("Failed to send message to user %s", userID)
+			log.Printf("Failed to send message to connection %s", connectionID)
 		}
 	}
 }
 
-// broadcastToRoom sends a message to all clients in a room
+// connected reports whether a connection is still in a workspace's room
+func (h *Hub) connected(workspaceID, connectionID string) bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.Rooms[workspaceID] {
+		if client.ID == connectionID {
+			return true
+		}
+	}
+	return false
+}
+
+// disconnectClients removes the clients of a room, or of every room if
+// workspaceID is "", that match and closes their Send channels; their pumps
+// then close the connections and unregister, which tells the rest of the
+// room they left
+func (h *Hub) disconnectClients(workspaceID string, match func(*Client) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for id, room := range h.Rooms {
+		if workspaceID != "" && id != workspaceID {
+			continue
+		}
+		for client := range room {
+			if match == nil || match(client) {
+				delete(room, client)
+				close(client.Send)
+				log.Printf("Disconnected client %s from workspace %s", client.ID, id)
+			}
+		}
+		if len(room) == 0 {
+			delete(h.Rooms, id)
+		}
+	}
+}
+
+// sendToClient sends messages to a client that is still registered; once it
+// has left, its Send channel is closed and the messages are dropped
+func (h *Hub) sendToClient(client *Client, messages []*Message) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.Rooms[client.WorkspaceID][client] {
+		return
+	}
+	for _, message := range messages {
+		data, err := json.Marshal(message)
+		if err != nil {
+			log.Printf("Error marshaling message: %v", err)
+			continue
+		}
+		select {
+		case client.Send <- data:
+		default:
+			log.Printf("Failed to send message to connection %s", client.ID)
+		}
+	}
+}
+
+// broadcastToRoom sends a message to all clients in a room; it must only be
+// called from Run, other goroutines use queueBroadcast
 func (h *Hub) broadcastToRoom(workspaceID string, message *Message, exclude *Client) {
 	data, err := json.Marshal(message)
 	if err != nil {
@@ -325,8 +599,8 @@ func (h *Hub) broadcastToRoom(workspaceID string, message *Message, exclude *Cli
 	}
 }
 
-// broadcastToRoomExcludeUser sends a message to all clients except the one with the given userID
-func (h *Hub) broadcastToRoomExcludeUser(workspaceID string, message *Message, excludeUserID string) {
+// broadcastToRoomExcludeConnection sends a message to all clients except the given connection
+func (h *Hub) broadcastToRoomExcludeConnection(workspaceID string, message *Message, excludeConnectionID string) {
 	data, err := json.Marshal(message)
 	if err != nil {
 		log.Printf("Error marshaling message: %v", err)
@@ -342,7 +616,7 @@ func (h *Hub) broadcastToRoomExcludeUser(workspaceID string, message *Message, e
 	h.mu.RUnlock()
 
 	for _, client := range clients {
-		if client.ID == excludeUserID {
+		if client.ID == excludeConnectionID {
 			continue
 		}
 		select {
@@ -358,21 +632,198 @@ func (h *Hub) broadcastToRoomExcludeUser(workspaceID string, message *Message, e
 	}
 }
 
-// autoSave saves pending content to database
+// autoSave writes every document edited since the last save to the database
+// and snapshots it. It runs in the hub loop, so the saves and snapshots of a
+// document happen in version order; a failed save is retried on the next tick.
 func (h *Hub) autoSave() {
 	h.contentMu.Lock()
 	defer h.contentMu.Unlock()
 
-	cutoff := time.Now().Add(-30 * time.Second)
-	for wsID, content := range h.lastContent {
-		if lastTime, ok := h.lastContentTime[wsID]; ok && lastTime.After(cutoff) {
-			if err := database.UpdateWorkspaceContent(wsID, content); err != nil {
-				log.Printf("Error auto-saving workspace %s: %v", wsID, err)
-			} else {
-				log.Printf("Auto-saved workspace %s", wsID)
+	for key := range h.lastContent {
+		doc, ok := h.versionManager.Loaded(key.workspaceID, key.channel)
+		if !ok {
+			// Forgotten with its deleted workspace
+			delete(h.lastContent, key)
+			delete(h.lastContentTime, key)
+			continue
+		}
+		content, version, err := doc.Save()
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			log.Printf("Dropping edits to deleted workspace %s (%s)", key.workspaceID, key.channel)
+		case err != nil:
+			log.Printf("Error auto-saving workspace %s (%s): %v", key.workspaceID, key.channel, err)
+			continue
+		default:
+			log.Printf("Auto-saved workspace %s (%s) at version %d", key.workspaceID, key.channel, version)
+			h.versionManager.History.Record(key.workspaceID, key.channel, content, version, h.lastAuthor[key])
+		}
+		delete(h.lastContent, key)
+		delete(h.lastContentTime, key)
+	}
+	h.auditEdits()
+	h.versionManager.Blame.Flush()
+}
+
+// auditEdits writes a content update audit event per author of each document
+// edited since the last call, so typing is not logged keystroke by keystroke;
+// caller must hold h.contentMu
+func (h *Hub) auditEdits() {
+	for key, authors := range h.editors {
+		name := ""
+		if ws, err := database.GetWorkspace(key.workspaceID); err == nil {
+			name = ws.Name
+		}
+		_, version, _ := h.versionManager.GetDocument(key.workspaceID, key.channel).GetState()
+		for _, author := range authors {
+			err := database.CreateAuditEvent(&database.AuditEvent{
+				Action:     database.AuditContentUpdate,
+				ActorID:    author.ID,
+				ActorName:  author.Name,
+				Target:     key.workspaceID,
+				TargetName: name,
+				Detail:     fmt.Sprintf("%s version %d", key.channel, version),
+				IP:         author.IP,
+			})
+			if err != nil {
+				log.Printf("Error recording audit event: %v", err)
 			}
 		}
+		delete(h.editors, key)
+	}
+}
+
+// ApplyContent replaces a workspace document from the server side and pushes
+// the new state to every client in the room
+func (h *Hub) ApplyContent(workspaceID, channel, content string, author Author) (UpdateResult, error) {
+	doc := h.versionManager.GetDocument(workspaceID, channel)
+	result, err := doc.ReplaceContent(content, author)
+	if err != nil {
+		return result, err
+	}
+
+	// The next auto-save snapshots it with the other edits
+	h.markPending(doc, result.Content, author)
+
+	h.broadcastContent(workspaceID, channel, result, author)
+	return result, nil
+}
+
+// ErrEditRejected is returned when a server-side edit cannot be merged with the live document
+var ErrEditRejected = errors.New("edit could not be applied to the current document")
+
+// EditContent changes a workspace document from baseContent, read at
+// baseVersion, to newContent. The change is transformed over edits made since
+// baseVersion so concurrent typing elsewhere in the document is kept.
+func (h *Hub) EditContent(workspaceID, channel, baseContent, newContent string, baseVersion int64, author Author) (UpdateResult, error) {
+	doc := h.versionManager.GetDocument(workspaceID, channel)
+	op := textOperationBetween(baseContent, newContent)
+	result := doc.ApplyOperations(op.Operations(), baseVersion, author)
+	if !result.Success {
+		return result.UpdateResult, ErrEditRejected
+	}
+
+	h.markPending(doc, result.Content, author)
+	h.broadcastContent(workspaceID, channel, result.UpdateResult, author)
+	return result.UpdateResult, nil
+}
+
+// broadcastContent pushes the full state of a document to every client in the room
+func (h *Hub) broadcastContent(workspaceID, channel string, result UpdateResult, author Author) {
+	h.queueBroadcast(workspaceID, &Message{
+		Type:        MessageTypeContent,
+		Channel:     channel,
+		Content:     result.Content,
+		UserID:      author.ID,
+		WorkspaceID: workspaceID,
+		Version:     result.Version,
+		Hash:        result.Hash,
+	})
+}
+
+// RestoreContent replaces a workspace document with a historical snapshot.
+// Both the replaced and the restored content are snapshotted so the restore can be undone.
+func (h *Hub) RestoreContent(workspaceID, channel, content string, author Author) (UpdateResult, error) {
+	current, version, _ := h.versionManager.GetDocument(workspaceID, channel).GetState()
+	h.contentMu.RLock()
+	previous := h.lastAuthor[documentKey{workspaceID, channel}]
+	h.contentMu.RUnlock()
+	h.versionManager.History.Force(workspaceID, channel, current, version, previous)
+
+	result, err := h.ApplyContent(workspaceID, channel, content, author)
+	if err != nil {
+		return result, err
+	}
+
+	h.versionManager.History.Force(workspaceID, channel, result.Content, result.Version, author)
+	return result, nil
+}
+
+// PushAlerts sends the current alerts of a workspace to every client in its room
+func (h *Hub) PushAlerts(workspaceID string, alerts []parser.Alert) {
+	h.queueBroadcast(workspaceID, &Message{
+		Type:        MessageTypeAlerts,
+		WorkspaceID: workspaceID,
+		Alerts:      alerts,
+	})
+}
+
+// queueBroadcast hands a message for every client in a room to Run, for
+// callers outside the hub loop such as HTTP handlers
+func (h *Hub) queueBroadcast(workspaceID string, message *Message) {
+	h.outbound <- roomMessage{workspaceID: workspaceID, message: message}
+}
+
+// SendTo queues messages for a client, in order. Messages queued after the
+// client is registered reach it after the hub has added it to its room.
+func (h *Hub) SendTo(client *Client, messages ...*Message) {
+	h.direct <- directMessage{client: client, messages: messages}
+}
+
+// Disconnect closes the connections to a workspace, or to every workspace if
+// workspaceID is "", of the clients that match, or of all of them if match is
+// nil, and returns once they are closed
+func (h *Hub) Disconnect(workspaceID string, match func(*Client) bool) {
+	done := make(chan struct{})
+	h.disconnect <- disconnectRequest{workspaceID: workspaceID, match: match, done: done}
+	<-done
+}
+
+// DisconnectUser closes a user's connections to a workspace, or to every
+// workspace if workspaceID is "", after their access changed; access is only
+// checked when a client connects
+func (h *Hub) DisconnectUser(workspaceID, userID string) {
+	h.Disconnect(workspaceID, func(c *Client) bool { return c.UserID == userID })
+}
+
+// DisconnectShareLink closes the connections made with a revoked share link
+func (h *Hub) DisconnectShareLink(workspaceID, shareLinkID string) {
+	h.Disconnect(workspaceID, func(c *Client) bool { return c.ShareLinkID == shareLinkID })
+}
+
+// CloseWorkspace disconnects every client of a deleted workspace and forgets
+// its documents and pending edits, so nothing is written for it again
+func (h *Hub) CloseWorkspace(workspaceID string) {
+	h.Disconnect(workspaceID, nil)
+	h.versionManager.Forget(workspaceID)
+
+	h.contentMu.Lock()
+	for _, channel := range []string{database.ChannelMarkdown, database.ChannelCollie} {
+		key := documentKey{workspaceID, channel}
+		delete(h.lastContent, key)
+		delete(h.lastContentTime, key)
+		delete(h.lastAuthor, key)
+		delete(h.editors, key)
+	}
+	h.contentMu.Unlock()
+
+	h.lintMu.Lock()
+	key := documentKey{workspaceID, database.ChannelMarkdown}
+	if timer, ok := h.lintTimers[key]; ok {
+		timer.Stop()
+		delete(h.lintTimers, key)
 	}
+	h.lintMu.Unlock()
 }
 
 // GetVersionManager returns the version manager
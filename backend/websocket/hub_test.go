@@ -0,0 +1,273 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kywk/sheltie/backend/database"
+	"github.com/kywk/sheltie/backend/parser"
+)
+
+// receive returns the type of the next message sent to a client
+func receive(t *testing.T, c *Client) string {
+	t.Helper()
+	select {
+	case data, ok := <-c.Send:
+		if !ok {
+			t.Fatal("Send was closed")
+		}
+		var msg Message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		return msg.Type
+	case <-time.After(time.Second):
+		t.Fatal("no message")
+	}
+	return ""
+}
+
+func TestSendTo(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	c := &Client{ID: "c1", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	h.Register <- c
+	h.SendTo(c, &Message{Type: MessageTypeWelcome}, &Message{Type: MessageTypeContent})
+
+	for _, want := range []string{MessageTypeUsers, MessageTypeWelcome, MessageTypeContent} {
+		if got := receive(t, c); got != want {
+			t.Errorf("message = %s, want %s", got, want)
+		}
+	}
+}
+
+func TestSendToAfterUnregister(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	c := &Client{ID: "c1", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	h.Register <- c
+	h.Unregister <- c
+
+	// the hub closed Send; queuing more must not panic the hub
+	h.SendTo(c, &Message{Type: MessageTypeWelcome})
+	other := &Client{ID: "c2", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	h.Register <- other
+	if got := receive(t, other); got != MessageTypeUsers {
+		t.Errorf("message = %s, want %s", got, MessageTypeUsers)
+	}
+}
+
+// initTestDB opens an empty database for the test
+func initTestDB(t *testing.T) {
+	t.Helper()
+	if err := database.Init(filepath.Join(t.TempDir(), "sheltie.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Close() })
+}
+
+func TestEditContentWhileClientsLeave(t *testing.T) {
+	initTestDB(t)
+	h := NewHub()
+	go h.Run()
+
+	clients := make([]*Client, 50)
+	for i := range clients {
+		clients[i] = &Client{ID: fmt.Sprint(i), WorkspaceID: "w1", Send: make(chan []byte, 256)}
+		h.Register <- clients[i]
+	}
+
+	// broadcasts from HTTP handlers race with clients disconnecting; only
+	// Run may send on Send, or a closed channel panics the server
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		content := ""
+		for i := 0; i < 200; i++ {
+			_, version, _ := h.GetVersionManager().GetDocument("w1", database.ChannelMarkdown).GetState()
+			next := content + "x"
+			if _, err := h.EditContent("w1", database.ChannelMarkdown, content, next, version, amy); err != nil {
+				t.Error(err)
+				return
+			}
+			content = next
+		}
+	}()
+	for _, c := range clients {
+		h.Unregister <- c
+	}
+	<-done
+}
+
+func TestLintAfterEditsPause(t *testing.T) {
+	initTestDB(t)
+	h := NewHub()
+	h.LintDelay = 10 * time.Millisecond
+	paused := make(chan string, 10)
+	h.EditsPaused = func(workspaceID, content string) { paused <- content }
+	go h.Run()
+
+	c := &Client{ID: "c1", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	h.Register <- c
+	receive(t, c) // users
+
+	doc := h.GetVersionManager().GetDocument("w1", database.ChannelMarkdown)
+	for _, next := range []string{"# A\n", "# A\n## 基本資訊\n", "# A\n## 基本資訊\n- 燈號: 藍\n"} {
+		content, version, _ := doc.GetState()
+		if _, err := h.EditContent("w1", database.ChannelMarkdown, content, next, version, amy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, version, _ := doc.GetState()
+
+	// a slow run may lint between edits too; wait for the final version
+	var lint Message
+	for lint.Type != MessageTypeLint || lint.Version != version {
+		select {
+		case data := <-c.Send:
+			if err := json.Unmarshal(data, &lint); err != nil {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("no lint message")
+		}
+	}
+	if len(lint.Diagnostics) != 1 || lint.Diagnostics[0].Code != parser.CodeInvalidStatus {
+		t.Errorf("diagnostics = %+v, want one %s", lint.Diagnostics, parser.CodeInvalidStatus)
+	}
+
+	if got := <-paused; got == "" {
+		t.Error("EditsPaused got no content")
+	}
+
+	h.lintMu.Lock()
+	defer h.lintMu.Unlock()
+	if len(h.lintTimers) != 0 {
+		t.Errorf("%d lint timers left after they fired", len(h.lintTimers))
+	}
+}
+
+func TestCloseWorkspace(t *testing.T) {
+	initTestDB(t)
+	h := NewHub()
+	go h.Run()
+
+	gone := &Client{ID: "c1", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	other := &Client{ID: "c2", WorkspaceID: "w2", Send: make(chan []byte, 256)}
+	h.Register <- gone
+	h.Register <- other
+	doc := h.GetVersionManager().GetDocument("w1", database.ChannelMarkdown)
+	if _, err := h.EditContent("w1", database.ChannelMarkdown, "", "# A\n", 0, amy); err != nil {
+		t.Fatal(err)
+	}
+
+	h.CloseWorkspace("w1")
+
+	for range gone.Send {
+		// drain until the hub closes Send
+	}
+	if _, ok := h.GetVersionManager().Loaded("w1", database.ChannelMarkdown); ok {
+		t.Error("document of a closed workspace is still loaded")
+	}
+	h.contentMu.RLock()
+	pending := len(h.lastContent)
+	h.contentMu.RUnlock()
+	if pending != 0 {
+		t.Errorf("%d documents still pending a save", pending)
+	}
+
+	// an edit the client sent before it was disconnected is dropped
+	h.Broadcast <- &Message{Type: MessageTypeContent, WorkspaceID: "w1", ConnectionID: "c1", Content: "late"}
+	h.Broadcast <- &Message{Type: MessageTypeCursor, WorkspaceID: "w2", ConnectionID: "c2"}
+	receive(t, other) // users
+	if got := receive(t, other); got != MessageTypeCursor {
+		t.Fatalf("other room got %s", got)
+	}
+	if _, ok := h.GetVersionManager().Loaded("w1", database.ChannelMarkdown); ok {
+		t.Error("a late edit loaded the document again")
+	}
+	if content, _, _ := doc.GetState(); content != "# A\n" {
+		t.Errorf("content = %q", content)
+	}
+}
+
+func TestAutoSave(t *testing.T) {
+	initTestDB(t)
+	if err := database.CreateWorkspace(&database.Workspace{ID: "w1", Name: "w1"}); err != nil {
+		t.Fatal(err)
+	}
+	h := NewHub()
+
+	doc := h.GetVersionManager().GetDocument("w1", database.ChannelMarkdown)
+	for _, next := range []string{"# A\n", "# AB\n"} {
+		content, version, _ := doc.GetState()
+		if _, err := h.EditContent("w1", database.ChannelMarkdown, content, next, version, amy); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// edits are written behind, not on every keystroke
+	if ws, _ := database.GetWorkspace("w1"); ws.Content != "" {
+		t.Errorf("content saved before auto-save: %q", ws.Content)
+	}
+
+	h.autoSave()
+	ws, err := database.GetWorkspace("w1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ws.Content != "# AB\n" || ws.Version != 2 {
+		t.Errorf("saved %q at version %d, want the latest at version 2", ws.Content, ws.Version)
+	}
+	if v, err := database.GetLatestWorkspaceVersion("w1", database.ChannelMarkdown); err != nil || v.Version != 2 {
+		t.Errorf("latest snapshot = %+v, %v", v, err)
+	}
+
+	// edits to a workspace deleted meanwhile are dropped, not retried forever
+	if err := database.DeleteWorkspace("w1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.EditContent("w1", database.ChannelMarkdown, "# AB\n", "# ABC\n", 2, amy); err != nil {
+		t.Fatal(err)
+	}
+	h.autoSave()
+	if len(h.lastContent) != 0 {
+		t.Errorf("%d documents still pending a save", len(h.lastContent))
+	}
+}
+
+func TestDisconnectUser(t *testing.T) {
+	h := NewHub()
+	go h.Run()
+
+	amyW1 := &Client{ID: "c1", UserID: "u1", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	amyW2 := &Client{ID: "c2", UserID: "u1", WorkspaceID: "w2", Send: make(chan []byte, 256)}
+	guest := &Client{ID: "c3", UserID: "guest", WorkspaceID: "w1", ShareLinkID: "s1", Send: make(chan []byte, 256)}
+	bob := &Client{ID: "c4", UserID: "u2", WorkspaceID: "w1", Send: make(chan []byte, 256)}
+	for _, c := range []*Client{amyW1, amyW2, guest, bob} {
+		h.Register <- c
+	}
+
+	closed := func(c *Client) bool {
+		h.mu.RLock()
+		defer h.mu.RUnlock()
+		return !h.Rooms[c.WorkspaceID][c]
+	}
+
+	h.DisconnectUser("w1", "u1")
+	if !closed(amyW1) || closed(amyW2) {
+		t.Error("DisconnectUser with a workspace closed connections to others")
+	}
+	h.DisconnectShareLink("w1", "s1")
+	if !closed(guest) || closed(bob) {
+		t.Error("DisconnectShareLink closed the wrong connections")
+	}
+	h.DisconnectUser("", "u1")
+	if !closed(amyW2) || closed(bob) {
+		t.Error("DisconnectUser without a workspace missed a connection")
+	}
+}
@@ -0,0 +1,359 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf16"
+)
+
+// Operation types
+const (
+	OperationInsert = "insert"
+	OperationDelete = "delete"
+)
+
+// Operation is a single edit sent over the wire. Operations in a message are
+// applied in order, each relative to the document produced by the previous one.
+// Positions and lengths are UTF-16 code units, matching JavaScript strings.
+type Operation struct {
+	Type     string `json:"type"`
+	Position int    `json:"position"`
+	Text     string `json:"text,omitempty"`   // insert only
+	Length   int    `json:"length,omitempty"` // delete only
+}
+
+// component is one step of a TextOperation: retain (n > 0), delete (n < 0) or insert (s)
+type component struct {
+	n int
+	s []uint16
+}
+
+func (c component) isRetain() bool { return c.s == nil && c.n > 0 }
+func (c component) isDelete() bool { return c.s == nil && c.n < 0 }
+func (c component) isInsert() bool { return c.s != nil }
+
+// TextOperation describes a change to a whole document as a sequence of
+// retain, insert and delete components, following the ot.js model
+type TextOperation struct {
+	ops          []component
+	BaseLength   int
+	TargetLength int
+}
+
+var errOperationLength = errors.New("operation length does not match document")
+
+// retain skips over n units of the document
+func (o *TextOperation) retain(n int) {
+	if n <= 0 {
+		return
+	}
+	o.BaseLength += n
+	o.TargetLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isRetain() {
+		o.ops[last].n += n
+		return
+	}
+	o.ops = append(o.ops, component{n: n})
+}
+
+// insert adds s at the current position
+func (o *TextOperation) insert(s []uint16) {
+	if len(s) == 0 {
+		return
+	}
+	o.TargetLength += len(s)
+	last := len(o.ops) - 1
+	switch {
+	case last >= 0 && o.ops[last].isInsert():
+		o.ops[last].s = concat(o.ops[last].s, s)
+	case last >= 0 && o.ops[last].isDelete():
+		// Keep inserts before deletes so equivalent operations compare equal
+		if last >= 1 && o.ops[last-1].isInsert() {
+			o.ops[last-1].s = concat(o.ops[last-1].s, s)
+		} else {
+			o.ops = append(o.ops, o.ops[last])
+			o.ops[last] = component{s: concat(nil, s)}
+		}
+	default:
+		o.ops = append(o.ops, component{s: concat(nil, s)})
+	}
+}
+
+// delete removes n units at the current position
+func (o *TextOperation) delete(n int) {
+	if n <= 0 {
+		return
+	}
+	o.BaseLength += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isDelete() {
+		o.ops[last].n -= n
+		return
+	}
+	o.ops = append(o.ops, component{n: -n})
+}
+
+// apply runs the operation on a document
+func (o *TextOperation) apply(doc []uint16) ([]uint16, error) {
+	if len(doc) != o.BaseLength {
+		return nil, errOperationLength
+	}
+	out := make([]uint16, 0, o.TargetLength)
+	pos := 0
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			out = append(out, doc[pos:pos+c.n]...)
+			pos += c.n
+		case c.isInsert():
+			out = append(out, c.s...)
+		default:
+			pos -= c.n
+		}
+	}
+	return out, nil
+}
+
+// compose returns an operation equivalent to applying o and then next
+func (o *TextOperation) compose(next *TextOperation) (*TextOperation, error) {
+	if o.TargetLength != next.BaseLength {
+		return nil, errOperationLength
+	}
+
+	result := &TextOperation{}
+	it1, it2 := newIterator(o.ops), newIterator(next.ops)
+	op1, ok1 := it1.next()
+	op2, ok2 := it2.next()
+
+	for ok1 || ok2 {
+		if ok1 && op1.isDelete() {
+			result.delete(-op1.n)
+			op1, ok1 = it1.next()
+			continue
+		}
+		if ok2 && op2.isInsert() {
+			result.insert(op2.s)
+			op2, ok2 = it2.next()
+			continue
+		}
+		if !ok1 || !ok2 {
+			return nil, errOperationLength
+		}
+
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.n, op2.n)
+			result.retain(n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isInsert() && op2.isDelete():
+			n := min(len(op1.s), -op2.n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isInsert() && op2.isRetain():
+			n := min(len(op1.s), op2.n)
+			result.insert(op1.s[:n])
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.n, -op2.n)
+			result.delete(n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		}
+	}
+	return result, nil
+}
+
+// transform takes two operations a and b made concurrently on the same document
+// and returns a' and b' such that applying a then b' equals applying b then a'.
+// When both insert at the same position, a's insert goes first.
+func transform(a, b *TextOperation) (*TextOperation, *TextOperation, error) {
+	if a.BaseLength != b.BaseLength {
+		return nil, nil, errOperationLength
+	}
+
+	aPrime, bPrime := &TextOperation{}, &TextOperation{}
+	it1, it2 := newIterator(a.ops), newIterator(b.ops)
+	op1, ok1 := it1.next()
+	op2, ok2 := it2.next()
+
+	for ok1 || ok2 {
+		if ok1 && op1.isInsert() {
+			aPrime.insert(op1.s)
+			bPrime.retain(len(op1.s))
+			op1, ok1 = it1.next()
+			continue
+		}
+		if ok2 && op2.isInsert() {
+			aPrime.retain(len(op2.s))
+			bPrime.insert(op2.s)
+			op2, ok2 = it2.next()
+			continue
+		}
+		if !ok1 || !ok2 {
+			return nil, nil, errOperationLength
+		}
+
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			n := min(op1.n, op2.n)
+			aPrime.retain(n)
+			bPrime.retain(n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isDelete() && op2.isDelete():
+			// Both deleted the same text
+			n := min(-op1.n, -op2.n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isDelete() && op2.isRetain():
+			n := min(-op1.n, op2.n)
+			aPrime.delete(n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		case op1.isRetain() && op2.isDelete():
+			n := min(op1.n, -op2.n)
+			bPrime.delete(n)
+			op1, ok1 = it1.consume(op1, n)
+			op2, ok2 = it2.consume(op2, n)
+		}
+	}
+	return aPrime, bPrime, nil
+}
+
+// iterator walks the components of an operation, allowing partial consumption
+type iterator struct {
+	ops []component
+	i   int
+}
+
+func newIterator(ops []component) *iterator {
+	return &iterator{ops: ops}
+}
+
+// next returns the next whole component
+func (it *iterator) next() (component, bool) {
+	if it.i >= len(it.ops) {
+		return component{}, false
+	}
+	c := it.ops[it.i]
+	it.i++
+	return c, true
+}
+
+// consume uses n units of c and returns the remainder, or the next component if c is used up
+func (it *iterator) consume(c component, n int) (component, bool) {
+	switch {
+	case c.isInsert():
+		if n < len(c.s) {
+			return component{s: c.s[n:]}, true
+		}
+	case c.isDelete():
+		if n < -c.n {
+			return component{n: c.n + n}, true
+		}
+	default:
+		if n < c.n {
+			return component{n: c.n - n}, true
+		}
+	}
+	return it.next()
+}
+
+// newTextOperation converts wire operations into a single TextOperation on a document of baseLength units
+func newTextOperation(ops []Operation, baseLength int) (*TextOperation, error) {
+	result := &TextOperation{}
+	result.retain(baseLength)
+
+	for _, op := range ops {
+		length := result.TargetLength
+		step := &TextOperation{}
+		switch op.Type {
+		case OperationInsert:
+			if op.Position < 0 || op.Position > length {
+				return nil, fmt.Errorf("insert position %d out of range", op.Position)
+			}
+			step.retain(op.Position)
+			step.insert(utf16.Encode([]rune(op.Text)))
+			step.retain(length - op.Position)
+		case OperationDelete:
+			if op.Position < 0 || op.Length < 0 || op.Position+op.Length > length {
+				return nil, fmt.Errorf("delete range %d+%d out of range", op.Position, op.Length)
+			}
+			step.retain(op.Position)
+			step.delete(op.Length)
+			step.retain(length - op.Position - op.Length)
+		default:
+			return nil, fmt.Errorf("unknown operation type %q", op.Type)
+		}
+
+		var err error
+		if result, err = result.compose(step); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// Operations converts a TextOperation back into sequential wire operations
+func (o *TextOperation) Operations() []Operation {
+	ops := []Operation{}
+	pos := 0
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			pos += c.n
+		case c.isInsert():
+			ops = append(ops, Operation{Type: OperationInsert, Position: pos, Text: string(utf16.Decode(c.s))})
+			pos += len(c.s)
+		default:
+			ops = append(ops, Operation{Type: OperationDelete, Position: pos, Length: -c.n})
+		}
+	}
+	return ops
+}
+
+// textOperationBetween builds an operation turning oldContent into newContent
+// by replacing the region between their common prefix and suffix. The region
+// never splits a surrogate pair, so its text survives Operations.
+func textOperationBetween(oldContent, newContent string) *TextOperation {
+	a, b := utf16.Encode([]rune(oldContent)), utf16.Encode([]rune(newContent))
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	if prefix > 0 && isHighSurrogate(a[prefix-1]) {
+		prefix--
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	if suffix > 0 && isLowSurrogate(a[len(a)-suffix]) {
+		suffix--
+	}
+
+	op := &TextOperation{}
+	op.retain(prefix)
+	op.delete(len(a) - prefix - suffix)
+	op.insert(b[prefix : len(b)-suffix])
+	op.retain(suffix)
+	return op
+}
+
+// isHighSurrogate reports whether u starts a UTF-16 surrogate pair
+func isHighSurrogate(u uint16) bool {
+	return u >= 0xd800 && u < 0xdc00
+}
+
+// isLowSurrogate reports whether u ends a UTF-16 surrogate pair
+func isLowSurrogate(u uint16) bool {
+	return u >= 0xdc00 && u < 0xe000
+}
+
+// concat appends b to a copy of a
+func concat(a, b []uint16) []uint16 {
+	out := make([]uint16, 0, len(a)+len(b))
+	return append(append(out, a...), b...)
+}
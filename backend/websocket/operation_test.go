@@ -0,0 +1,251 @@
+package websocket
+
+import (
+	"reflect"
+	"testing"
+	"unicode/utf16"
+)
+
+// units encodes s as UTF-16, the unit operations count in
+func units(s string) []uint16 {
+	return utf16.Encode([]rune(s))
+}
+
+// mustOperation builds an operation from wire operations on doc
+func mustOperation(t *testing.T, doc string, ops ...Operation) *TextOperation {
+	t.Helper()
+	op, err := newTextOperation(ops, len(units(doc)))
+	if err != nil {
+		t.Fatalf("newTextOperation(%+v): %v", ops, err)
+	}
+	return op
+}
+
+// mustApply runs op on doc
+func mustApply(t *testing.T, op *TextOperation, doc string) string {
+	t.Helper()
+	out, err := op.apply(units(doc))
+	if err != nil {
+		t.Fatalf("apply on %q: %v", doc, err)
+	}
+	return string(utf16.Decode(out))
+}
+
+func insertOp(pos int, text string) Operation {
+	return Operation{Type: OperationInsert, Position: pos, Text: text}
+}
+
+func deleteOp(pos, length int) Operation {
+	return Operation{Type: OperationDelete, Position: pos, Length: length}
+}
+
+func TestTransform(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		a, b []Operation
+		want string // the document both orders converge on
+	}{
+		{
+			name: "insert/insert at the same position puts a first",
+			doc:  "ab",
+			a:    []Operation{insertOp(1, "X")},
+			b:    []Operation{insertOp(1, "Y")},
+			want: "aXYb",
+		},
+		{
+			name: "insert/insert at different positions",
+			doc:  "abcd",
+			a:    []Operation{insertOp(1, "X")},
+			b:    []Operation{insertOp(3, "Y")},
+			want: "aXbcYd",
+		},
+		{
+			name: "insert before a delete",
+			doc:  "abcdef",
+			a:    []Operation{insertOp(1, "X")},
+			b:    []Operation{deleteOp(2, 2)},
+			want: "aXbef",
+		},
+		{
+			name: "insert inside a deleted range is kept",
+			doc:  "abcdef",
+			a:    []Operation{insertOp(3, "X")},
+			b:    []Operation{deleteOp(1, 4)},
+			want: "aXf",
+		},
+		{
+			name: "insert at the end of a deleted range",
+			doc:  "abcdef",
+			a:    []Operation{insertOp(4, "X")},
+			b:    []Operation{deleteOp(2, 2)},
+			want: "abXef",
+		},
+		{
+			name: "delete/delete identical",
+			doc:  "abcdef",
+			a:    []Operation{deleteOp(1, 3)},
+			b:    []Operation{deleteOp(1, 3)},
+			want: "aef",
+		},
+		{
+			name: "delete/delete overlapping",
+			doc:  "abcdef",
+			a:    []Operation{deleteOp(1, 3)},
+			b:    []Operation{deleteOp(2, 3)},
+			want: "af",
+		},
+		{
+			name: "delete/delete one inside the other",
+			doc:  "abcdef",
+			a:    []Operation{deleteOp(0, 5)},
+			b:    []Operation{deleteOp(2, 1)},
+			want: "f",
+		},
+		{
+			name: "CJK edits in different projects",
+			doc:  "# 專案甲\n- 燈號: 綠\n# 專案乙\n- 燈號: 綠\n",
+			a:    []Operation{deleteOp(12, 1), insertOp(12, "紅")},
+			b:    []Operation{deleteOp(26, 1), insertOp(26, "黃")},
+			want: "# 專案甲\n- 燈號: 紅\n# 專案乙\n- 燈號: 黃\n",
+		},
+		{
+			name: "surrogate pairs before both edits",
+			doc:  "𠮷野家😀",
+			a:    []Operation{insertOp(2, "吉")},
+			b:    []Operation{deleteOp(3, 1)},
+			want: "𠮷吉野😀",
+		},
+		{
+			name: "insert and delete of whole surrogate pairs",
+			doc:  "a😀b𠮷c",
+			a:    []Operation{insertOp(3, "𩸽")},
+			b:    []Operation{deleteOp(1, 2), deleteOp(2, 2)},
+			want: "a𩸽bc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := mustOperation(t, tt.doc, tt.a...)
+			b := mustOperation(t, tt.doc, tt.b...)
+			aPrime, bPrime, err := transform(a, b)
+			if err != nil {
+				t.Fatalf("transform: %v", err)
+			}
+
+			ab := mustApply(t, bPrime, mustApply(t, a, tt.doc))
+			ba := mustApply(t, aPrime, mustApply(t, b, tt.doc))
+			if ab != ba {
+				t.Fatalf("did not converge: a then b' = %q, b then a' = %q", ab, ba)
+			}
+			if ab != tt.want {
+				t.Errorf("got %q, want %q", ab, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformLengthMismatch(t *testing.T) {
+	a := mustOperation(t, "abc", insertOp(0, "x"))
+	b := mustOperation(t, "abcd", insertOp(0, "y"))
+	if _, _, err := transform(a, b); err == nil {
+		t.Error("transform of operations on different documents should fail")
+	}
+}
+
+func TestCompose(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		first []Operation
+		then  []Operation
+	}{
+		{"insert then delete part of it", "abc", []Operation{insertOp(1, "XYZ")}, []Operation{deleteOp(2, 1)}},
+		{"delete then insert at the same place", "abcdef", []Operation{deleteOp(1, 2)}, []Operation{insertOp(1, "QQ")}},
+		{"retain across both", "abcdef", []Operation{insertOp(6, "!")}, []Operation{insertOp(0, "¡")}},
+		{"surrogate pairs", "😀𠮷", []Operation{insertOp(2, "野")}, []Operation{deleteOp(0, 2)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := mustOperation(t, tt.doc, tt.first...)
+			middle := mustApply(t, first, tt.doc)
+			then := mustOperation(t, middle, tt.then...)
+			want := mustApply(t, then, middle)
+
+			composed, err := first.compose(then)
+			if err != nil {
+				t.Fatalf("compose: %v", err)
+			}
+			if got := mustApply(t, composed, tt.doc); got != want {
+				t.Errorf("composed operation gave %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestNewTextOperationSequential(t *testing.T) {
+	// Each operation is relative to the document produced by the previous one
+	doc := "hello world"
+	op := mustOperation(t, doc, deleteOp(0, 5), insertOp(0, "goodbye"), insertOp(13, "!"))
+	if got := mustApply(t, op, doc); got != "goodbye world!" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestNewTextOperationRejectsOutOfRange(t *testing.T) {
+	for _, op := range []Operation{insertOp(4, "x"), insertOp(-1, "x"), deleteOp(2, 2), {Type: "replace"}} {
+		if _, err := newTextOperation([]Operation{op}, 3); err == nil {
+			t.Errorf("%+v on a 3 unit document should fail", op)
+		}
+	}
+}
+
+func TestOperationsRoundTrip(t *testing.T) {
+	doc := "# 專案\n- 燈號: 綠 😀\n"
+	op := mustOperation(t, doc, deleteOp(10, 1), insertOp(10, "紅"), insertOp(len(units(doc)), "𠮷\n"))
+
+	again, err := newTextOperation(op.Operations(), len(units(doc)))
+	if err != nil {
+		t.Fatalf("newTextOperation(Operations()): %v", err)
+	}
+	if !reflect.DeepEqual(again.ops, op.ops) {
+		t.Errorf("round trip changed the operation: %+v, want %+v", again.ops, op.ops)
+	}
+}
+
+func TestTextOperationBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+	}{
+		{"identical", "abc", "abc"},
+		{"middle change", "- 燈號: 綠\n", "- 燈號: 紅\n"},
+		{"insert at start", "abc", "xabc"},
+		{"delete at end", "abc", "ab"},
+		{"from empty", "", "專案"},
+		{"to empty", "專案", ""},
+		{"surrogate pairs sharing a high surrogate", "a𠮷b", "a𠮸b"},
+		{"surrogate pair replaced by a BMP character", "x😀y", "x☺y"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := textOperationBetween(tt.old, tt.new)
+			if got := mustApply(t, op, tt.old); got != tt.new {
+				t.Errorf("applied operation gave %q, want %q", got, tt.new)
+			}
+
+			// The wire form must not split surrogate pairs, or the
+			// inserted text cannot be represented as a string
+			again, err := newTextOperation(op.Operations(), len(units(tt.old)))
+			if err != nil {
+				t.Fatalf("newTextOperation(Operations()): %v", err)
+			}
+			if got := mustApply(t, again, tt.old); got != tt.new {
+				t.Errorf("wire operations gave %q, want %q", got, tt.new)
+			}
+		})
+	}
+}
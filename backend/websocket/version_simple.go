@@ -3,28 +3,57 @@ package websocket
 import (
 	"crypto/md5"
 	"fmt"
+	"log"
 	"sync"
 	"time"
+	"unicode/utf16"
 
 	"github.com/kywk/sheltie/backend/database"
 )
 
 // VersionManager manages document versions with conflict detection
 type VersionManager struct {
-	documents map[string]*VersionedDoc
+	documents map[documentKey]*VersionedDoc
 	mu        sync.RWMutex
+
+	// History records content snapshots for all documents
+	History *SnapshotRecorder
+
+	// Blame records the author of every line of the markdown documents
+	Blame *BlameTracker
+}
+
+// documentKey identifies one document channel of a workspace
+type documentKey struct {
+	workspaceID string
+	channel     string
 }
 
 // VersionedDoc tracks document state with version and hash
 type VersionedDoc struct {
 	WorkspaceID string
+	Channel     string
 	Content     string
 	Version     int64
 	Hash        string
 	LastUpdated time.Time
 	mu          sync.RWMutex
+
+	blame *BlameTracker // nil for channels without line authorship
+
+	// Recently applied operations, used to transform concurrent edits
+	operations []appliedOperation
 }
 
+// appliedOperation is an operation that produced the given document version
+type appliedOperation struct {
+	version int64
+	op      *TextOperation
+}
+
+// maxOperationHistory bounds how far behind a client may be and still have its operations transformed
+const maxOperationHistory = 500
+
 // UpdateResult represents the result of an update operation
 type UpdateResult struct {
 	Success bool
@@ -36,47 +65,71 @@ type UpdateResult struct {
 // NewVersionManager creates a new version manager
 func NewVersionManager() *VersionManager {
 	return &VersionManager{
-		documents: make(map[string]*VersionedDoc),
+		documents: make(map[documentKey]*VersionedDoc),
+		History:   NewSnapshotRecorder(),
+		Blame:     NewBlameTracker(),
 	}
 }
 
-// GetDocument gets or creates a versioned document
-func (vm *VersionManager) GetDocument(workspaceID string) *VersionedDoc {
+// GetDocument gets or creates a versioned document for a workspace channel
+func (vm *VersionManager) GetDocument(workspaceID, channel string) *VersionedDoc {
 	vm.mu.Lock()
 	defer vm.mu.Unlock()
 
-	if doc, exists := vm.documents[workspaceID]; exists {
-		return doc
-	}
-
-	// Load from database
-	workspace, err := database.GetWorkspace(workspaceID)
-	if err != nil {
-		// Create new document if not found
-		doc := &VersionedDoc{
-			WorkspaceID: workspaceID,
-			Content:     "",
-			Version:     0,
-			Hash:        generateHash(""),
-			LastUpdated: time.Now(),
-		}
-		vm.documents[workspaceID] = doc
+	key := documentKey{workspaceID, channel}
+	if doc, exists := vm.documents[key]; exists {
 		return doc
 	}
 
 	doc := &VersionedDoc{
 		WorkspaceID: workspaceID,
-		Content:     workspace.Content,
-		Version:     workspace.Version,
-		Hash:        generateHash(workspace.Content),
-		LastUpdated: workspace.UpdatedAt,
+		Channel:     channel,
+		Content:     "",
+		Version:     0,
+		Hash:        generateHash(""),
+		LastUpdated: time.Now(),
+	}
+	if channel == database.ChannelMarkdown {
+		doc.blame = vm.Blame
+	}
+
+	// Load from database, or start an empty document if not found
+	if workspace, err := database.GetWorkspace(workspaceID); err == nil {
+		if channel == database.ChannelCollie {
+			doc.Content, doc.Version = workspace.CollieContent, workspace.CollieVersion
+		} else {
+			doc.Content, doc.Version = workspace.Content, workspace.Version
+		}
+		doc.Hash = generateHash(doc.Content)
+		doc.LastUpdated = workspace.UpdatedAt
 	}
-	vm.documents[workspaceID] = doc
+
+	vm.documents[key] = doc
 	return doc
 }
 
+// Loaded returns a workspace document if it is already in memory, without
+// loading it from the database
+func (vm *VersionManager) Loaded(workspaceID, channel string) (*VersionedDoc, bool) {
+	vm.mu.RLock()
+	defer vm.mu.RUnlock()
+	doc, ok := vm.documents[documentKey{workspaceID, channel}]
+	return doc, ok
+}
+
+// Forget drops the documents of a workspace, such as a deleted one
+func (vm *VersionManager) Forget(workspaceID string) {
+	vm.mu.Lock()
+	for _, channel := range []string{database.ChannelMarkdown, database.ChannelCollie} {
+		delete(vm.documents, documentKey{workspaceID, channel})
+	}
+	vm.mu.Unlock()
+	vm.History.Forget(workspaceID)
+	vm.Blame.Forget(workspaceID)
+}
+
 // UpdateContent updates document content with conflict detection
-func (doc *VersionedDoc) UpdateContent(newContent string, clientVersion int64, clientHash string) UpdateResult {
+func (doc *VersionedDoc) UpdateContent(newContent string, clientVersion int64, clientHash string, author Author) UpdateResult {
 	doc.mu.Lock()
 	defer doc.mu.Unlock()
 
@@ -94,28 +147,170 @@ func (doc *VersionedDoc) UpdateContent(newContent string, clientVersion int64, c
 	}
 
 	// No conflict - update content
+	previous := doc.Content
+	doc.recordOperation(doc.Version+1, textOperationBetween(doc.Content, newContent))
 	doc.Content = newContent
 	doc.Version++
 	doc.Hash = generateHash(newContent)
 	doc.LastUpdated = time.Now()
+	doc.recordBlame(previous, author)
 
-	// Save to database
-	go func() {
-		success, err := database.UpdateWorkspaceContentWithVersion(doc.WorkspaceID, newContent, doc.Version-1)
-		if err != nil || !success {
-			// If database update fails, revert version
-			doc.mu.Lock()
-			doc.Version--
-			doc.mu.Unlock()
-		}
-	}()
+	return UpdateResult{
+		Success: true,
+		Version: doc.Version,
+		Hash:    doc.Hash,
+		Content: doc.Content,
+	}
+}
+
+// ReplaceContent overwrites the document content without conflict detection.
+// It is used for server-side changes such as restoring a snapshot.
+func (doc *VersionedDoc) ReplaceContent(newContent string, author Author) (UpdateResult, error) {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	if err := database.UpdateWorkspaceContentVersion(doc.WorkspaceID, doc.Channel, newContent, doc.Version+1); err != nil {
+		return UpdateResult{}, err
+	}
+
+	previous := doc.Content
+	doc.recordOperation(doc.Version+1, textOperationBetween(doc.Content, newContent))
+	doc.Content = newContent
+	doc.Version++
+	doc.Hash = generateHash(newContent)
+	doc.LastUpdated = time.Now()
+	doc.recordBlame(previous, author)
 
 	return UpdateResult{
 		Success: true,
 		Version: doc.Version,
 		Hash:    doc.Hash,
 		Content: doc.Content,
+	}, nil
+}
+
+// OperationResult represents the result of applying operations
+type OperationResult struct {
+	UpdateResult
+	Ops []Operation // The operations as applied, after transformation
+}
+
+// ApplyOperations transforms operations made against baseVersion over every
+// operation applied since, then applies them to the document
+func (doc *VersionedDoc) ApplyOperations(ops []Operation, baseVersion int64, author Author) OperationResult {
+	doc.mu.Lock()
+	defer doc.mu.Unlock()
+
+	rejected := OperationResult{UpdateResult: UpdateResult{
+		Success: false,
+		Version: doc.Version,
+		Hash:    doc.Hash,
+		Content: doc.Content,
+	}}
+
+	concurrent, ok := doc.operationsSince(baseVersion)
+	if !ok {
+		return rejected
+	}
+
+	current := utf16.Encode([]rune(doc.Content))
+	baseLength := len(current)
+	if len(concurrent) > 0 {
+		baseLength = concurrent[0].op.BaseLength
+	}
+
+	op, err := newTextOperation(ops, baseLength)
+	if err != nil {
+		log.Printf("Rejected operation on workspace %s (%s): %v", doc.WorkspaceID, doc.Channel, err)
+		return rejected
+	}
+	for _, applied := range concurrent {
+		if op, _, err = transform(op, applied.op); err != nil {
+			log.Printf("Failed to transform operation on workspace %s: %v", doc.WorkspaceID, err)
+			return rejected
+		}
+	}
+
+	updated, err := op.apply(current)
+	if err != nil {
+		log.Printf("Failed to apply operation on workspace %s: %v", doc.WorkspaceID, err)
+		return rejected
+	}
+
+	previous := doc.Content
+	doc.recordOperation(doc.Version+1, op)
+	doc.Content = string(utf16.Decode(updated))
+	doc.Version++
+	doc.Hash = generateHash(doc.Content)
+	doc.LastUpdated = time.Now()
+	doc.recordBlame(previous, author)
+
+	return OperationResult{
+		UpdateResult: UpdateResult{
+			Success: true,
+			Version: doc.Version,
+			Hash:    doc.Hash,
+			Content: doc.Content,
+		},
+		Ops: op.Operations(),
+	}
+}
+
+// Save writes the document to the database and returns what it wrote. It
+// holds the document lock while writing, so a save cannot overwrite a newer
+// version written by ReplaceContent.
+func (doc *VersionedDoc) Save() (string, int64, error) {
+	doc.mu.RLock()
+	defer doc.mu.RUnlock()
+	err := database.UpdateWorkspaceContentVersion(doc.WorkspaceID, doc.Channel, doc.Content, doc.Version)
+	return doc.Content, doc.Version, err
+}
+
+// operationsSince returns the operations applied after baseVersion;
+// ok is false if the version is unknown or too old to transform against
+func (doc *VersionedDoc) operationsSince(baseVersion int64) ([]appliedOperation, bool) {
+	if baseVersion > doc.Version {
+		return nil, false
+	}
+	if baseVersion == doc.Version {
+		return nil, true
 	}
+	behind := int(doc.Version - baseVersion)
+	if behind > len(doc.operations) {
+		return nil, false
+	}
+	return doc.operations[len(doc.operations)-behind:], true
+}
+
+// recordOperation remembers the operation producing version; caller must hold doc.mu
+func (doc *VersionedDoc) recordOperation(version int64, op *TextOperation) {
+	// A gap means earlier versions can no longer be transformed against
+	if n := len(doc.operations); n > 0 && doc.operations[n-1].version != version-1 {
+		doc.operations = nil
+	}
+	doc.operations = append(doc.operations, appliedOperation{version: version, op: op})
+	if len(doc.operations) > maxOperationHistory {
+		doc.operations = doc.operations[len(doc.operations)-maxOperationHistory:]
+	}
+}
+
+// recordBlame attributes the lines changed from previous to the current
+// content to author; caller must hold doc.mu
+func (doc *VersionedDoc) recordBlame(previous string, author Author) {
+	if doc.blame != nil {
+		doc.blame.Record(doc.WorkspaceID, previous, doc.Content, author, doc.LastUpdated)
+	}
+}
+
+// Blame returns the lines of the document with their last authors, and its
+// version; it is empty for channels without line authorship
+func (doc *VersionedDoc) Blame() ([]database.BlameLine, int64) {
+	doc.mu.RLock()
+	defer doc.mu.RUnlock()
+	if doc.blame == nil {
+		return nil, doc.Version
+	}
+	return doc.blame.Lines(doc.WorkspaceID, doc.Content), doc.Version
 }
 
 // GetState returns current document state
@@ -128,4 +323,4 @@ func (doc *VersionedDoc) GetState() (string, int64, string) {
 // generateHash creates MD5 hash of content
 func generateHash(content string) string {
 	return fmt.Sprintf("%x", md5.Sum([]byte(content)))
-}
\ No newline at end of file
+}
@@ -6,20 +6,24 @@ export const useAdminStore = defineStore('admin', () => {
     const token = ref<string | null>(localStorage.getItem('sheltie-admin-token'))
     const isAuthenticated = ref(!!token.value)
 
-    const login = async (password: string): Promise<boolean> => {
+    const setToken = (newToken: string) => {
+        token.value = newToken
+        isAuthenticated.value = true
+        localStorage.setItem('sheltie-admin-token', newToken)
+    }
+
+    const login = async (username: string, password: string): Promise<boolean> => {
         try {
             const response = await fetch(apiUrl('/api/admin/login'), {
                 method: 'POST',
                 headers: { 'Content-Type': 'application/json' },
-                body: JSON.stringify({ password })
+                body: JSON.stringify({ username, password })
             })
 
             if (!response.ok) return false
 
             const data = await response.json()
-            token.value = data.token
-            isAuthenticated.value = true
-            localStorage.setItem('sheltie-admin-token', data.token)
+            setToken(data.token)
             return true
         } catch {
             return false
@@ -48,6 +52,7 @@ export const useAdminStore = defineStore('admin', () => {
         token,
         isAuthenticated,
         login,
+        setToken,
         logout,
         getAuthHeaders
     }
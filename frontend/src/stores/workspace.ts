@@ -1,6 +1,7 @@
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
-import { apiUrl, wsUrl as buildWsUrl } from '@/utils/api'
+import { apiUrl, authHeaders, authParams, wsUrl as buildWsUrl } from '@/utils/api'
+import { DocumentSync, TextOperation, type WireOperation } from '@/utils/ot'
 
 interface Workspace {
     id: string
@@ -10,6 +11,7 @@ interface Workspace {
     collieContent: string  // BorderCollie Gantt data
     createdAt: string
     updatedAt: string
+    access?: 'view' | 'comment' | 'edit' | 'owner'
 }
 
 export interface WorkspaceListItem {
@@ -19,9 +21,10 @@ export interface WorkspaceListItem {
     updatedAt: string
 }
 
-// User presence with cursor position
+// User presence with cursor position, one per connection
 interface UserPresence {
     id: string
+    userId: string
     username: string
     color: string
     cursorPosition: number | null
@@ -30,6 +33,29 @@ interface UserPresence {
     lastSeen: number
 }
 
+// Format problem reported by the server lint (0-indexed line, UTF-16 columns)
+export interface LintDiagnostic {
+    line: number
+    column: number
+    endColumn: number
+    severity: 'error' | 'warning'
+    code: string
+    message: string
+    fix?: { title: string; text: string }
+}
+
+// Overdue phase or stale project flagged by the server (0-indexed line)
+export interface ProjectAlert {
+    kind: 'overdue-phase' | 'stale-project'
+    project: string
+    slug: string
+    line: number
+    phase?: string
+    date?: string
+    days: number
+    message: string
+}
+
 // Color palette for users (like Google Docs)
 const USER_COLORS = [
     '#4285f4', // Blue
@@ -55,6 +81,7 @@ export const useWorkspaceStore = defineStore('workspace', () => {
     const isConnected = ref(false)
 
     // User presence tracking
+    const currentConnectionId = ref<string>('')
     const currentUserId = ref<string>('')
     const currentUsername = ref<string>('')
     const users = ref<Map<string, UserPresence>>(new Map())
@@ -64,12 +91,34 @@ export const useWorkspaceStore = defineStore('workspace', () => {
     const documentHash = ref<string>('')
     const pendingChanges = ref<boolean>(false)
 
-    // Remote content update signal (used by the view to apply with cursor preservation)
-    const remoteContentUpdate = ref<{ content: string; conflict: boolean; timestamp: number } | null>(null)
+    // Latest lint diagnostics for the markdown document
+    const diagnostics = ref<LintDiagnostic[]>([])
+
+    // Latest overdue/stale alerts for the workspace
+    const alerts = ref<ProjectAlert[]>([])
+
+    // Version control for the collie (manpower) document channel
+    const collieVersion = ref<number>(0)
+    const collieHash = ref<string>('')
+
+    // Remote content update signal (used by the view to apply with cursor preservation);
+    // op maps positions in the old content to the new one
+    const remoteContentUpdate = ref<{ content: string; conflict: boolean; op: TextOperation | null; timestamp: number } | null>(null)
+
+    // Edits go to the server as operations against the version they were made on.
+    // Each channel starts from the full content the server sends on connect.
+    const sendOperation = (channel: string, ops: WireOperation[], version: number) => {
+        if (ws.value && ws.value.readyState === WebSocket.OPEN) {
+            ws.value.send(JSON.stringify({ type: 'operation', channel, ops, version }))
+        }
+    }
+    const markdownSync = new DocumentSync((ops, version) => sendOperation('markdown', ops, version))
+    const collieSync = new DocumentSync((ops, version) => sendOperation('collie', ops, version))
+    const synced = { markdown: false, collie: false }
 
-    // Get list of other connected users (excluding self)
+    // Get list of other connections (excluding this one)
     const otherUsers = computed(() => {
-        return Array.from(users.value.values()).filter(u => u.id !== currentUserId.value)
+        return Array.from(users.value.values()).filter(u => u.id !== currentConnectionId.value)
     })
 
     // Get user count
@@ -93,7 +142,8 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         isLoading.value = true
         error.value = null
         try {
-            const response = await fetch(apiUrl(`/api/workspaces/${id}`))
+            const response = await fetch(apiUrl(`/api/workspaces/${id}`), { headers: authHeaders() })
+            if (response.status === 401 || response.status === 403) throw new Error('You do not have access to this workspace')
             if (!response.ok) throw new Error('Workspace not found')
             currentWorkspace.value = await response.json()
         } catch (e) {
@@ -103,78 +153,66 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         }
     }
 
-    const connectWebSocket = (workspaceId: string, username: string = 'Anonymous') => {
+    // The server tells us who we are from the login or share link; a share
+    // link guest may pass a name to show instead of "Guest"
+    const connectWebSocket = (workspaceId: string, username?: string) => {
         if (ws.value) {
             ws.value.close()
         }
+        currentConnectionId.value = ''
+        currentUserId.value = ''
+        currentUsername.value = ''
+        synced.markdown = false
+        synced.collie = false
 
-        // Generate unique user ID
-        currentUserId.value = `user_${Date.now()}_${Math.random().toString(36).substr(2, 9)}`
-        currentUsername.value = username
-
-        const wsConnUrl = buildWsUrl(`/ws/${workspaceId}?userId=${encodeURIComponent(currentUserId.value)}&username=${encodeURIComponent(username)}`)
+        const params = authParams()
+        if (username) params.set('username', username)
+        const wsConnUrl = buildWsUrl(`/ws/${workspaceId}?${params}`)
 
         ws.value = new WebSocket(wsConnUrl)
 
         ws.value.onopen = () => {
             isConnected.value = true
             console.log('WebSocket connected')
-
-            // Add self to users
-            users.value.set(currentUserId.value, {
-                id: currentUserId.value,
-                username: username,
-                color: getUserColor(currentUserId.value),
-                cursorPosition: null,
-                selectionStart: null,
-                selectionEnd: null,
-                lastSeen: Date.now()
-            })
         }
 
         ws.value.onmessage = (event) => {
             try {
                 const message = JSON.parse(event.data)
 
+                // Collie channel has its own version and is applied through the store
+                if (message.channel === 'collie') {
+                    handleCollieMessage(message)
+                    return
+                }
+
                 switch (message.type) {
-                    case 'content':
-                        if (currentWorkspace.value) {
-                            documentVersion.value = message.version || 0
-                            documentHash.value = message.hash || ''
-                            pendingChanges.value = false
-
-                            if (message.conflict) {
-                                // Conflict: server overrides local — signal view to apply
-                                console.warn('Content conflict detected, reverting to server version')
-                                remoteContentUpdate.value = {
-                                    content: message.content,
-                                    conflict: true,
-                                    timestamp: Date.now()
-                                }
-                            } else if (message.userId !== currentUserId.value) {
-                                // Remote update from another user — signal view to apply with cursor protection
-                                remoteContentUpdate.value = {
-                                    content: message.content,
-                                    conflict: false,
-                                    timestamp: Date.now()
-                                }
-                            }
-                            // If userId === currentUserId: this is echoed back from an old broadcast, ignore
-                        }
+                    case 'welcome':
+                        currentConnectionId.value = message.connectionId
+                        currentUserId.value = message.userId
+                        currentUsername.value = message.username
                         break
 
+                    case 'content':
+                    case 'operation':
                     case 'ack':
-                        // Server confirmed our last write — just update version/hash, don't touch content
-                        documentVersion.value = message.version || 0
-                        documentHash.value = message.hash || ''
-                        pendingChanges.value = false
+                        handleMarkdownMessage(message)
+                        break
+
+                    case 'lint':
+                        diagnostics.value = message.diagnostics || []
+                        break
+
+                    case 'alerts':
+                        alerts.value = message.alerts || []
                         break
 
                     case 'join':
-                        users.value.set(message.userId, {
-                            id: message.userId,
+                        users.value.set(message.connectionId, {
+                            id: message.connectionId,
+                            userId: message.userId,
                             username: message.username,
-                            color: getUserColor(message.userId),
+                            color: getUserColor(message.connectionId),
                             cursorPosition: null,
                             selectionStart: null,
                             selectionEnd: null,
@@ -183,13 +221,13 @@ export const useWorkspaceStore = defineStore('workspace', () => {
                         break
 
                     case 'leave':
-                        users.value.delete(message.userId)
+                        users.value.delete(message.connectionId)
                         break
 
                     case 'cursor':
-                        // Update cursor position for remote user
-                        if (message.userId !== currentUserId.value) {
-                            const user = users.value.get(message.userId)
+                        // Update cursor position for remote connection
+                        if (message.connectionId !== currentConnectionId.value) {
+                            const user = users.value.get(message.connectionId)
                             if (user) {
                                 user.cursorPosition = message.position
                                 user.selectionStart = message.selectionStart
@@ -203,10 +241,11 @@ export const useWorkspaceStore = defineStore('workspace', () => {
                         // Sync user list from server
                         users.value.clear()
                         for (const u of message.users) {
-                            users.value.set(u.userId, {
-                                id: u.userId,
+                            users.value.set(u.connectionId, {
+                                id: u.connectionId,
+                                userId: u.userId,
                                 username: u.username,
-                                color: getUserColor(u.userId),
+                                color: getUserColor(u.connectionId),
                                 cursorPosition: u.cursorPosition ?? null,
                                 selectionStart: null,
                                 selectionEnd: null,
@@ -221,7 +260,9 @@ export const useWorkspaceStore = defineStore('workspace', () => {
                         break
                 }
             } catch (e) {
-                console.error('Failed to parse WebSocket message:', e)
+                // Our copy no longer matches the server's; reconnect to start from its content
+                console.error('Failed to handle WebSocket message, resyncing:', e)
+                connectWebSocket(workspaceId, username)
             }
         }
 
@@ -236,16 +277,51 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         }
     }
 
-    const sendContent = (content: string) => {
-        if (ws.value && ws.value.readyState === WebSocket.OPEN) {
-            pendingChanges.value = true
-            ws.value.send(JSON.stringify({
-                type: 'content',
-                content,
-                version: documentVersion.value,
-                hash: documentHash.value
-            }))
+    const handleMarkdownMessage = (message: any) => {
+        if (!currentWorkspace.value) return
+        let op: TextOperation | null
+        if (!synced.markdown && message.type === 'content') {
+            // Initial state: take the server's content as the base for our operations
+            op = TextOperation.between(currentWorkspace.value.content, message.content || '')
+            markdownSync.reset(message.content || '', message.version || 0)
+            synced.markdown = true
+        } else {
+            if (message.conflict) {
+                console.warn('Operation rejected, reverting to server version')
+            }
+            op = markdownSync.receive(message)
+        }
+        documentVersion.value = markdownSync.version
+        if (message.hash) documentHash.value = message.hash
+        pendingChanges.value = markdownSync.pending
+
+        if (op && !op.isNoop()) {
+            // Signal the view to apply the change with cursor protection
+            currentWorkspace.value.content = markdownSync.content
+            remoteContentUpdate.value = {
+                content: markdownSync.content,
+                conflict: !!message.conflict,
+                op,
+                timestamp: Date.now()
+            }
+        }
+    }
+
+    const handleCollieMessage = (message: any) => {
+        if (!currentWorkspace.value) return
+        if (!synced.collie && message.type === 'content') {
+            collieSync.reset(message.content || '', message.version || 0)
+            synced.collie = true
+            currentWorkspace.value.collieContent = collieSync.content
+        } else {
+            if (message.conflict) {
+                console.warn('Collie operation rejected, reverting to server version')
+            }
+            const op = collieSync.receive(message)
+            if (op && !op.isNoop()) currentWorkspace.value.collieContent = collieSync.content
         }
+        collieVersion.value = collieSync.version
+        if (message.hash) collieHash.value = message.hash
     }
 
     // Send cursor position to other users
@@ -253,7 +329,6 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         if (ws.value && ws.value.readyState === WebSocket.OPEN) {
             ws.value.send(JSON.stringify({
                 type: 'cursor',
-                userId: currentUserId.value,
                 position,
                 selectionStart: selectionStart ?? position,
                 selectionEnd: selectionEnd ?? position
@@ -268,13 +343,24 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         }
         isConnected.value = false
         users.value.clear()
+        currentConnectionId.value = ''
         currentUserId.value = ''
     }
 
     const updateContent = (content: string) => {
         if (currentWorkspace.value) {
             currentWorkspace.value.content = content
-            sendContent(content)
+            if (synced.markdown) {
+                markdownSync.edit(content)
+                pendingChanges.value = markdownSync.pending
+            }
+        }
+    }
+
+    const updateCollieContent = (content: string) => {
+        if (currentWorkspace.value) {
+            currentWorkspace.value.collieContent = content
+            if (synced.collie) collieSync.edit(content)
         }
     }
 
@@ -284,17 +370,21 @@ export const useWorkspaceStore = defineStore('workspace', () => {
         users,
         otherUsers,
         userCount,
+        currentConnectionId,
         currentUserId,
         currentUsername,
         documentVersion,
         documentHash,
         pendingChanges,
         remoteContentUpdate,
+        diagnostics,
+        alerts,
         getUserIcon,
         fetchWorkspace,
         connectWebSocket,
         disconnectWebSocket,
         updateContent,
+        updateCollieContent,
         sendCursor
     }
 })
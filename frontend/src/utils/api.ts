@@ -8,3 +8,33 @@ export function wsUrl(path: string): string {
   const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
   return `${protocol}//${window.location.host}${base}${path}`
 }
+
+const ADMIN_TOKEN_KEY = 'sheltie-admin-token'
+const SHARE_TOKEN_KEY = 'sheltie-share-token'
+
+// The share token of the page, from ?share= or remembered for this tab
+export function shareToken(): string | null {
+  const fromUrl = new URLSearchParams(window.location.search).get('share')
+  if (fromUrl) sessionStorage.setItem(SHARE_TOKEN_KEY, fromUrl)
+  return fromUrl || sessionStorage.getItem(SHARE_TOKEN_KEY)
+}
+
+// Headers carrying the login and share tokens for workspace requests
+export function authHeaders(): Record<string, string> {
+  const headers: Record<string, string> = {}
+  const token = localStorage.getItem(ADMIN_TOKEN_KEY)
+  if (token) headers['Authorization'] = token
+  const share = shareToken()
+  if (share) headers['X-Share-Token'] = share
+  return headers
+}
+
+// The same tokens as query parameters, for WebSocket URLs which cannot carry headers
+export function authParams(): URLSearchParams {
+  const params = new URLSearchParams()
+  const token = localStorage.getItem(ADMIN_TOKEN_KEY)
+  if (token) params.set('token', token)
+  const share = shareToken()
+  if (share) params.set('share', share)
+  return params
+}
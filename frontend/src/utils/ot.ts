@@ -0,0 +1,394 @@
+// Operational transform for collaborative editing, the client side of
+// backend/websocket/operation.go. Positions and lengths count UTF-16 code
+// units, which is what JavaScript string indices are.
+
+// A single edit as sent over the WebSocket; operations in a message apply in order
+export interface WireOperation {
+    type: 'insert' | 'delete'
+    position: number
+    text?: string
+    length?: number
+}
+
+// One step of a TextOperation: retain (n > 0), delete (n < 0) or insert (string)
+type Component = number | string
+
+const isRetain = (c: Component): c is number => typeof c === 'number' && c > 0
+const isDelete = (c: Component): c is number => typeof c === 'number' && c < 0
+const isInsert = (c: Component): c is string => typeof c === 'string'
+
+// Walks the components of an operation, allowing partial consumption
+class ComponentIterator {
+    private i = 0
+    constructor(private ops: Component[]) {}
+
+    next(): Component | undefined {
+        return this.i < this.ops.length ? this.ops[this.i++] : undefined
+    }
+
+    // Uses n units of c and returns the remainder, or the next component if c is used up
+    consume(c: Component, n: number): Component | undefined {
+        if (isInsert(c)) {
+            if (n < c.length) return c.slice(n)
+        } else if (isDelete(c)) {
+            if (n < -c) return c + n
+        } else if (n < c) {
+            return c - n
+        }
+        return this.next()
+    }
+}
+
+// A change to a whole document as retain, insert and delete components (the ot.js model)
+export class TextOperation {
+    ops: Component[] = []
+    baseLength = 0
+    targetLength = 0
+
+    retain(n: number): this {
+        if (n <= 0) return this
+        this.baseLength += n
+        this.targetLength += n
+        const last = this.ops.length - 1
+        if (last >= 0 && isRetain(this.ops[last])) {
+            (this.ops[last] as number) += n
+        } else {
+            this.ops.push(n)
+        }
+        return this
+    }
+
+    insert(s: string): this {
+        if (s === '') return this
+        this.targetLength += s.length
+        const last = this.ops.length - 1
+        if (last >= 0 && isInsert(this.ops[last])) {
+            this.ops[last] = (this.ops[last] as string) + s
+        } else if (last >= 0 && isDelete(this.ops[last])) {
+            // Keep inserts before deletes, as the server does
+            if (last >= 1 && isInsert(this.ops[last - 1])) {
+                this.ops[last - 1] = (this.ops[last - 1] as string) + s
+            } else {
+                this.ops.push(this.ops[last])
+                this.ops[last] = s
+            }
+        } else {
+            this.ops.push(s)
+        }
+        return this
+    }
+
+    delete(n: number): this {
+        if (n <= 0) return this
+        this.baseLength += n
+        const last = this.ops.length - 1
+        if (last >= 0 && isDelete(this.ops[last])) {
+            (this.ops[last] as number) -= n
+        } else {
+            this.ops.push(-n)
+        }
+        return this
+    }
+
+    isNoop(): boolean {
+        return this.ops.length === 0 || (this.ops.length === 1 && isRetain(this.ops[0]))
+    }
+
+    apply(doc: string): string {
+        if (doc.length !== this.baseLength) throw new Error('operation length does not match document')
+        let out = ''
+        let pos = 0
+        for (const c of this.ops) {
+            if (isRetain(c)) {
+                out += doc.slice(pos, pos + c)
+                pos += c
+            } else if (isInsert(c)) {
+                out += c
+            } else {
+                pos -= c
+            }
+        }
+        return out
+    }
+
+    // Returns an operation equivalent to applying this and then next
+    compose(next: TextOperation): TextOperation {
+        if (this.targetLength !== next.baseLength) throw new Error('operation length does not match document')
+        const result = new TextOperation()
+        const it1 = new ComponentIterator(this.ops)
+        const it2 = new ComponentIterator(next.ops)
+        let op1 = it1.next()
+        let op2 = it2.next()
+
+        while (op1 !== undefined || op2 !== undefined) {
+            if (op1 !== undefined && isDelete(op1)) {
+                result.delete(-op1)
+                op1 = it1.next()
+                continue
+            }
+            if (op2 !== undefined && isInsert(op2)) {
+                result.insert(op2)
+                op2 = it2.next()
+                continue
+            }
+            if (op1 === undefined || op2 === undefined) throw new Error('operation length does not match document')
+
+            let n: number
+            if (isRetain(op1) && isRetain(op2)) {
+                n = Math.min(op1, op2)
+                result.retain(n)
+            } else if (isInsert(op1) && isDelete(op2)) {
+                n = Math.min(op1.length, -op2)
+            } else if (isInsert(op1) && isRetain(op2)) {
+                n = Math.min(op1.length, op2)
+                result.insert(op1.slice(0, n))
+            } else {
+                // retain then delete
+                n = Math.min(op1 as number, -(op2 as number))
+                result.delete(n)
+            }
+            op1 = it1.consume(op1, n)
+            op2 = it2.consume(op2, n)
+        }
+        return result
+    }
+
+    // Takes operations a and b made concurrently on the same document and
+    // returns [a', b'] such that a then b' equals b then a'. When both insert
+    // at the same position, a's insert goes first, as on the server.
+    static transform(a: TextOperation, b: TextOperation): [TextOperation, TextOperation] {
+        if (a.baseLength !== b.baseLength) throw new Error('operation length does not match document')
+        const aPrime = new TextOperation()
+        const bPrime = new TextOperation()
+        const it1 = new ComponentIterator(a.ops)
+        const it2 = new ComponentIterator(b.ops)
+        let op1 = it1.next()
+        let op2 = it2.next()
+
+        while (op1 !== undefined || op2 !== undefined) {
+            if (op1 !== undefined && isInsert(op1)) {
+                aPrime.insert(op1)
+                bPrime.retain(op1.length)
+                op1 = it1.next()
+                continue
+            }
+            if (op2 !== undefined && isInsert(op2)) {
+                aPrime.retain(op2.length)
+                bPrime.insert(op2)
+                op2 = it2.next()
+                continue
+            }
+            if (op1 === undefined || op2 === undefined) throw new Error('operation length does not match document')
+
+            const n1 = op1 as number
+            const n2 = op2 as number
+            let n: number
+            if (n1 > 0 && n2 > 0) {
+                n = Math.min(n1, n2)
+                aPrime.retain(n)
+                bPrime.retain(n)
+            } else if (n1 < 0 && n2 < 0) {
+                // Both deleted the same text
+                n = Math.min(-n1, -n2)
+            } else if (n1 < 0) {
+                n = Math.min(-n1, n2)
+                aPrime.delete(n)
+            } else {
+                n = Math.min(n1, -n2)
+                bPrime.delete(n)
+            }
+            op1 = it1.consume(op1, n)
+            op2 = it2.consume(op2, n)
+        }
+        return [aPrime, bPrime]
+    }
+
+    // Builds an operation from sequential wire operations on a document of baseLength units
+    static fromWire(ops: WireOperation[], baseLength: number): TextOperation {
+        let result = new TextOperation().retain(baseLength)
+        for (const op of ops) {
+            const length = result.targetLength
+            const step = new TextOperation()
+            if (op.type === 'insert') {
+                if (op.position < 0 || op.position > length) throw new Error(`insert position ${op.position} out of range`)
+                step.retain(op.position).insert(op.text || '').retain(length - op.position)
+            } else if (op.type === 'delete') {
+                const n = op.length || 0
+                if (op.position < 0 || n < 0 || op.position + n > length) throw new Error(`delete range ${op.position}+${n} out of range`)
+                step.retain(op.position).delete(n).retain(length - op.position - n)
+            } else {
+                throw new Error(`unknown operation type ${(op as WireOperation).type}`)
+            }
+            result = result.compose(step)
+        }
+        return result
+    }
+
+    // Converts the operation into sequential wire operations
+    toWire(): WireOperation[] {
+        const ops: WireOperation[] = []
+        let pos = 0
+        for (const c of this.ops) {
+            if (isRetain(c)) {
+                pos += c
+            } else if (isInsert(c)) {
+                ops.push({ type: 'insert', position: pos, text: c })
+                pos += c.length
+            } else {
+                ops.push({ type: 'delete', position: pos, length: -c })
+            }
+        }
+        return ops
+    }
+
+    // Builds an operation turning oldText into newText by replacing the region
+    // between their common prefix and suffix, without splitting surrogate pairs
+    static between(oldText: string, newText: string): TextOperation {
+        let prefix = 0
+        while (prefix < oldText.length && prefix < newText.length && oldText.charCodeAt(prefix) === newText.charCodeAt(prefix)) {
+            prefix++
+        }
+        if (prefix > 0 && isHighSurrogate(oldText.charCodeAt(prefix - 1))) prefix--
+        let suffix = 0
+        while (
+            suffix < oldText.length - prefix && suffix < newText.length - prefix &&
+            oldText.charCodeAt(oldText.length - 1 - suffix) === newText.charCodeAt(newText.length - 1 - suffix)
+        ) {
+            suffix++
+        }
+        if (suffix > 0 && isLowSurrogate(oldText.charCodeAt(oldText.length - suffix))) suffix--
+
+        return new TextOperation()
+            .retain(prefix)
+            .delete(oldText.length - prefix - suffix)
+            .insert(newText.slice(prefix, newText.length - suffix))
+            .retain(suffix)
+    }
+
+    // Moves a cursor index over the operation; inserts at the cursor push it along
+    transformIndex(index: number): number {
+        let pos = 0
+        let moved = index
+        for (const c of this.ops) {
+            if (pos >= index) break
+            if (isRetain(c)) {
+                pos += c
+            } else if (isInsert(c)) {
+                moved += c.length
+            } else {
+                moved -= Math.min(-c, index - pos)
+                pos -= c
+            }
+        }
+        return moved
+    }
+}
+
+const isHighSurrogate = (u: number) => u >= 0xd800 && u < 0xdc00
+const isLowSurrogate = (u: number) => u >= 0xdc00 && u < 0xe000
+
+// A document message from the server: content, operation or ack
+export interface DocumentMessage {
+    type: string
+    content?: string
+    ops?: WireOperation[]
+    version?: number
+    conflict?: boolean
+}
+
+// DocumentSync keeps one document channel in step with the server. Local
+// edits become operations; one is in flight at a time and later edits are
+// buffered until the server acknowledges it. Remote operations are
+// transformed over the pending ones. The server numbers document versions
+// one by one, so messages that arrive early wait for the ones before them.
+export class DocumentSync {
+    version = 0
+    private server = ''
+    private inflight: TextOperation | null = null
+    private buffer: TextOperation | null = null
+    private early = new Map<number, DocumentMessage>()
+
+    constructor(private send: (ops: WireOperation[], version: number) => void) {}
+
+    // The document with local edits applied
+    get content(): string {
+        let doc = this.server
+        if (this.inflight) doc = this.inflight.apply(doc)
+        if (this.buffer) doc = this.buffer.apply(doc)
+        return doc
+    }
+
+    get pending(): boolean {
+        return this.inflight !== null || this.buffer !== null
+    }
+
+    // Takes the server's state, dropping local edits not yet acknowledged
+    reset(content: string, version: number) {
+        this.server = content
+        this.version = version
+        this.inflight = null
+        this.buffer = null
+        this.early.clear()
+    }
+
+    // Records a local edit that turned the document into newContent
+    edit(newContent: string) {
+        const op = TextOperation.between(this.content, newContent)
+        if (op.isNoop()) return
+        this.buffer = this.buffer ? this.buffer.compose(op) : op
+        this.flush()
+    }
+
+    // Handles an ack, operation or content message for the document. It
+    // returns the operation that changed the local document, if any.
+    receive(message: DocumentMessage): TextOperation | null {
+        const version = message.version || 0
+        if (message.conflict) {
+            // The server refused our operation; start again from its content
+            const local = this.content
+            this.reset(message.content || '', version)
+            return TextOperation.between(local, this.server)
+        }
+        if (version <= this.version) return null // already seen
+        if (version > this.version + 1) {
+            this.early.set(version, message)
+            return null
+        }
+
+        let changed = this.handle(message)
+        // Run messages that were waiting for this one
+        for (let next = this.early.get(this.version + 1); next; next = this.early.get(this.version + 1)) {
+            this.early.delete(this.version + 1)
+            const op = this.handle(next)
+            if (op) changed = changed ? changed.compose(op) : op
+        }
+        return changed
+    }
+
+    // Applies the message for the next version
+    private handle(message: DocumentMessage): TextOperation | null {
+        this.version = message.version || 0
+        if (message.type === 'ack') {
+            // Our operation was transformed over everything before it, as it was here
+            if (this.inflight) this.server = this.inflight.apply(this.server)
+            this.inflight = null
+            this.flush()
+            return null
+        }
+
+        let remote = message.type === 'operation'
+            ? TextOperation.fromWire(message.ops || [], this.server.length)
+            : TextOperation.between(this.server, message.content || '')
+        this.server = remote.apply(this.server)
+        if (this.inflight) [this.inflight, remote] = TextOperation.transform(this.inflight, remote)
+        if (this.buffer) [this.buffer, remote] = TextOperation.transform(this.buffer, remote)
+        return remote
+    }
+
+    private flush() {
+        if (this.inflight || !this.buffer) return
+        this.inflight = this.buffer
+        this.buffer = null
+        this.send(this.inflight.toWire(), this.version)
+    }
+}
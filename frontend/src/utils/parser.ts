@@ -183,8 +183,9 @@ function parseBasicInfo(
     } else if (trimmed.match(/^[\*\-]\s*相關單位:/)) {
         setInTimeline(false)
         setInDepartments(true)
-    } else if (trimmed.match(/^[\*\-]\s*承辦:/)) {
-        const value = trimmed.replace(/^[\*\-]\s*承辦:/, '').trim()
+    } else if (trimmed.match(/^[\*\-]\s*(承辦|主辦):/)) {
+        // 主辦 is the older name, still used by the default template
+        const value = trimmed.replace(/^[\*\-]\s*(承辦|主辦):/, '').trim()
         if (value) {
             project.departments.承辦 = value.split(',').map(s => s.trim()).filter(s => s)
         }